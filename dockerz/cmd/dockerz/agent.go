@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/agent"
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	agentURLs      string
+	agentToken     string
+	agentListen    string
+	agentName      string
+	agentCapacity  int
+	agentPlatforms string
+	agentBackend   string
+	simDuration    time.Duration
+	simFail        string
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run a build agent that builds services for 'dockerz build --agents'",
+	Long: `Serve build tasks over HTTP for a coordinating 'dockerz build --agents <url>,...'.
+
+The coordinator registers the agent, reading the capacity (tasks at once) and platforms
+it advertises, then sends it build tasks. The agent builds them with its own Docker
+daemon, runs their hooks, pushes when the build pushes, and streams the build output
+and result back.
+
+Run the agent from a checkout of the same commit as the coordinator: services are built
+from the agent's working directory, and tasks from another commit are refused.
+
+Agents listen on 127.0.0.1 by default. Listening on other addresses requires a token
+(--token or DOCKERZ_AGENT_TOKEN) that coordinators must present, since tasks run hooks.
+
+The simulator backend builds nothing: every task writes BuildKit-like output for
+--sim-duration and succeeds unless listed in --sim-fail. Use it to try distributed
+builds on one machine without Docker.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		token := agentToken
+		if token == "" {
+			token = os.Getenv(agent.EnvToken)
+		}
+		host, port, err := net.SplitHostPort(agentListen)
+		if err != nil {
+			log.Fatalf("Invalid listen address '%s': %v", agentListen, err)
+		}
+		if ip := net.ParseIP(host); token == "" && host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			log.Fatalf("Listening on %s requires a token: set --token or %s", agentListen, agent.EnvToken)
+		}
+
+		var backend agent.Backend
+		switch agentBackend {
+		case agent.BackendDocker:
+			backend = agent.Docker{}
+		case agent.BackendSimulator:
+			fail := make(map[string]bool)
+			for _, service := range strings.Split(simFail, ",") {
+				if service = strings.TrimSpace(service); service != "" {
+					fail[service] = true
+				}
+			}
+			backend = agent.Simulator{Duration: simDuration, Fail: fail}
+		default:
+			log.Fatalf("Invalid backend '%s': must be %s or %s", agentBackend, agent.BackendDocker, agent.BackendSimulator)
+		}
+
+		name := agentName
+		if name == "" {
+			hostname, _ := os.Hostname()
+			name = fmt.Sprintf("%s:%s", hostname, port)
+		}
+		var platforms []string
+		for _, platform := range strings.Split(agentPlatforms, ",") {
+			if platform = strings.TrimSpace(platform); platform != "" {
+				platforms = append(platforms, platform)
+			}
+		}
+
+		server := agent.NewServer(agent.Info{Name: name, Version: dockerzVersion, Capacity: agentCapacity, Platforms: platforms}, backend, token, builder.GetGitCommitID())
+		log.Printf("Agent %s listening on %s (%s backend, capacity %d, platforms %s)", name, agentListen, backend.Name(), agentCapacity, strings.Join(platforms, ","))
+		if err := http.ListenAndServe(agentListen, server.Handler()); err != nil {
+			log.Fatalf("Agent stopped: %v", err)
+		}
+	},
+}
+
+// newCoordinator registers the build agents given with --agents
+func newCoordinator(cfg *config.Config, logger *logging.Logger) *agent.Coordinator {
+	token := agentToken
+	if token == "" {
+		token = os.Getenv(agent.EnvToken)
+	}
+	coordinator, err := agent.NewCoordinator(strings.Split(agentURLs, ","), token, cfg.UseGAR && cfg.PushToGAR, builder.GetGitCommitID())
+	if err != nil {
+		logger.Error(logging.CATEGORY_BUILD, err.Error())
+		log.Fatalf("Failed to register build agents: %v", err)
+	}
+	logger.Info(logging.CATEGORY_BUILD, fmt.Sprintf("Distributing builds to agents with capacity %d", coordinator.Capacity()))
+	return coordinator
+}
+
+func init() {
+	agentCmd.Flags().StringVar(&agentListen, "listen", agent.DefaultListen, "Address to serve build tasks on")
+	agentCmd.Flags().StringVar(&agentName, "name", "", "Agent name shown by coordinators (default: host name and port)")
+	agentCmd.Flags().IntVar(&agentCapacity, "capacity", runtime.NumCPU(), "Number of tasks to build at once")
+	agentCmd.Flags().StringVar(&agentPlatforms, "platforms", "linux/"+runtime.GOARCH, "Comma-separated platforms this agent can build")
+	agentCmd.Flags().StringVar(&agentBackend, "backend", agent.BackendDocker, "Build backend: docker or simulator")
+	agentCmd.Flags().StringVar(&agentToken, "token", "", "Token coordinators must present (default: DOCKERZ_AGENT_TOKEN)")
+	agentCmd.Flags().DurationVar(&simDuration, "sim-duration", 3*time.Second, "simulator backend: how long each build takes")
+	agentCmd.Flags().StringVar(&simFail, "sim-fail", "", "simulator backend: comma-separated services whose builds fail")
+	rootCmd.AddCommand(agentCmd)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/baseimage"
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/dockerfile"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/registry"
+)
+
+var (
+	trackBaseImagesFlag bool
+	baseImagesInsecure  bool
+)
+
+// serviceBaseImages returns the tracked base images of each service by service key: the
+// external images its Dockerfile builds from, without ignored images and images built by
+// another discovered service
+func serviceBaseImages(cfg *config.Config, services []discovery.DiscoveredService) (map[string][]string, []error) {
+	built := make(map[string]bool)
+	for _, service := range services {
+		for _, tag := range append([]string{service.Tag}, service.Tags...) {
+			if ref, err := registry.ParseReference(builder.ImageReference(cfg, service.ImageName, tag)); err == nil {
+				built[ref.Name()] = true
+			}
+		}
+	}
+
+	bases := make(map[string][]string)
+	var failures []error
+	for _, service := range services {
+		name := service.Dockerfile
+		if name == "" {
+			name = discovery.DefaultDockerfile
+		}
+		file, err := dockerfile.ParseFile(filepath.Join(service.Path, name))
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed to read base images of %s: %w", service.Key(), err))
+			continue
+		}
+		for _, image := range baseimage.Bases(file) {
+			if baseimage.Ignored(image, cfg.BaseImages.Ignore) {
+				continue
+			}
+			if ref, err := registry.ParseReference(image); err == nil && built[ref.Name()] {
+				continue
+			}
+			bases[service.Key()] = append(bases[service.Key()], image)
+		}
+	}
+	return bases, failures
+}
+
+// resolveBaseImages returns the current digest of every base image; images that cannot be
+// resolved are left out and reported
+func resolveBaseImages(resolver *baseimage.Resolver, bases map[string][]string) (map[string]string, []error) {
+	digests := make(map[string]string)
+	var failures []error
+	for _, images := range bases {
+		for _, image := range images {
+			if _, done := digests[image]; done {
+				continue
+			}
+			digest, err := resolver.Resolve(image)
+			if err != nil {
+				failures = append(failures, fmt.Errorf("failed to resolve base image %s: %w", image, err))
+				digests[image] = ""
+				continue
+			}
+			digests[image] = digest
+		}
+	}
+	for image, digest := range digests {
+		if digest == "" {
+			delete(digests, image)
+		}
+	}
+	return digests, failures
+}
+
+// trackBaseImages resolves the base image digests of the services and compares them with the
+// digests they were last built from. Services seen for the first time record their digests as
+// a baseline; unresolvable images never trigger a rebuild.
+func trackBaseImages(cfg *config.Config, services []discovery.DiscoveredService, store *baseimage.Store, logger *logging.Logger) {
+	logger.Info(logging.CATEGORY_DISCOVERY, "Resolving base image digests")
+	bases, failures := serviceBaseImages(cfg, services)
+	digests, resolveFailures := resolveBaseImages(baseimage.NewResolver(registry.NewClient(cfg.BaseImages.Insecure)), bases)
+	for _, failure := range append(failures, resolveFailures...) {
+		logger.Warn(logging.CATEGORY_DISCOVERY, failure.Error())
+	}
+
+	now := time.Now()
+	for i := range services {
+		key := services[i].Key()
+		current := make(map[string]string)
+		for _, image := range bases[key] {
+			if digest, ok := digests[image]; ok {
+				current[image] = digest
+			}
+		}
+		services[i].BaseImages = current
+		services[i].BaseUpdates = baseimage.Updated(store.Compare(key, bases[key], current))
+		store.Baseline(key, current, now)
+		if len(services[i].BaseUpdates) > 0 {
+			logger.Info(logging.CATEGORY_DISCOVERY, fmt.Sprintf("Base image updated for %s: %s", services[i].Name, strings.Join(services[i].BaseUpdates, ", ")))
+		}
+	}
+}
+
+// recordBaseImages stores the base image digests of the services built successfully
+func recordBaseImages(services []discovery.DiscoveredService, results []builder.BuildResult, store *baseimage.Store, logger *logging.Logger) {
+	built := make(map[string]bool)
+	for _, result := range results {
+		if result.Status == "success" {
+			built[result.Service] = true
+		}
+	}
+	now := time.Now()
+	for _, service := range services {
+		if built[service.Key()] {
+			store.Record(service.Key(), service.BaseImages, now)
+		}
+	}
+	if err := store.Save(); err != nil {
+		logger.Warn(logging.CATEGORY_BUILD, err.Error())
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/ci"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/logging"
+)
+
+var ciProvider string
+
+// collectCIRun gathers the outcome of every discovered service: built services from their
+// results and every other service as skipped, with the reason smart mode gave
+func collectCIRun(cfg *config.Config, services []discovery.DiscoveredService, results []builder.BuildResult, skipReasons map[string]string, summary builder.Summary) ci.Run {
+	run := ci.Run{Duration: summary.Duration}
+
+	byKey := make(map[string]builder.BuildResult, len(results))
+	for _, result := range results {
+		byKey[result.Service] = result
+	}
+
+	for _, service := range services {
+		dockerfile := service.Dockerfile
+		if dockerfile == "" {
+			dockerfile = discovery.DefaultDockerfile
+		}
+		entry := ci.Service{
+			Key:        service.Key(),
+			Name:       service.Name,
+			ImageName:  service.ImageName,
+			Image:      builder.ImageReference(cfg, service.ImageName, service.Tag),
+			Dockerfile: filepath.ToSlash(filepath.Join(service.Path, dockerfile)),
+			Status:     "skipped",
+			Reason:     skipReasons[service.Key()],
+		}
+		if result, built := byKey[service.Key()]; built {
+			entry.Image = result.Image
+			entry.Images = result.Images
+			entry.Digest = result.Digest
+			entry.Status = result.Status
+			entry.Duration = result.Duration
+			entry.FailedLine = result.FailedLine
+			entry.FailedStep = result.FailedStep
+			if result.Status == "failed" {
+				entry.Error = result.BuildOutput
+			} else if result.PushStatus == "failed" {
+				entry.PushFailed = true
+				entry.Error = result.PushOutput
+			}
+		}
+		run.Services = append(run.Services, entry)
+	}
+	return run
+}
+
+// publishCI writes the run's CI integration outputs for the provider.
+// Failures are logged but never fail the build.
+func publishCI(cfg *config.Config, provider ci.Provider, run ci.Run, logger *logging.Logger) {
+	switch provider {
+	case ci.ProviderGitHub:
+		if err := ci.WriteGitHub(run, os.Stdout); err != nil {
+			logger.Warn(logging.CATEGORY_BUILD, err.Error())
+		} else {
+			logger.Info(logging.CATEGORY_BUILD, "GitHub Actions outputs and step summary written")
+		}
+	case ci.ProviderGitLab:
+		path := cfg.CI.Dotenv
+		if path == "" {
+			path = ci.DefaultDotenv
+		}
+		if err := ci.WriteGitLab(run, path); err != nil {
+			logger.Warn(logging.CATEGORY_BUILD, err.Error())
+		} else {
+			logger.Info(logging.CATEGORY_BUILD, fmt.Sprintf("GitLab dotenv report written to %s", path))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/spf13/cobra"
+)
+
+var (
+	discoverExplain bool
+	discoverInput   string
+)
+
+var discoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "List the services discovery finds, and why",
+	Long: `Run service discovery without building and list the services found.
+
+With --explain, every path discovery looked at is listed with the rule that included
+or skipped it: discovery.exclude, discovery.include, .gitignore (with discovery.gitignore),
+the built-in exclusions (node_modules, vendor, hidden directories, ...), and the
+discovery.dockerfiles and discovery.ignore patterns.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.ReadConfig(configPath, profileName)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+
+		defaultTag := cfg.GlobalTag
+		if defaultTag == "" {
+			defaultTag = builder.GetGitCommitID()
+		}
+
+		result, err := discovery.DiscoverServices(cfg, defaultTag, discoverInput)
+		if err != nil {
+			log.Fatalf("Failed to discover services: %v", err)
+		}
+
+		if discoverExplain {
+			fmt.Println("=== Discovery Decisions ===")
+			for _, decision := range result.Decisions {
+				mark, rule := "+", decision.Rule
+				if !decision.Included {
+					mark = "-"
+				}
+				if rule == "" {
+					rule = "scanned"
+				}
+				fmt.Printf("%s %-40s %s\n", mark, decision.Path, rule)
+			}
+			fmt.Println()
+		}
+
+		fmt.Printf("=== Services (%d) ===\n", len(result.Services))
+		for _, service := range result.Services {
+			fmt.Printf("%-30s %-40s %s\n", service.Name, service.Key(), service.ImageName)
+		}
+
+		for _, err := range result.Errors {
+			fmt.Printf("Discovery error: %v\n", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(discoverCmd)
+
+	discoverCmd.Flags().StringVarP(&configPath, "config", "c", "build.yaml", "Path to the build.yaml configuration file")
+	discoverCmd.Flags().StringVar(&profileName, "profile", "", "Configuration profile to use")
+	discoverCmd.Flags().StringVar(&discoverInput, "input-changed-services", "", "Discover the services listed in this file")
+	discoverCmd.Flags().BoolVar(&discoverExplain, "explain", false, "Show which rule included or skipped each path")
+}
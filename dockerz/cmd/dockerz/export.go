@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/baseimage"
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/export"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/tagging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat string
+	exportOutput string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export --format bake-hcl|bake-json|compose",
+	Short: "Export the build graph as a docker buildx bake or compose file",
+	Long: `Resolve services, tags, contexts, build args, platforms and dependencies the way
+'dockerz build' does, and write them as a build file for another tool instead of building:
+
+  bake-hcl   docker-bake.hcl for 'docker buildx bake'
+  bake-json  docker-bake.json for 'docker buildx bake'
+  compose    a docker-compose file with build sections
+
+With --smart (or smart: true) only the services smart mode decided to build are exported.
+Dependencies between exported services become bake "target:" contexts or compose
+"service:" additional contexts, keyed by the dependency's image name, so a Dockerfile
+using "FROM <image>" builds against the freshly built image.
+
+Paths in the output are relative to the output file's directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger, err := logging.NewLogger("")
+		if err != nil {
+			log.Fatalf("Failed to create logger: %v", err)
+		}
+		// stdout may carry the exported file
+		logger.SetConsoleOutput(os.Stderr)
+
+		cfg, err := config.ReadConfig(configPath, profileName)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		applyBuildFlags(cmd, cfg)
+
+		defaultTag := cfg.GlobalTag
+		if defaultTag == "" {
+			defaultTag = builder.GetGitCommitID()
+		}
+
+		inputFile := cfg.InputChangedServices
+		if cmd.Flags().Changed("input-changed-services") {
+			inputFile = inputChangedServices
+		}
+
+		discoveryResult, err := discovery.DiscoverServices(cfg, defaultTag, inputFile)
+		if err != nil {
+			log.Fatalf("Failed to discover services: %v", err)
+		}
+		for _, discoveryErr := range discoveryResult.Errors {
+			logger.Warn(logging.CATEGORY_DISCOVERY, fmt.Sprintf("Discovery error: %v", discoveryErr))
+		}
+
+		if cfg.Versioning.Enabled {
+			applyServiceVersions(cfg, discoveryResult.Services, logger)
+		}
+		if err := tagging.ApplyTags(cfg, discoveryResult.Services, tagging.LoadGitInfo()); err != nil {
+			log.Fatalf("Failed to resolve image tags: %v", err)
+		}
+
+		if cfg.BaseImages.Track {
+			store, err := baseimage.Load(baseimage.DefaultDir)
+			if err != nil {
+				logger.Warn(logging.CATEGORY_DISCOVERY, err.Error())
+			}
+			trackBaseImages(cfg, discoveryResult.Services, store, logger)
+		}
+		services, _, _ := selectServices(cfg, discoveryResult, logger)
+
+		baseDir := "."
+		if exportOutput != "" {
+			baseDir = filepath.Dir(exportOutput)
+		}
+		targets, err := export.Targets(cfg, services, baseDir)
+		if err != nil {
+			log.Fatalf("Failed to export services: %v", err)
+		}
+		data, err := export.Render(exportFormat, targets)
+		if err != nil {
+			log.Fatalf("Failed to export services: %v", err)
+		}
+
+		if exportOutput == "" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.MkdirAll(baseDir, 0755); err != nil {
+			log.Fatalf("Failed to create %s: %v", baseDir, err)
+		}
+		if err := os.WriteFile(exportOutput, data, 0644); err != nil {
+			log.Fatalf("Failed to write %s: %v", exportOutput, err)
+		}
+		logger.Info(logging.CATEGORY_CONFIG, fmt.Sprintf("Exported %d services to %s", len(targets), exportOutput))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&configPath, "config", "c", "build.yaml", "Path to the build.yaml configuration file")
+	exportCmd.Flags().StringVar(&profileName, "profile", "", "Configuration profile to apply from the profiles: section")
+	exportCmd.Flags().StringVar(&exportFormat, "format", export.FormatBakeHCL, "Output format: "+strings.Join(export.Formats, ", "))
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write the exported file here instead of stdout")
+	exportCmd.Flags().StringVar(&inputChangedServices, "input-changed-services", "", "Export only the services listed in this file")
+	exportCmd.Flags().StringVar(&servicesDir, "services-dir", "", "Comma-separated list of directories to scan for service definitions (overrides config file)")
+	exportCmd.Flags().StringVar(&globalTag, "global-tag", "", "Global Docker tag to apply to all images (overrides config file and git commit ID)")
+	exportCmd.Flags().StringVar(&project, "project", "", "Google Cloud Platform project ID for GAR image names (overrides config file)")
+	exportCmd.Flags().StringVar(&region, "region", "", "GCP region for GAR (overrides config file)")
+	exportCmd.Flags().StringVar(&gar, "gar", "", "Name of the Google Artifact Registry repository (overrides config file)")
+	exportCmd.Flags().BoolVar(&useGAR, "use-gar", false, "Use Google Artifact Registry image names")
+	exportCmd.Flags().BoolVar(&smartEnabled, "smart", false, "Export only the services smart orchestration decides to build")
+	exportCmd.Flags().BoolVar(&gitTrack, "git-track", false, "Enable git change tracking")
+	exportCmd.Flags().IntVar(&depth, "depth", 2, "Git tracking depth (0 for full history, default 2)")
+	exportCmd.Flags().BoolVar(&cacheEnabled, "cache", false, "Use the build cache when deciding what to build")
+	exportCmd.Flags().BoolVar(&forceRebuild, "force", false, "Export every service, ignoring cache and change detection")
+	exportCmd.Flags().BoolVar(&trackBaseImagesFlag, "track-base-images", false, "Rebuild services whose base image digest moved since their last build")
+	exportCmd.Flags().BoolVar(&baseImagesInsecure, "base-images-insecure", false, "Resolve base images over plain HTTP; localhost always is (overrides base_images.insecure)")
+	exportCmd.Flags().BoolVar(&versioning, "versioning", false, "Compute per-service semantic versions from conventional commits and use them as tags")
+}
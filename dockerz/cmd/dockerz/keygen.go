@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/addy-47/dockerz/internal/sign"
+	"github.com/spf13/cobra"
+)
+
+var keyPrefix string
+
+var keygenCmd = &cobra.Command{
+	Use:   "generate-key-pair",
+	Short: "Create a key pair for signing images",
+	Long: `Create an ECDSA P-256 key pair for 'dockerz build --sign-key' and 'dockerz verify-signature'.
+
+The private key is written unencrypted as PKCS#8 PEM with mode 0600; keep it out of the
+repository and in your CI secret store. The public key is a PKIX PEM file that
+'cosign verify --key' accepts as well. Existing files are never overwritten.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		privatePath, publicPath := keyPrefix+".key", keyPrefix+".pub"
+		for _, path := range []string{privatePath, publicPath} {
+			if _, err := os.Stat(path); err == nil {
+				log.Fatalf("%s already exists", path)
+			}
+		}
+
+		private, public, err := sign.GenerateKeyPair()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if err := os.WriteFile(privatePath, private, 0600); err != nil {
+			log.Fatalf("Failed to write private key: %v", err)
+		}
+		if err := os.WriteFile(publicPath, public, 0644); err != nil {
+			log.Fatalf("Failed to write public key: %v", err)
+		}
+		fmt.Printf("Private key written to %s\n", privatePath)
+		fmt.Printf("Public key written to %s\n", publicPath)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(keygenCmd)
+
+	keygenCmd.Flags().StringVar(&keyPrefix, "output-key-prefix", "dockerz", "File name prefix of the key pair (<prefix>.key and <prefix>.pub)")
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/lint"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintFormat string
+	lintOutput string
+	lintFailOn string
+	lintGate   string
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [Dockerfile...]",
+	Short: "Check the services' Dockerfiles for common mistakes",
+	Long: `Lint the Dockerfile of every discovered service, or the Dockerfiles given as arguments.
+
+Rules:
+  latest-tag           warning  base image without a tag or digest, or tagged latest
+  apt-cleanup          warning  apt-get install without removing /var/lib/apt/lists
+  missing-user         warning  built stage runs as root
+  add-url              error    ADD of an http(s) URL without --checksum
+  missing-healthcheck  info     built stage has no HEALTHCHECK
+
+Rules are configured in the lint: section of build.yaml (ignore, severity) and per
+service with lint_ignore. A "# dockerz:ignore" comment suppresses every rule for the
+instruction below it, "# dockerz:ignore latest-tag,add-url" only the rules listed.
+
+Exits with status 1 when a finding is at least as severe as --fail-on or a Dockerfile
+cannot be linted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger, err := logging.NewLogger("")
+		if err != nil {
+			log.Fatalf("Failed to create logger: %v", err)
+		}
+		// stdout may carry the JSON or SARIF report
+		logger.SetConsoleOutput(os.Stderr)
+
+		if !lint.ValidSeverity(lintFailOn) {
+			log.Fatalf("Invalid --fail-on '%s': must be one of %s", lintFailOn, strings.Join(lint.Severities, ", "))
+		}
+
+		cfg, err := config.ReadConfig(configPath, profileName)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		applyBuildFlags(cmd, cfg)
+
+		// Lint failures still write the report; the exit status is decided at the end
+		exitStatus := 0
+		var findings []lint.Finding
+		if len(args) > 0 {
+			options := lint.Options{Ignore: cfg.Lint.Ignore, Severity: cfg.Lint.Severity}
+			for _, path := range args {
+				fileFindings, err := lint.LintFile(path, options)
+				if err != nil {
+					log.Fatalf("Failed to lint %s: %v", path, err)
+				}
+				findings = append(findings, fileFindings...)
+			}
+		} else {
+			inputFile := cfg.InputChangedServices
+			if cmd.Flags().Changed("input-changed-services") {
+				inputFile = inputChangedServices
+			}
+			discoveryResult, err := discovery.DiscoverServices(cfg, "latest", inputFile)
+			if err != nil {
+				log.Fatalf("Failed to discover services: %v", err)
+			}
+			for _, discoveryErr := range discoveryResult.Errors {
+				logger.Warn(logging.CATEGORY_DISCOVERY, fmt.Sprintf("Discovery error: %v", discoveryErr))
+			}
+			// The input file adds to the other discovery sources; --input-only restricts linting to it
+			if inputOnly {
+				if inputFile == "" {
+					log.Fatalf("--input-only requires an input changed services file")
+				}
+				if discoveryResult.Services, err = discovery.SelectListed(discoveryResult.Services, inputFile); err != nil {
+					log.Fatalf("Failed to read input changed services file: %v", err)
+				}
+			}
+			var failures []error
+			findings, failures = lintServices(cfg, discoveryResult.Services)
+			for _, failure := range failures {
+				logger.Error(logging.CATEGORY_CONFIG, failure.Error())
+			}
+			if len(failures) > 0 {
+				exitStatus = 1
+			}
+		}
+
+		var data []byte
+		switch lintFormat {
+		case "text":
+			var text strings.Builder
+			for _, finding := range findings {
+				fmt.Fprintln(&text, finding)
+			}
+			counts := lint.Count(findings)
+			fmt.Fprintf(&text, "%d error(s), %d warning(s), %d info\n", counts[lint.SeverityError], counts[lint.SeverityWarning], counts[lint.SeverityInfo])
+			data = []byte(text.String())
+		case "json":
+			if findings == nil {
+				findings = []lint.Finding{}
+			}
+			data, err = json.MarshalIndent(findings, "", "  ")
+			data = append(data, '\n')
+		case "sarif":
+			data, err = lint.SARIF(findings, dockerzVersion)
+			data = append(data, '\n')
+		default:
+			log.Fatalf("Invalid format '%s': must be text, json or sarif", lintFormat)
+		}
+		if err != nil {
+			log.Fatalf("Failed to encode findings: %v", err)
+		}
+
+		if lintOutput == "" {
+			os.Stdout.Write(data)
+		} else {
+			if dir := filepath.Dir(lintOutput); dir != "." {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					log.Fatalf("Failed to create %s: %v", dir, err)
+				}
+			}
+			if err := os.WriteFile(lintOutput, data, 0644); err != nil {
+				log.Fatalf("Failed to write %s: %v", lintOutput, err)
+			}
+			logger.Info(logging.CATEGORY_CONFIG, fmt.Sprintf("%d findings written to %s", len(findings), lintOutput))
+		}
+
+		if lint.Fails(findings, lintFailOn) {
+			exitStatus = 1
+		}
+		if exitStatus != 0 {
+			os.Exit(exitStatus)
+		}
+	},
+}
+
+// lintServices lints the Dockerfile of every service with the global and per-service settings.
+// Services sharing a Dockerfile and target are linted once.
+func lintServices(cfg *config.Config, services []discovery.DiscoveredService) ([]lint.Finding, []error) {
+	var findings []lint.Finding
+	var failures []error
+	linted := make(map[string]bool)
+	for _, service := range services {
+		dockerfile := service.Dockerfile
+		if dockerfile == "" {
+			dockerfile = discovery.DefaultDockerfile
+		}
+		path := filepath.Join(service.Path, dockerfile)
+
+		options := lint.Options{Ignore: cfg.Lint.Ignore, Severity: cfg.Lint.Severity, Target: service.Target}
+		if serviceCfg, ok := cfg.ServiceConfig(service.Key()); ok {
+			options.Ignore = append(append([]string(nil), cfg.Lint.Ignore...), serviceCfg.LintIgnore...)
+		}
+		id := path + "#" + service.Target + "#" + strings.Join(options.Ignore, ",")
+		if linted[id] {
+			continue
+		}
+		linted[id] = true
+
+		serviceFindings, err := lint.LintFile(path, options)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed to lint %s: %w", service.Key(), err))
+			continue
+		}
+		for i := range serviceFindings {
+			serviceFindings[i].Service = service.Key()
+		}
+		findings = append(findings, serviceFindings...)
+	}
+	return findings, failures
+}
+
+// lintGateCheck lints the services about to be built and stops the build when a finding
+// reaches the gate severity
+func lintGateCheck(cfg *config.Config, gate string, services []discovery.DiscoveredService, logger *logging.Logger) {
+	logger.PrintSection("DOCKERFILE LINT")
+	findings, failures := lintServices(cfg, services)
+	for _, failure := range failures {
+		logger.Error(logging.CATEGORY_CONFIG, failure.Error())
+	}
+	for _, finding := range findings {
+		if finding.Severity == lint.SeverityError {
+			logger.Error(logging.CATEGORY_CONFIG, finding.String())
+		} else {
+			logger.Warn(logging.CATEGORY_CONFIG, finding.String())
+		}
+	}
+	counts := lint.Count(findings)
+	logger.Info(logging.CATEGORY_CONFIG, fmt.Sprintf("Lint: %d error(s), %d warning(s), %d info", counts[lint.SeverityError], counts[lint.SeverityWarning], counts[lint.SeverityInfo]))
+	if len(failures) > 0 || lint.Fails(findings, gate) {
+		log.Fatalf("Dockerfile lint failed (gate: %s). Fix the findings, suppress them in build.yaml or with '# %s', or use --lint-gate off.", gate, lint.IgnoreDirective)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVarP(&configPath, "config", "c", "build.yaml", "Path to the build.yaml configuration file")
+	lintCmd.Flags().StringVar(&profileName, "profile", "", "Configuration profile to apply from the profiles: section")
+	lintCmd.Flags().StringVar(&lintFormat, "format", "text", "Output format: text, json or sarif")
+	lintCmd.Flags().StringVarP(&lintOutput, "output", "o", "", "Write the findings here instead of stdout")
+	lintCmd.Flags().StringVar(&lintFailOn, "fail-on", lint.SeverityError, "Lowest severity that makes lint exit with status 1: error, warning, info or off")
+	lintCmd.Flags().StringVar(&inputChangedServices, "input-changed-services", "", "Add the services listed in this file to the discovered ones")
+	lintCmd.Flags().BoolVar(&inputOnly, "input-only", false, "Lint only the services listed in the input changed services file, ignoring other discovery sources")
+	lintCmd.Flags().StringVar(&servicesDir, "services-dir", "", "Comma-separated list of directories to scan for service definitions (overrides config file)")
+}
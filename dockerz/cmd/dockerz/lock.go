@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/baseimage"
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/lock"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/registry"
+)
+
+var (
+	lockEnabled bool
+	lockedBuild bool
+	lockFile    string
+)
+
+// lockPath returns the lock file of the configuration
+func lockPath(cfg *config.Config) string {
+	if cfg.Lock.File != "" {
+		return cfg.Lock.File
+	}
+	return lock.DefaultPath
+}
+
+// lockEntries resolves the lock entry of every service: its image, tags, build args,
+// platforms, content hash and base image digests. Base images already resolved by base
+// image tracking are reused; unresolvable base images are left out of the entry.
+func lockEntries(cfg *config.Config, services []discovery.DiscoveredService, logger *logging.Logger) map[string]lock.Service {
+	var untracked []discovery.DiscoveredService
+	for _, service := range services {
+		if service.BaseImages == nil {
+			untracked = append(untracked, service)
+		}
+	}
+	bases, failures := serviceBaseImages(cfg, untracked)
+	digests, resolveFailures := resolveBaseImages(baseimage.NewResolver(registry.NewClient(cfg.BaseImages.Insecure)), bases)
+	for _, failure := range append(failures, resolveFailures...) {
+		logger.Warn(logging.CATEGORY_BUILD, failure.Error())
+	}
+
+	entries := make(map[string]lock.Service, len(services))
+	for _, service := range services {
+		key := service.Key()
+		hash, err := serviceContentHash(cfg, service)
+		if err != nil {
+			logger.Warn(logging.CATEGORY_BUILD, err.Error())
+		}
+
+		current := service.BaseImages
+		if current == nil {
+			current = make(map[string]string)
+			for _, image := range bases[key] {
+				if digest, ok := digests[image]; ok {
+					current[image] = digest
+				}
+			}
+		}
+
+		tags := service.Tags
+		if len(tags) == 0 {
+			tags = []string{service.Tag}
+		}
+		entries[key] = lock.Service{
+			Name:        service.Name,
+			Image:       strings.TrimSuffix(builder.ImageReference(cfg, service.ImageName, tags[0]), ":"+tags[0]),
+			Tags:        tags,
+			BuildArgs:   service.BuildArgs,
+			Platforms:   service.Platforms,
+			ContentHash: hash,
+			BaseImages:  current,
+		}
+	}
+	return entries
+}
+
+// serviceContentHash hashes the build context and Dockerfile of a service, leaving out the
+// lock file, dockerz's own state and build.log, which change with every build
+func serviceContentHash(cfg *config.Config, service discovery.DiscoveredService) (string, error) {
+	context := service.Path
+	if service.Context != "" {
+		context = service.Context
+	}
+	dockerfile := service.Dockerfile
+	if dockerfile == "" {
+		dockerfile = discovery.DefaultDockerfile
+	}
+	return lock.ContentHash(context, filepath.Join(service.Path, dockerfile), []string{lockPath(cfg), ".dockerz", "build.log"})
+}
+
+// checkLocked stops a --locked build when a service to build is not in the lock file or
+// any of its inputs resolves differently from the lock
+func checkLocked(cfg *config.Config, services []discovery.DiscoveredService, logger *logging.Logger) {
+	path := lockPath(cfg)
+	logger.PrintSection("LOCK FILE CHECK")
+	file, err := lock.Read(path)
+	if err != nil {
+		log.Fatalf("--locked requires a lock file: %v", err)
+	}
+
+	entries := lockEntries(cfg, services, logger)
+	failed := 0
+	for _, service := range services {
+		key := service.Key()
+		current := entries[key]
+		locked, ok := file.Services[key]
+		if !ok {
+			logger.Error(logging.CATEGORY_BUILD, fmt.Sprintf("%s: not in %s", key, path))
+			failed++
+			continue
+		}
+		diffs := lock.Diff(locked, current)
+		for _, diff := range diffs {
+			logger.Error(logging.CATEGORY_BUILD, fmt.Sprintf("%s: %s", key, diff))
+		}
+		if len(diffs) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Fatalf("%d service(s) resolve differently from %s. Rebuild without --locked to update it.", failed, path)
+	}
+	logger.Info(logging.CATEGORY_BUILD, fmt.Sprintf("%d service(s) match %s", len(services), path))
+}
+
+// updateLock records the services built successfully in the lock file, with the digest
+// they were pushed with
+func updateLock(cfg *config.Config, services []discovery.DiscoveredService, results []builder.BuildResult, logger *logging.Logger) {
+	path := lockPath(cfg)
+	file, err := lock.Load(path)
+	if err != nil {
+		logger.Warn(logging.CATEGORY_BUILD, fmt.Sprintf("%v; lock file not updated", err))
+		return
+	}
+
+	built := make(map[string]builder.BuildResult)
+	for _, result := range results {
+		if result.Status == "success" {
+			built[result.Service] = result
+		}
+	}
+	var buildServices []discovery.DiscoveredService
+	for _, service := range services {
+		if _, ok := built[service.Key()]; ok {
+			buildServices = append(buildServices, service)
+		}
+	}
+	if len(buildServices) == 0 {
+		return
+	}
+
+	for key, entry := range lockEntries(cfg, buildServices, logger) {
+		if result := built[key]; result.PushStatus == "success" {
+			entry.Digest = result.Digest
+		}
+		file.Update(key, entry)
+	}
+	if err := lock.Write(path, file); err != nil {
+		logger.Warn(logging.CATEGORY_BUILD, err.Error())
+		return
+	}
+	logger.Info(logging.CATEGORY_BUILD, fmt.Sprintf("Lock file updated: %s (%d services)", path, len(buildServices)))
+}
@@ -7,19 +7,29 @@ import (
 	"strings"
 	"time"
 
+	"github.com/addy-47/dockerz/internal/baseimage"
 	"github.com/addy-47/dockerz/internal/builder"
-	"github.com/addy-47/dockerz/internal/cache"
+	"github.com/addy-47/dockerz/internal/cgroup"
+	"github.com/addy-47/dockerz/internal/ci"
 	"github.com/addy-47/dockerz/internal/config"
 	"github.com/addy-47/dockerz/internal/discovery"
-	"github.com/addy-47/dockerz/internal/git"
+	"github.com/addy-47/dockerz/internal/lint"
 	"github.com/addy-47/dockerz/internal/logging"
-	"github.com/addy-47/dockerz/internal/smart"
+	"github.com/addy-47/dockerz/internal/report"
+	"github.com/addy-47/dockerz/internal/shard"
+	"github.com/addy-47/dockerz/internal/tagging"
+	"github.com/addy-47/dockerz/internal/tracing"
+	"github.com/addy-47/dockerz/internal/validate"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
+// dockerzVersion is the released version, also advertised by build agents
+const dockerzVersion = "2.75.0"
+
 var (
 	configPath            string
+	profileName           string
 	maxProcesses          int
 	gitTrack              bool
 	depth                 int
@@ -35,6 +45,9 @@ var (
 	useGAR                bool
 	pushToGAR             bool
 	servicesDir           string
+	versioning            bool
+	skipValidation        bool
+	inputOnly             bool
 	version               bool
 )
 
@@ -64,7 +77,7 @@ var rootCmd = &cobra.Command{
 	Long:  `Dockerz is a tool for building and pushing multiple Docker images in parallel based on a build.yaml configuration file.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if version {
-			fmt.Println("dockerz version 2.75.0")
+			fmt.Println("dockerz version " + dockerzVersion)
 			return
 		}
 		PrintDockerzBanner()
@@ -118,19 +131,32 @@ All flags can override corresponding settings in the configuration file.`,
 		// Print startup banner with configuration
 		logger.PrintBanner("DOCKERZ BUILD START", []string{
 			fmt.Sprintf("Config: %s", configPath),
+			fmt.Sprintf("Profile: %s", profileName),
 			fmt.Sprintf("Smart Features: %v", smartEnabled),
 			fmt.Sprintf("Git Tracking: %v", gitTrack),
 			fmt.Sprintf("Cache Enabled: %v", cacheEnabled),
 			fmt.Sprintf("Force Rebuild: %v", forceRebuild),
+			fmt.Sprintf("Host: %s", builder.HostResources()),
+			fmt.Sprintf("Cgroup: %s", builder.CgroupResources(cgroup.Read())),
 		})
 
+		// Spans are recorded from the start; whether they are exported is only known once the config is loaded
+		root := tracing.Begin("dockerz build", tracing.String("dockerz.config", configPath), tracing.String("dockerz.profile", profileName))
+
 		// Load configuration
 		logger.Info(logging.CATEGORY_CONFIG, fmt.Sprintf("Loading config from %s", configPath))
-		cfg, err := config.LoadConfig(configPath)
+		configSpan := tracing.Start("config.load", root, tracing.String("dockerz.config", configPath))
+		// Checks are left to validation below unless it is skipped
+		loadConfig := config.ReadConfig
+		if skipValidation {
+			loadConfig = config.LoadConfig
+		}
+		cfg, err := loadConfig(configPath, profileName)
 		if err != nil {
 			logger.Error(logging.CATEGORY_CONFIG, fmt.Sprintf("Failed to load config: %v", err))
 			log.Fatalf("Failed to load config: %v", err)
 		}
+		configSpan.End()
 
 		// Validate GAR settings if use_gar is True
 		if cfg.UseGAR {
@@ -151,44 +177,39 @@ All flags can override corresponding settings in the configuration file.`,
 		}
 
 		// Override config with CLI flags if provided
-		if cmd.Flags().Changed("git-track") {
-			cfg.GitTrack = gitTrack
-			cfg.GitTrackDepth = depth
-		}
-		if cmd.Flags().Changed("cache") {
-			cfg.Cache = cacheEnabled
-		}
-		if cmd.Flags().Changed("force") {
-			cfg.Force = forceRebuild
-		}
-		if cmd.Flags().Changed("smart") {
-			cfg.Smart = smartEnabled
-		}
-		if cmd.Flags().Changed("project") {
-			cfg.Project = project
-		}
-		if cmd.Flags().Changed("region") {
-			cfg.Region = region
-		}
-		if cmd.Flags().Changed("gar") {
-			cfg.GAR = gar
-		}
-		if cmd.Flags().Changed("global-tag") {
-			cfg.GlobalTag = globalTag
+		applyBuildFlags(cmd, cfg)
+
+		// Validate the configuration (after CLI overrides) before doing any work
+		if !skipValidation {
+			logger.Info(logging.CATEGORY_CONFIG, "Validating configuration")
+			report := validate.Validate(configPath, profileName, cfg)
+			for _, issue := range report.Issues {
+				if issue.Severity == validate.SeverityError {
+					logger.Error(logging.CATEGORY_CONFIG, issue.String())
+				} else {
+					logger.Warn(logging.CATEGORY_CONFIG, issue.String())
+				}
+			}
+			if report.HasErrors() {
+				log.Fatalf("Configuration is invalid: %d error(s). Run 'dockerz validate' for details or use --skip-validation.", report.Errors())
+			}
 		}
-		if cmd.Flags().Changed("use-gar") {
-			cfg.UseGAR = useGAR
+
+		var buildShard shard.Shard
+		if shardSpec != "" {
+			if buildShard, err = shard.Parse(shardSpec); err != nil {
+				log.Fatalf("%v", err)
+			}
 		}
-		if cmd.Flags().Changed("push-to-gar") {
-			cfg.PushToGAR = pushToGAR
+
+		// CI integration: collapsible log sections now, outputs once the build finishes
+		provider, err := ci.Resolve(cfg.CI.Provider)
+		if err != nil {
+			log.Fatalf("Invalid CI provider: %v", err)
 		}
-		if servicesDir != "" {
-			// Parse comma-separated services directories
-			dirs := strings.Split(servicesDir, ",")
-			for i, dir := range dirs {
-				dirs[i] = strings.TrimSpace(dir)
-			}
-			cfg.ServicesDir = dirs
+		if provider != ci.ProviderNone {
+			logger.Info(logging.CATEGORY_CONFIG, fmt.Sprintf("CI integration: %s", provider))
+			logger.SetSections(ci.Sections(provider))
 		}
 
 		// Handle input/output changed services files with proper priority:
@@ -217,17 +238,45 @@ All flags can override corresponding settings in the configuration file.`,
 		// Discover services (unified discovery including input file)
 		logger.PrintSection("SERVICE DISCOVERY")
 		logger.Info(logging.CATEGORY_DISCOVERY, fmt.Sprintf("Discovering services from input file: %s", effectiveInputFile))
+		discoverySpan := tracing.Start("discovery", root)
 		discoveryResult, err := discovery.DiscoverServices(cfg, defaultTag, effectiveInputFile)
 		if err != nil {
 			logger.Error(logging.CATEGORY_DISCOVERY, fmt.Sprintf("Failed to discover services: %v", err))
 			log.Fatalf("Failed to discover services: %v", err)
 		}
 
+		// The input file usually adds to the other discovery sources; --input-only restricts the build to it
+		if inputOnly {
+			if effectiveInputFile == "" {
+				log.Fatalf("--input-only requires an input changed services file")
+			}
+			listed, err := discovery.SelectListed(discoveryResult.Services, effectiveInputFile)
+			if err != nil {
+				log.Fatalf("Failed to read input changed services file: %v", err)
+			}
+			logger.Info(logging.CATEGORY_DISCOVERY, fmt.Sprintf("Building only the %d services listed in %s", len(listed), effectiveInputFile))
+			discoveryResult.Services = listed
+		}
+
+		// Compute per-service semantic versions from conventional commits
+		if cfg.Versioning.Enabled {
+			logger.Info(logging.CATEGORY_GIT, "Computing service versions from conventional commits")
+			applyServiceVersions(cfg, discoveryResult.Services, logger)
+		}
+
+		// Resolve tag templates (global tags: and per-service tags:) into concrete tags
+		if err := tagging.ApplyTags(cfg, discoveryResult.Services, tagging.LoadGitInfo()); err != nil {
+			logger.Error(logging.CATEGORY_DISCOVERY, fmt.Sprintf("Failed to resolve tags: %v", err))
+			log.Fatalf("Failed to resolve tags: %v", err)
+		}
+		discoverySpan.SetAttributes(tracing.Int("dockerz.services", int64(len(discoveryResult.Services))))
+		discoverySpan.End()
+
 		logger.Info(logging.CATEGORY_DISCOVERY, fmt.Sprintf("Found %d services", len(discoveryResult.Services)))
 		if len(discoveryResult.Services) > 0 {
 			serviceList := make([]string, len(discoveryResult.Services))
 			for i, service := range discoveryResult.Services {
-				serviceList[i] = fmt.Sprintf("  %s (%s)", service.Name, service.Path)
+				serviceList[i] = fmt.Sprintf("  %s (%s) tags: %s", service.Name, service.Key(), strings.Join(service.Tags, ", "))
 			}
 			logger.Info(logging.CATEGORY_DISCOVERY, "Services discovered:")
 			for _, service := range serviceList {
@@ -247,96 +296,41 @@ All flags can override corresponding settings in the configuration file.`,
 			log.Printf("Discovery error: %v", discoveryErr)
 		}
 
-		// Smart orchestration if enabled (disabled by default for basic builds)
-		var servicesToBuild []discovery.DiscoveredService
-		var changedFiles map[string][]string // Track changed files for output
-
-		if cfg.Smart {
-			logger.PrintSection("SMART ORCHESTRATION")
-			logger.Info(logging.CATEGORY_SMART, "Smart build orchestration enabled")
-			logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("Git tracking: %v (depth: %d)", cfg.GitTrack, cfg.GitTrackDepth))
-			logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("Cache enabled: %v", cfg.Cache))
-			logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("Force rebuild: %v", cfg.Force))
-
-			smartConfig := &smart.SmartConfig{
-				Enabled:       cfg.Smart,
-				GitTracking:   cfg.GitTrack,
-				GitTrackDepth: cfg.GitTrackDepth,
-				CacheEnabled:  cfg.Cache,
-				CacheLevel:    cache.RegistryCacheLevel, // Default to registry cache
-				CacheTTL:      24 * time.Hour,           // 24 hours TTL
-				ForceRebuild:  cfg.Force,
-			}
-
-			orchestrator := smart.NewOrchestrator(smartConfig)
-			orchestrator.SetLogger(logger)
-			result, err := orchestrator.OrchestrateBuilds(cfg, discoveryResult.Services)
+		// A moved base image digest is a reason to rebuild, like a source change
+		var baseImages *baseimage.Store
+		if cfg.BaseImages.Track {
+			baseImages, err = baseimage.Load(baseimage.DefaultDir)
 			if err != nil {
-				logger.Error(logging.CATEGORY_SMART, fmt.Sprintf("Failed to orchestrate builds: %v", err))
-				log.Fatalf("Failed to orchestrate builds: %v", err)
+				logger.Warn(logging.CATEGORY_DISCOVERY, err.Error())
 			}
+			trackBaseImages(cfg, discoveryResult.Services, baseImages, logger)
+		}
 
-			logger.Info(logging.CATEGORY_SMART, orchestrator.GetStats(result))
-
-			// Log detailed decisions for each service
-			logger.Info(logging.CATEGORY_SMART, "Service build decisions:")
-			for serviceName, decision := range result.Decisions {
-				switch decision {
-				case smart.ForceBuild:
-					logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("  %s: FORCE_BUILD (configured)", serviceName))
-				case smart.ConditionalBuild:
-					logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("  %s: BUILD (changes detected)", serviceName))
-				case smart.SkipBuild:
-					logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("  %s: SKIP (no changes)", serviceName))
-				}
-			}
+		// Smart orchestration if enabled (disabled by default for basic builds)
+		servicesToBuild, changedFiles, skipReasons := selectServices(cfg, discoveryResult, logger)
 
-			// Filter services that need building
-			for i, service := range discoveryResult.Services {
-				if decision, exists := result.Decisions[service.Name]; exists && decision != smart.SkipBuild {
-					servicesToBuild = append(servicesToBuild, service)
-					// Update service with smart info
-					if i < len(result.ServiceStates) {
-						state := result.ServiceStates[i]
-						servicesToBuild[len(servicesToBuild)-1].CurrentHash = state.CurrentHash
-						servicesToBuild[len(servicesToBuild)-1].ChangedFiles = state.ChangedFiles
-						servicesToBuild[len(servicesToBuild)-1].NeedsBuild = true
-					}
-				}
-			}
-		} else {
-			logger.PrintSection("GIT TRACKING (NON-SMART)")
-			// For non-smart builds, build all services but check for git changes if requested
-			servicesToBuild = discoveryResult.Services
-
-			// If git tracking is enabled but smart is disabled, check for changes
-			if cfg.GitTrack {
-				logger.Info(logging.CATEGORY_GIT, fmt.Sprintf("Git tracking enabled (depth: %d)", cfg.GitTrackDepth))
-				changedFiles = make(map[string][]string)
-				gitTracker := git.NewTracker()
-				changesFound := false
-				for _, service := range servicesToBuild {
-					depth := cfg.GitTrackDepth
-					if depth == 0 {
-						depth = 2
-					}
-					if files, err := gitTracker.GetChangedFiles(service.Path, depth); err == nil && len(files) > 0 {
-						changedFiles[service.Path] = files
-						changesFound = true
-						logger.Info(logging.CATEGORY_GIT, fmt.Sprintf("Changes found in %s: %d files", service.Name, len(files)))
-					}
-				}
-				if !changesFound {
-					logger.Info(logging.CATEGORY_GIT, "No git changes detected in any service")
-				}
-			} else {
-				logger.Info(logging.CATEGORY_GIT, "Git tracking disabled, building all services")
-			}
+		// With --shard, build only this shard's part of the services
+		var currentShard *report.Shard
+		var elsewhere map[string]bool
+		if shardSpec != "" {
+			servicesToBuild, currentShard, elsewhere = applyShard(buildShard, servicesToBuild, skipReasons, logger)
+		}
 
-			// Mark all as needing build when smart features disabled
-			for i := range servicesToBuild {
-				servicesToBuild[i].NeedsBuild = true
-			}
+		// Lint the Dockerfiles about to be built when a gate is configured
+		gate := cfg.Lint.Gate
+		if cmd.Flags().Changed("lint-gate") {
+			gate = lintGate
+		}
+		if gate != "" && !lint.ValidSeverity(gate) {
+			log.Fatalf("Invalid lint gate '%s': must be one of %s", gate, strings.Join(lint.Severities, ", "))
+		}
+		if gate != "" && gate != lint.SeverityOff {
+			lintGateCheck(cfg, gate, servicesToBuild, logger)
+		}
+
+		// --locked builds only what the lock file describes
+		if lockedBuild {
+			checkLocked(cfg, servicesToBuild, logger)
 		}
 
 		// Root feature: Write changed services to file if requested (works with any command)
@@ -384,20 +378,81 @@ All flags can override corresponding settings in the configuration file.`,
 			maxProcs = cfg.MaxProcesses
 		}
 
+		// With --agents, this build coordinates and the agents build
+		var runner builder.Runner
+		if agentURLs != "" {
+			coordinator := newCoordinator(cfg, logger)
+			runner = coordinator
+			maxProcs = coordinator.Capacity()
+		}
+
 		startBuildTime := time.Now()
-		_, summary := builder.BuildImages(cfg, filteredResult, maxProcs)
+		results, summary := builder.BuildImagesWith(cfg, filteredResult, maxProcs, runner)
 		buildDuration := time.Since(startBuildTime)
 
+		// Successful builds move the services' base image records to the digests just built from
+		if baseImages != nil {
+			recordBaseImages(discoveryResult.Services, results, baseImages, logger)
+		}
+
+		// Sign the pushed digests and attach their provenance
+		var signStatuses map[string]string
+		if cfg.Signing.Key != "" {
+			signStatuses = signImages(cfg, servicesToBuild, results, logger)
+		}
+		signFailures := 0
+		for _, status := range signStatuses {
+			if status == "failed" {
+				signFailures++
+			}
+		}
+
+		// The lock file records what went into each image; --locked builds leave it unchanged
+		if cfg.Lock.Enabled && !lockedBuild {
+			updateLock(cfg, servicesToBuild, results, logger)
+		}
+
+		// Record released versions as git tags once their images are pushed
+		if cfg.Versioning.Enabled && cfg.Versioning.CreateGitTags {
+			if !(cfg.UseGAR && cfg.PushToGAR) {
+				logger.Warn(logging.CATEGORY_GIT, "create_git_tags requires pushing images; no git tags created")
+			} else {
+				createVersionTags(cfg, servicesToBuild, results, logger)
+			}
+		}
+
+		logger.EndSection()
+
 		// Log build summary with metrics
-		logger.PrintSummary(map[string]interface{}{
+		summaryData := map[string]interface{}{
 			"total_services":      len(discoveryResult.Services),
 			"services_built":      len(servicesToBuild),
 			"successful_builds":   summary.SuccessfulBuilds,
 			"failed_builds":       summary.FailedBuilds,
 			"skipped_builds":      len(discoveryResult.Services) - len(servicesToBuild),
 			"build_duration":      buildDuration,
-			"cache_effectiveness": fmt.Sprintf("%.1f%%", float64(summary.SuccessfulBuilds)/float64(len(servicesToBuild))*100),
-		})
+			"sequential_duration": summary.Sequential.Round(time.Second),
+			"time_saved":          (summary.Sequential - summary.Duration).Round(time.Second),
+		}
+		if summary.Estimated > 0 {
+			summaryData["estimated_duration"] = summary.Estimated.Round(time.Second)
+		}
+		if ratio, ok := cacheHitRatio(results); ok {
+			summaryData["layer_cache_hit_ratio"] = fmt.Sprintf("%.1f%%", ratio)
+		}
+		logger.PrintSummary(summaryData)
+
+		exportMetrics(cfg, collectMetrics(cfg, discoveryResult.Services, results, skipReasons, summary), logger)
+		exportTraces(cfg, root, logger)
+		publishCI(cfg, provider, collectCIRun(cfg, discoveryResult.Services, results, skipReasons, summary), logger)
+
+		// Shards always write a report so 'dockerz report merge' can combine them
+		if path := reportPath; path != "" || currentShard != nil {
+			if path == "" {
+				path = defaultReportPath(currentShard)
+			}
+			writeReport(path, collectReport(cfg, discoveryResult.Services, results, skipReasons, signStatuses, summary, currentShard, elsewhere), logger)
+		}
 
 		// Log final performance metrics
 		logger.PrintMetrics("Total Build", buildDuration, summary.SuccessfulBuilds+summary.FailedBuilds)
@@ -406,6 +461,9 @@ All flags can override corresponding settings in the configuration file.`,
 		if summary.FailedBuilds > 0 {
 			logger.Error(logging.CATEGORY_BUILD, fmt.Sprintf("Build completed with %d failures", summary.FailedBuilds))
 			os.Exit(1)
+		} else if signFailures > 0 {
+			logger.Error(logging.CATEGORY_BUILD, fmt.Sprintf("Build completed but %d images could not be signed", signFailures))
+			os.Exit(1)
 		} else {
 			logger.Info(logging.CATEGORY_BUILD, "Build completed successfully")
 		}
@@ -427,6 +485,7 @@ func init() {
 	rootCmd.Flags().BoolVarP(&version, "version", "v", false, "Print version information")
 
 	buildCmd.Flags().StringVarP(&configPath, "config", "c", "build.yaml", "Path to the build.yaml configuration file (default: build.yaml)")
+	buildCmd.Flags().StringVar(&profileName, "profile", "", "Configuration profile to apply from the profiles: section (overrides DOCKERZ_PROFILE)")
 	buildCmd.Flags().IntVarP(&maxProcesses, "max-processes", "m", 0, "Maximum number of parallel build processes (0 = use system default; overrides config file)")
 	buildCmd.Flags().StringVar(&project, "project", "", "Google Cloud Platform project ID for GAR integration (overrides config file)")
 	buildCmd.Flags().StringVar(&region, "region", "", "GCP region for GAR (e.g., us-central1, europe-west1; overrides config file)")
@@ -434,6 +493,7 @@ func init() {
 	buildCmd.Flags().StringVar(&globalTag, "global-tag", "", "Global Docker tag to apply to all built images (overrides config file and git commit ID)")
 	buildCmd.Flags().StringVar(&servicesDir, "services-dir", "", "Comma-separated list of directories to scan for service definitions (overrides config file)")
 	buildCmd.Flags().StringVar(&inputChangedServices, "input-changed-services", "", "Path to a file containing a newline-separated list of service names to build selectively")
+	buildCmd.Flags().BoolVar(&inputOnly, "input-only", false, "Build only the services listed in the input changed services file, ignoring other discovery sources")
 	buildCmd.Flags().StringVar(&outputChangedServices, "output-changed-services", "", "Path to output file where the list of changed services will be written for CI/CD integration")
 
 	buildCmd.Flags().BoolVar(&gitTrack, "git-track", false, "Enable git change tracking")
@@ -444,6 +504,28 @@ func init() {
 	buildCmd.Flags().BoolVar(&smartEnabled, "smart", false, "Enable smart build orchestration with automatic dependency analysis and optimization")
 	buildCmd.Flags().BoolVar(&useGAR, "use-gar", false, "Use Google Artifact Registry naming convention for image tags (requires GAR authentication)")
 	buildCmd.Flags().BoolVar(&pushToGAR, "push-to-gar", false, "Automatically push built images to Google Artifact Registry after successful builds")
+	buildCmd.Flags().BoolVar(&versioning, "versioning", false, "Compute per-service semantic versions from conventional commits and use them as tags")
+	buildCmd.Flags().BoolVar(&skipValidation, "skip-validation", false, "Skip configuration validation before building")
+	buildCmd.Flags().StringVar(&metricsFile, "metrics-file", "", "Write build metrics in OpenMetrics format to this file (overrides metrics.textfile)")
+	buildCmd.Flags().StringVar(&pushgateway, "pushgateway", "", "Push build metrics to this Pushgateway URL (overrides metrics.pushgateway)")
+	buildCmd.Flags().StringVar(&traceEndpoint, "trace-endpoint", "", "Export build trace spans to this OTLP/HTTP collector URL (overrides tracing.endpoint)")
+	buildCmd.Flags().StringVar(&traceFile, "trace-file", "", "Write build trace spans as OTLP JSON to this file (overrides tracing.file)")
+	buildCmd.Flags().StringVar(&agentURLs, "agents", "", "Comma-separated build agent URLs; builds run on the agents instead of the local Docker daemon")
+	buildCmd.Flags().StringVar(&agentToken, "agent-token", "", "Token to present to build agents (default: DOCKERZ_AGENT_TOKEN)")
+	buildCmd.Flags().BoolVar(&trackBaseImagesFlag, "track-base-images", false, "Resolve base image digests and rebuild services whose base image moved (overrides base_images.track)")
+	buildCmd.Flags().BoolVar(&baseImagesInsecure, "base-images-insecure", false, "Resolve base images over plain HTTP; localhost always is (overrides base_images.insecure)")
+	buildCmd.Flags().BoolVar(&lockEnabled, "lock", false, "Record the inputs and pushed digest of every successful build in the lock file (overrides lock.enabled)")
+	buildCmd.Flags().BoolVar(&lockedBuild, "locked", false, "Fail before building if a service's base images, content, build args or tags differ from the lock file")
+	buildCmd.Flags().StringVar(&lockFile, "lock-file", "", "Lock file path (overrides lock.file; default dockerz.lock)")
+	buildCmd.Flags().StringVar(&signKey, "sign-key", "", "Sign pushed image digests with this PEM private key (overrides signing.key)")
+	buildCmd.Flags().BoolVar(&provenanceEnabled, "provenance", false, "Attach a signed SLSA provenance attestation to pushed images (overrides signing.provenance)")
+	buildCmd.Flags().BoolVar(&signInsecure, "sign-insecure", false, "Use plain HTTP for the registry signatures are pushed to; localhost always is (overrides signing.insecure)")
+	buildCmd.Flags().StringVar(&lintGate, "lint-gate", "", "Lint Dockerfiles before building and stop at findings of this severity: error, warning, info or off (overrides lint.gate)")
+	buildCmd.Flags().StringVar(&shardSpec, "shard", "", "Build only shard i of N (e.g. 2/4); services are split by dependencies and --shard-history")
+	buildCmd.Flags().StringVar(&shardHistory, "shard-history", "", "Weight shards by the service durations in this build report, e.g. the last merged report (default: equal weights)")
+	buildCmd.Flags().StringVar(&shardPlan, "shard-plan", "", "Fail before building unless the shard plan has this ID (see 'dockerz report plan')")
+	buildCmd.Flags().StringVar(&reportPath, "report", "", "Write a JSON build report to this file (default dockerz-report-<i>-of-<N>.json with --shard)")
+	buildCmd.Flags().StringVar(&ciProvider, "ci", "", "CI integration outputs: auto, github, gitlab or none (overrides ci.provider; default auto)")
 }
 
 func main() {
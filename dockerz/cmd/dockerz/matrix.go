@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/baseimage"
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/ci"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/tagging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	matrixFormat     string
+	matrixOutput     string
+	matrixLevel      int
+	matrixBuildFlags string
+	matrixJobImage   string
+)
+
+var ciMatrixCmd = &cobra.Command{
+	Use:   "ci-matrix --format github-matrix|gitlab-child|json",
+	Short: "Generate a CI matrix or child pipeline with one build job per service",
+	Long: `Decide which services to build the way 'dockerz build' does (with --smart, only the
+services smart mode decides to build) and emit one CI job per service:
+
+  github-matrix  a strategy.matrix value as single-line JSON for fromJSON()
+  gitlab-child   a GitLab child pipeline YAML for trigger:include:artifact
+  json           the jobs as JSON
+
+Each job writes its service to an input file and runs
+'dockerz build --input-changed-services <file> --input-only', plus --build-flags.
+Dependencies are encoded as needs: between the jobs. A GitHub matrix cannot order its own
+entries, so entries carry needs and a dependency level; --level selects one level for
+chaining one matrix job per level.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger, err := logging.NewLogger("")
+		if err != nil {
+			log.Fatalf("Failed to create logger: %v", err)
+		}
+		// stdout carries the matrix
+		logger.SetConsoleOutput(os.Stderr)
+
+		cfg, err := config.ReadConfig(configPath, profileName)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		applyBuildFlags(cmd, cfg)
+
+		defaultTag := cfg.GlobalTag
+		if defaultTag == "" {
+			defaultTag = builder.GetGitCommitID()
+		}
+
+		inputFile := cfg.InputChangedServices
+		if cmd.Flags().Changed("input-changed-services") {
+			inputFile = inputChangedServices
+		}
+
+		discoveryResult, err := discovery.DiscoverServices(cfg, defaultTag, inputFile)
+		if err != nil {
+			log.Fatalf("Failed to discover services: %v", err)
+		}
+		for _, discoveryErr := range discoveryResult.Errors {
+			logger.Warn(logging.CATEGORY_DISCOVERY, fmt.Sprintf("Discovery error: %v", discoveryErr))
+		}
+		if err := tagging.ApplyTags(cfg, discoveryResult.Services, tagging.LoadGitInfo()); err != nil {
+			log.Fatalf("Failed to resolve image tags: %v", err)
+		}
+
+		dependencies := make(map[string][]string, len(discoveryResult.Services))
+		for _, service := range discoveryResult.Services {
+			dependencies[service.Key()] = service.DependsOn
+		}
+
+		if cfg.BaseImages.Track {
+			store, err := baseimage.Load(baseimage.DefaultDir)
+			if err != nil {
+				logger.Warn(logging.CATEGORY_DISCOVERY, err.Error())
+			}
+			trackBaseImages(cfg, discoveryResult.Services, store, logger)
+		}
+		selected, _, _ := selectServices(cfg, discoveryResult, logger)
+		var services []ci.MatrixService
+		for _, service := range discovery.SortByDependencies(selected) {
+			services = append(services, ci.MatrixService{
+				Key:       service.Key(),
+				Name:      service.Name,
+				Image:     builder.ImageReference(cfg, service.ImageName, service.Tag),
+				DependsOn: service.DependsOn,
+			})
+		}
+
+		jobs := ci.Jobs(services, dependencies, matrixBuildCommand(cmd))
+		data, err := ci.RenderMatrix(matrixFormat, jobs, matrixLevel, matrixJobImage)
+		if err != nil {
+			log.Fatalf("Failed to generate matrix: %v", err)
+		}
+
+		if matrixOutput == "" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(matrixOutput), 0755); err != nil {
+			log.Fatalf("Failed to create %s: %v", filepath.Dir(matrixOutput), err)
+		}
+		if err := os.WriteFile(matrixOutput, data, 0644); err != nil {
+			log.Fatalf("Failed to write %s: %v", matrixOutput, err)
+		}
+		logger.Info(logging.CATEGORY_CONFIG, fmt.Sprintf("Wrote %d jobs to %s", len(jobs), matrixOutput))
+	},
+}
+
+// matrixBuildCommand returns the dockerz build command the jobs run, with the same config and profile.
+// Smart mode is turned off because the matrix already decided what to build.
+func matrixBuildCommand(cmd *cobra.Command) string {
+	parts := []string{"dockerz", "build"}
+	if cmd.Flags().Changed("config") {
+		parts = append(parts, "-c", ci.ShellQuote(configPath))
+	}
+	if profileName != "" {
+		parts = append(parts, "--profile", ci.ShellQuote(profileName))
+	}
+	parts = append(parts, "--smart=false")
+	if matrixBuildFlags != "" {
+		parts = append(parts, matrixBuildFlags)
+	}
+	return strings.Join(parts, " ")
+}
+
+func init() {
+	rootCmd.AddCommand(ciMatrixCmd)
+
+	ciMatrixCmd.Flags().StringVarP(&configPath, "config", "c", "build.yaml", "Path to the build.yaml configuration file")
+	ciMatrixCmd.Flags().StringVar(&profileName, "profile", "", "Configuration profile to apply from the profiles: section")
+	ciMatrixCmd.Flags().StringVar(&matrixFormat, "format", ci.FormatGitHubMatrix, "Output format: "+strings.Join(ci.MatrixFormats, ", "))
+	ciMatrixCmd.Flags().StringVarP(&matrixOutput, "output", "o", "", "Write the matrix here instead of stdout")
+	ciMatrixCmd.Flags().IntVar(&matrixLevel, "level", -1, "github-matrix only: include just the jobs of this dependency level (-1 for all)")
+	ciMatrixCmd.Flags().StringVar(&matrixBuildFlags, "build-flags", "", "Extra flags for the 'dockerz build' command each job runs, e.g. \"--use-gar --push-to-gar\"")
+	ciMatrixCmd.Flags().StringVar(&matrixJobImage, "job-image", "", "gitlab-child only: image the generated jobs run in")
+	ciMatrixCmd.Flags().StringVar(&inputChangedServices, "input-changed-services", "", "Add the services listed in this file")
+	ciMatrixCmd.Flags().StringVar(&servicesDir, "services-dir", "", "Comma-separated list of directories to scan for service definitions (overrides config file)")
+	ciMatrixCmd.Flags().StringVar(&globalTag, "global-tag", "", "Global Docker tag to apply to all images (overrides config file and git commit ID)")
+	ciMatrixCmd.Flags().BoolVar(&useGAR, "use-gar", false, "Use Google Artifact Registry image names")
+	ciMatrixCmd.Flags().BoolVar(&smartEnabled, "smart", false, "Include only the services smart orchestration decides to build")
+	ciMatrixCmd.Flags().BoolVar(&gitTrack, "git-track", false, "Enable git change tracking")
+	ciMatrixCmd.Flags().IntVar(&depth, "depth", 2, "Git tracking depth (0 for full history, default 2)")
+	ciMatrixCmd.Flags().BoolVar(&cacheEnabled, "cache", false, "Use the build cache when deciding what to build")
+	ciMatrixCmd.Flags().BoolVar(&forceRebuild, "force", false, "Include every service, ignoring cache and change detection")
+	ciMatrixCmd.Flags().BoolVar(&trackBaseImagesFlag, "track-base-images", false, "Rebuild services whose base image digest moved since their last build")
+	ciMatrixCmd.Flags().BoolVar(&baseImagesInsecure, "base-images-insecure", false, "Resolve base images over plain HTTP; localhost always is (overrides base_images.insecure)")
+}
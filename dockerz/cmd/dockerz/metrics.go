@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/metrics"
+)
+
+var (
+	metricsFile string
+	pushgateway string
+)
+
+// collectMetrics gathers the metrics of a build run: the built services from their results
+// and every other discovered service as skipped, with the reason smart mode gave
+func collectMetrics(cfg *config.Config, services []discovery.DiscoveredService, results []builder.BuildResult, skipReasons map[string]string, summary builder.Summary) metrics.Run {
+	run := metrics.Run{
+		Timestamp:  time.Now(),
+		Duration:   summary.Duration,
+		Sequential: summary.Sequential,
+		Labels:     cfg.Metrics.Labels,
+	}
+
+	built := make(map[string]bool, len(results))
+	for _, result := range results {
+		built[result.Service] = true
+		status := result.Status
+		if status != "success" && status != "skipped" {
+			status = "failed"
+		}
+		run.Services = append(run.Services, metrics.Service{
+			Service:     result.Service,
+			Image:       result.Image,
+			Status:      status,
+			Build:       result.EndTime.Sub(result.StartTime),
+			Push:        result.PushDuration,
+			QueueWait:   result.QueueWait,
+			PushRetries: result.PushRetries,
+			CacheHits:   result.CacheHits,
+			CacheMisses: result.CacheMisses,
+			ImageSize:   result.ImageSize,
+		})
+	}
+
+	for _, service := range services {
+		if built[service.Key()] {
+			continue
+		}
+		reason := skipReasons[service.Key()]
+		if reason == "" {
+			reason = "not selected"
+		}
+		run.Services = append(run.Services, metrics.Service{
+			Service:    service.Key(),
+			Image:      builder.ImageReference(cfg, service.ImageName, service.Tag),
+			Status:     "skipped",
+			SkipReason: reason,
+		})
+	}
+	return run
+}
+
+// exportMetrics writes the metrics textfile and pushes to the Pushgateway when configured.
+// Export failures are logged but never fail the build.
+func exportMetrics(cfg *config.Config, run metrics.Run, logger *logging.Logger) {
+	if cfg.Metrics.Textfile != "" {
+		if err := metrics.WriteTextfile(cfg.Metrics.Textfile, run); err != nil {
+			logger.Warn(logging.CATEGORY_PERFORMANCE, err.Error())
+		} else {
+			logger.Info(logging.CATEGORY_PERFORMANCE, fmt.Sprintf("Metrics written to %s", cfg.Metrics.Textfile))
+		}
+	}
+	if cfg.Metrics.Pushgateway != "" {
+		if err := metrics.Push(cfg.Metrics.Pushgateway, cfg.Metrics.Job, run); err != nil {
+			logger.Warn(logging.CATEGORY_PERFORMANCE, err.Error())
+		} else {
+			logger.Info(logging.CATEGORY_PERFORMANCE, fmt.Sprintf("Metrics pushed to %s", cfg.Metrics.Pushgateway))
+		}
+	}
+}
+
+// cacheHitRatio returns the share of Dockerfile steps served from the layer cache
+func cacheHitRatio(results []builder.BuildResult) (float64, bool) {
+	hits, total := 0, 0
+	for _, result := range results {
+		hits += result.CacheHits
+		total += result.CacheHits + result.CacheMisses
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return float64(hits) / float64(total) * 100, true
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/addy-47/dockerz/internal/baseimage"
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/registry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	outdatedFormat   string
+	outdatedAll      bool
+	outdatedExitCode bool
+)
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "List services whose base images moved since their last build",
+	Long: `Resolve the base images of every discovered service through the registry API and
+compare their digests with the digests recorded by the last successful build with
+base image tracking (base_images.track or --track-base-images).
+
+Statuses:
+  updated  the tag now points to a different digest; the next smart build rebuilds the service
+  new      no digest recorded yet for this base image
+  unknown  the base image could not be resolved
+
+Only updated bases are listed unless --all is given. With --exit-code the command exits
+with status 1 when a base image moved.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger, err := logging.NewLogger("")
+		if err != nil {
+			log.Fatalf("Failed to create logger: %v", err)
+		}
+		// stdout may carry the JSON report
+		logger.SetConsoleOutput(os.Stderr)
+
+		if outdatedFormat != "text" && outdatedFormat != "json" {
+			log.Fatalf("Invalid format '%s': must be text or json", outdatedFormat)
+		}
+
+		cfg, err := config.ReadConfig(configPath, profileName)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		applyBuildFlags(cmd, cfg)
+
+		defaultTag := cfg.GlobalTag
+		if defaultTag == "" {
+			defaultTag = builder.GetGitCommitID()
+		}
+		discoveryResult, err := discovery.DiscoverServices(cfg, defaultTag)
+		if err != nil {
+			log.Fatalf("Failed to discover services: %v", err)
+		}
+		for _, discoveryErr := range discoveryResult.Errors {
+			logger.Warn(logging.CATEGORY_DISCOVERY, fmt.Sprintf("Discovery error: %v", discoveryErr))
+		}
+
+		store, err := baseimage.Load(baseimage.DefaultDir)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		bases, failures := serviceBaseImages(cfg, discoveryResult.Services)
+		resolver := baseimage.NewResolver(registry.NewClient(cfg.BaseImages.Insecure))
+		digests, resolveFailures := resolveBaseImages(resolver, bases)
+		for _, failure := range append(failures, resolveFailures...) {
+			logger.Warn(logging.CATEGORY_DISCOVERY, failure.Error())
+		}
+
+		keys := make([]string, 0, len(bases))
+		for key := range bases {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var listed []baseimage.Base
+		outdated := 0
+		for _, key := range keys {
+			for _, base := range store.Compare(key, bases[key], digests) {
+				if base.Status == baseimage.StatusUnknown {
+					if _, err := resolver.Resolve(base.Image); err != nil {
+						base.Error = err.Error()
+					}
+				}
+				if base.Status == baseimage.StatusUpdated {
+					outdated++
+				} else if !outdatedAll {
+					continue
+				}
+				listed = append(listed, base)
+			}
+		}
+
+		if outdatedFormat == "json" {
+			if listed == nil {
+				listed = []baseimage.Base{}
+			}
+			data, err := json.MarshalIndent(listed, "", "  ")
+			if err != nil {
+				log.Fatalf("Failed to encode report: %v", err)
+			}
+			os.Stdout.Write(append(data, '\n'))
+		} else {
+			printOutdated(listed)
+			fmt.Printf("%d base image(s) updated across %d service(s)\n", outdated, len(baseimage.Services(listed, baseimage.StatusUpdated)))
+		}
+
+		if outdatedExitCode && outdated > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// printOutdated prints one block per base image
+func printOutdated(bases []baseimage.Base) {
+	for _, base := range bases {
+		fmt.Printf("%s: %s [%s]\n", base.Service, base.Image, base.Status)
+		if base.Recorded != "" {
+			fmt.Printf("  recorded: %s\n", base.Recorded)
+		}
+		if base.Current != "" {
+			fmt.Printf("  current:  %s\n", base.Current)
+		}
+		if base.Error != "" {
+			fmt.Printf("  error:    %s\n", base.Error)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+
+	outdatedCmd.Flags().StringVarP(&configPath, "config", "c", "build.yaml", "Path to the build.yaml configuration file")
+	outdatedCmd.Flags().StringVar(&profileName, "profile", "", "Configuration profile to apply from the profiles: section")
+	outdatedCmd.Flags().StringVar(&servicesDir, "services-dir", "", "Comma-separated list of directories to scan for service definitions (overrides config file)")
+	outdatedCmd.Flags().StringVar(&outdatedFormat, "format", "text", "Output format: text or json")
+	outdatedCmd.Flags().BoolVar(&outdatedAll, "all", false, "List every base image, not only updated ones")
+	outdatedCmd.Flags().BoolVar(&outdatedExitCode, "exit-code", false, "Exit with status 1 when a base image moved")
+	outdatedCmd.Flags().BoolVar(&baseImagesInsecure, "base-images-insecure", false, "Resolve base images over plain HTTP; localhost always is (overrides base_images.insecure)")
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/cache"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/git"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/smart"
+	"github.com/addy-47/dockerz/internal/tracing"
+	"github.com/spf13/cobra"
+)
+
+// applyBuildFlags overrides config values with the build flags given on the command line.
+// Flags a command does not define are never reported as changed.
+func applyBuildFlags(cmd *cobra.Command, cfg *config.Config) {
+	if cmd.Flags().Changed("git-track") {
+		cfg.GitTrack = gitTrack
+		cfg.GitTrackDepth = depth
+	}
+	if cmd.Flags().Changed("cache") {
+		cfg.Cache = cacheEnabled
+	}
+	if cmd.Flags().Changed("force") {
+		cfg.Force = forceRebuild
+	}
+	if cmd.Flags().Changed("smart") {
+		cfg.Smart = smartEnabled
+	}
+	if cmd.Flags().Changed("project") {
+		cfg.Project = project
+	}
+	if cmd.Flags().Changed("region") {
+		cfg.Region = region
+	}
+	if cmd.Flags().Changed("gar") {
+		cfg.GAR = gar
+	}
+	if cmd.Flags().Changed("global-tag") {
+		cfg.GlobalTag = globalTag
+	}
+	if cmd.Flags().Changed("use-gar") {
+		cfg.UseGAR = useGAR
+	}
+	if cmd.Flags().Changed("push-to-gar") {
+		cfg.PushToGAR = pushToGAR
+	}
+	if cmd.Flags().Changed("metrics-file") {
+		cfg.Metrics.Textfile = metricsFile
+	}
+	if cmd.Flags().Changed("pushgateway") {
+		cfg.Metrics.Pushgateway = pushgateway
+	}
+	if cmd.Flags().Changed("trace-endpoint") {
+		cfg.Tracing.Endpoint = traceEndpoint
+	}
+	if cmd.Flags().Changed("trace-file") {
+		cfg.Tracing.File = traceFile
+	}
+	if cmd.Flags().Changed("ci") {
+		cfg.CI.Provider = ciProvider
+	}
+	if cmd.Flags().Changed("track-base-images") {
+		cfg.BaseImages.Track = trackBaseImagesFlag
+	}
+	if cmd.Flags().Changed("base-images-insecure") {
+		cfg.BaseImages.Insecure = baseImagesInsecure
+	}
+	if cmd.Flags().Changed("lock") {
+		cfg.Lock.Enabled = lockEnabled
+	}
+	if cmd.Flags().Changed("lock-file") {
+		cfg.Lock.File = lockFile
+	}
+	if cmd.Flags().Changed("sign-key") {
+		cfg.Signing.Key = signKey
+	}
+	if cmd.Flags().Changed("provenance") {
+		cfg.Signing.Provenance = provenanceEnabled
+	}
+	if cmd.Flags().Changed("sign-insecure") {
+		cfg.Signing.Insecure = signInsecure
+	}
+	if cmd.Flags().Changed("versioning") {
+		cfg.Versioning.Enabled = versioning
+	}
+	if servicesDir != "" {
+		// Parse comma-separated services directories
+		dirs := strings.Split(servicesDir, ",")
+		for i, dir := range dirs {
+			dirs[i] = strings.TrimSpace(dir)
+		}
+		cfg.ServicesDir = dirs
+	}
+}
+
+// selectServices decides which discovered services to build: the smart orchestrator's build
+// decisions when smart mode is on, otherwise every service. Changed files are returned per
+// service path when git tracking runs without smart mode, and the reason each skipped
+// service is not built per service key.
+func selectServices(cfg *config.Config, discoveryResult *discovery.DiscoveryResult, logger *logging.Logger) ([]discovery.DiscoveredService, map[string][]string, map[string]string) {
+	var servicesToBuild []discovery.DiscoveredService
+	var changedFiles map[string][]string // Track changed files for output
+	skipReasons := make(map[string]string)
+
+	if cfg.Smart {
+		logger.PrintSection("SMART ORCHESTRATION")
+		logger.Info(logging.CATEGORY_SMART, "Smart build orchestration enabled")
+		logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("Git tracking: %v (depth: %d)", cfg.GitTrack, cfg.GitTrackDepth))
+		logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("Cache enabled: %v", cfg.Cache))
+		logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("Force rebuild: %v", cfg.Force))
+
+		smartConfig := &smart.SmartConfig{
+			Enabled:       cfg.Smart,
+			GitTracking:   cfg.GitTrack,
+			GitTrackDepth: cfg.GitTrackDepth,
+			CacheEnabled:  cfg.Cache,
+			CacheLevel:    cache.RegistryCacheLevel, // Default to registry cache
+			CacheTTL:      24 * time.Hour,           // 24 hours TTL
+			ForceRebuild:  cfg.Force,
+		}
+
+		orchestrator := smart.NewOrchestrator(smartConfig)
+		orchestrator.SetLogger(logger)
+		result, err := orchestrator.OrchestrateBuilds(cfg, discoveryResult.Services)
+		if err != nil {
+			logger.Error(logging.CATEGORY_SMART, fmt.Sprintf("Failed to orchestrate builds: %v", err))
+			log.Fatalf("Failed to orchestrate builds: %v", err)
+		}
+
+		logger.Info(logging.CATEGORY_SMART, orchestrator.GetStats(result))
+
+		// Log detailed decisions for each service
+		logger.Info(logging.CATEGORY_SMART, "Service build decisions:")
+		for serviceName, decision := range result.Decisions {
+			switch decision {
+			case smart.ForceBuild:
+				logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("  %s: FORCE_BUILD (configured)", serviceName))
+			case smart.ConditionalBuild:
+				logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("  %s: BUILD (changes detected)", serviceName))
+			case smart.SkipBuild:
+				logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("  %s: SKIP (no changes)", serviceName))
+			}
+		}
+
+		// Filter services that need building
+		for i, service := range discoveryResult.Services {
+			if decision, exists := result.Decisions[service.Key()]; exists && decision != smart.SkipBuild {
+				servicesToBuild = append(servicesToBuild, service)
+				// Update service with smart info
+				if i < len(result.ServiceStates) {
+					state := result.ServiceStates[i]
+					servicesToBuild[len(servicesToBuild)-1].CurrentHash = state.CurrentHash
+					servicesToBuild[len(servicesToBuild)-1].ChangedFiles = state.ChangedFiles
+					servicesToBuild[len(servicesToBuild)-1].NeedsBuild = true
+				}
+			} else {
+				skipReasons[service.Key()] = result.Reasons[service.Key()]
+			}
+		}
+	} else {
+		logger.PrintSection("GIT TRACKING (NON-SMART)")
+		// For non-smart builds, build all services but check for git changes if requested
+		servicesToBuild = discoveryResult.Services
+
+		// If git tracking is enabled but smart is disabled, check for changes
+		if cfg.GitTrack {
+			logger.Info(logging.CATEGORY_GIT, fmt.Sprintf("Git tracking enabled (depth: %d)", cfg.GitTrackDepth))
+			changedFiles = make(map[string][]string)
+			gitTracker := git.NewTracker()
+			span := tracing.Start("git.analyze", nil, tracing.Int("dockerz.services", int64(len(servicesToBuild))))
+			gitTracker.SetTraceParent(span)
+			changesFound := false
+			for _, service := range servicesToBuild {
+				depth := cfg.GitTrackDepth
+				if depth == 0 {
+					depth = 2
+				}
+				var files []string
+				for _, path := range append([]string{service.Path}, service.Watch...) {
+					if pathFiles, err := gitTracker.GetChangedFiles(path, depth); err == nil {
+						files = append(files, pathFiles...)
+					}
+				}
+				if len(files) > 0 {
+					changedFiles[service.Path] = files
+					changesFound = true
+					logger.Info(logging.CATEGORY_GIT, fmt.Sprintf("Changes found in %s: %d files", service.Name, len(files)))
+				}
+			}
+			if !changesFound {
+				logger.Info(logging.CATEGORY_GIT, "No git changes detected in any service")
+			}
+			span.End()
+		} else {
+			logger.Info(logging.CATEGORY_GIT, "Git tracking disabled, building all services")
+		}
+
+		// Mark all as needing build when smart features disabled
+		for i := range servicesToBuild {
+			servicesToBuild[i].NeedsBuild = true
+		}
+	}
+
+	return servicesToBuild, changedFiles, skipReasons
+}
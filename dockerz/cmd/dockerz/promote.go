@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/promote"
+	"github.com/addy-47/dockerz/internal/registry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	promoteFrom     string
+	promoteTo       string
+	promoteRecord   string
+	promoteInsecure bool
+	promoteDryRun   bool
+)
+
+var promoteCmd = &cobra.Command{
+	Use:   "promote --from <tag|registry> --to <tag|registry> [services...]",
+	Short: "Retag or copy built images between registries or environments",
+	Long: `Promote images without rebuilding them.
+
+--from and --to each take a tag (v1.2.3), a registry prefix (us-docker.pkg.dev/proj/staging)
+or both (localhost:5000/team:rc1). Missing parts default to the configured registry and
+global tag; the destination defaults to the source.
+
+Within one repository the manifest is retagged in place. Across repositories the manifests
+and blobs are copied through the registry API (mounted when on the same registry).
+Destination digests are verified against the source and a promotion record is printed.
+
+Services default to every discovered service and may be given by name, path or image name.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configPath, profileName)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+
+		defaultTag := cfg.GlobalTag
+		if defaultTag == "" {
+			defaultTag = builder.GetGitCommitID()
+		}
+
+		from, to, err := promote.Resolve(promote.ParseLocation(promoteFrom), promote.ParseLocation(promoteTo), cfg.RegistryPrefix(), defaultTag)
+		if err != nil {
+			log.Fatalf("Invalid promotion: %v", err)
+		}
+
+		discoveryResult, err := discovery.DiscoverServices(cfg, defaultTag)
+		if err != nil {
+			log.Fatalf("Failed to discover services: %v", err)
+		}
+
+		images, err := selectPromotionImages(discoveryResult.Services, args)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		fmt.Printf("Promoting %d images: %s -> %s\n", len(images), from, to)
+		promotion, promoteErr := promote.Promote(registry.NewClient(promoteInsecure), from, to, images, promoteDryRun)
+
+		printPromotion(promotion)
+
+		if promoteRecord != "" {
+			if err := promote.WriteRecord(promotion, promoteRecord); err != nil {
+				log.Printf("Failed to write promotion record: %v", err)
+			} else {
+				fmt.Printf("Promotion record written to %s\n", promoteRecord)
+			}
+		}
+
+		if promoteErr != nil {
+			fmt.Fprintln(os.Stderr, promoteErr)
+			os.Exit(1)
+		}
+	},
+}
+
+// selectPromotionImages picks the requested services, or all of them when none are named
+func selectPromotionImages(services []discovery.DiscoveredService, names []string) ([]promote.Image, error) {
+	var images []promote.Image
+	if len(names) == 0 {
+		for _, service := range services {
+			images = append(images, promote.Image{Service: service.Name, ImageName: service.ImageName})
+		}
+		return images, nil
+	}
+
+	for _, name := range names {
+		found := false
+		for _, service := range services {
+			if name == service.Name || name == service.Path || name == service.Key() || name == service.ImageName {
+				images = append(images, promote.Image{Service: service.Name, ImageName: service.ImageName})
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown service '%s'", name)
+		}
+	}
+	return images, nil
+}
+
+// printPromotion prints the promotion record as a table
+func printPromotion(promotion *promote.Promotion) {
+	fmt.Println()
+	fmt.Println("=== Promotion Record ===")
+	for _, record := range promotion.Records {
+		status := "verified"
+		switch {
+		case record.Error != "":
+			status = "FAILED: " + record.Error
+		case promotion.DryRun:
+			status = "dry run"
+		}
+
+		fmt.Printf("%s [%s]\n", record.Service, record.Mode)
+		fmt.Printf("  from:   %s\n", record.Source)
+		fmt.Printf("  to:     %s\n", record.Destination)
+		if record.Digest != "" {
+			fmt.Printf("  digest: %s\n", record.Digest)
+		}
+		if record.Mode == "copy" && !promotion.DryRun && record.Error == "" {
+			fmt.Printf("  blobs:  %d copied, %d mounted, %d already present\n", record.BlobsCopied, record.BlobsMounted, record.BlobsSkipped)
+		}
+		fmt.Printf("  status: %s\n", status)
+	}
+	fmt.Println(strings.Repeat("=", 24))
+}
+
+func init() {
+	rootCmd.AddCommand(promoteCmd)
+
+	promoteCmd.Flags().StringVarP(&configPath, "config", "c", "build.yaml", "Path to the build.yaml configuration file")
+	promoteCmd.Flags().StringVar(&profileName, "profile", "", "Configuration profile to apply from the profiles: section")
+	promoteCmd.Flags().StringVar(&promoteFrom, "from", "", "Source tag, registry prefix, or prefix:tag")
+	promoteCmd.Flags().StringVar(&promoteTo, "to", "", "Destination tag, registry prefix, or prefix:tag")
+	promoteCmd.Flags().StringVar(&promoteRecord, "record", "", "Write the promotion record as JSON to this file")
+	promoteCmd.Flags().BoolVar(&promoteInsecure, "insecure", false, "Use plain HTTP for registries (localhost is always plain HTTP)")
+	promoteCmd.Flags().BoolVar(&promoteDryRun, "dry-run", false, "Resolve source digests without copying anything")
+	promoteCmd.MarkFlagRequired("to")
+}
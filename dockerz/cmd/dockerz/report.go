@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportPath   string
+	reportOutput string
+)
+
+// collectReport records the outcome of every discovered service except those another shard builds
+func collectReport(cfg *config.Config, services []discovery.DiscoveredService, results []builder.BuildResult, skipReasons, signStatuses map[string]string, summary builder.Summary, current *report.Shard, elsewhere map[string]bool) report.Report {
+	r := report.Report{
+		Version:    report.Version,
+		Timestamp:  time.Now().UTC(),
+		DurationMs: summary.Duration.Milliseconds(),
+		Shard:      current,
+		Services:   []report.Service{},
+	}
+
+	byKey := make(map[string]builder.BuildResult, len(results))
+	for _, result := range results {
+		byKey[result.Service] = result
+	}
+
+	for _, service := range services {
+		if elsewhere[service.Key()] {
+			continue
+		}
+		entry := report.Service{
+			Service: service.Key(),
+			Name:    service.Name,
+			Image:   builder.ImageReference(cfg, service.ImageName, service.Tag),
+			Status:  "skipped",
+			Reason:  skipReasons[service.Key()],
+		}
+		if result, built := byKey[service.Key()]; built {
+			entry.Image = result.Image
+			entry.Images = result.Images
+			entry.Status = result.Status
+			entry.PushStatus = result.PushStatus
+			entry.Digest = result.Digest
+			entry.SignStatus = signStatuses[service.Key()]
+			entry.DurationMs = result.Duration.Milliseconds()
+			if current != nil {
+				entry.Shard = current.Index
+			}
+			if result.Status == "failed" {
+				entry.Error = result.BuildOutput
+			} else if result.PushStatus == "failed" {
+				entry.Error = result.PushOutput
+			}
+		}
+		r.Services = append(r.Services, entry)
+	}
+	r.Summary = report.Summarize(r.Services)
+	return r
+}
+
+// defaultReportPath is where a shard writes its report when --report is not given
+func defaultReportPath(current *report.Shard) string {
+	return fmt.Sprintf("dockerz-report-%d-of-%d.json", current.Index, current.Count)
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Work with JSON build reports",
+	Long:  `Work with the JSON build reports written by 'dockerz build --report' and 'dockerz build --shard'.`,
+}
+
+var reportMergeCmd = &cobra.Command{
+	Use:   "merge REPORT...",
+	Short: "Combine the reports of a sharded build into one",
+	Long: `Combine the JSON reports of the shards of one build into a single report, print its
+summary and exit with the status the unsharded build would have had: 1 when any service
+failed to build or a pushed image could not be signed.
+
+Every shard from 1 to N must be given exactly once, and all of them must have been planned
+from the same services and shard history; otherwise services may have been built twice or not at
+all and the merge fails. The merged report is written to -o, or to stdout.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logger, err := logging.NewLogger("")
+		if err != nil {
+			log.Fatalf("Failed to create logger: %v", err)
+		}
+		// stdout may carry the merged report
+		logger.SetConsoleOutput(os.Stderr)
+
+		reports := make([]report.Report, 0, len(args))
+		for _, path := range args {
+			r, err := report.Read(path)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			reports = append(reports, r)
+		}
+		merged, err := report.Merge(reports)
+		if err != nil {
+			log.Fatalf("Failed to merge reports: %v", err)
+		}
+
+		if reportOutput != "" {
+			if err := report.Write(reportOutput, merged); err != nil {
+				log.Fatalf("%v", err)
+			}
+			logger.Info(logging.CATEGORY_BUILD, fmt.Sprintf("Merged report written to %s", reportOutput))
+		} else {
+			if err := report.Encode(os.Stdout, merged); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+
+		for _, service := range merged.Services {
+			if service.Status == "failed" {
+				logger.Error(logging.CATEGORY_BUILD, fmt.Sprintf("%s failed: %s", service.Service, service.Error))
+			}
+			if service.SignStatus == "failed" {
+				logger.Error(logging.CATEGORY_BUILD, fmt.Sprintf("%s could not be signed", service.Image))
+			}
+		}
+		logger.PrintSummary(map[string]interface{}{
+			"shards":            len(merged.Shards),
+			"total_services":    merged.Summary.Total,
+			"successful_builds": merged.Summary.Successful,
+			"failed_builds":     merged.Summary.Failed,
+			"skipped_builds":    merged.Summary.Skipped,
+			"failed_pushes":     merged.Summary.FailedPushes,
+			"failed_signatures": merged.Summary.FailedSignatures,
+			"build_duration":    (time.Duration(merged.DurationMs) * time.Millisecond).Round(time.Second),
+		})
+		if merged.Summary.Failed > 0 {
+			logger.Error(logging.CATEGORY_BUILD, fmt.Sprintf("Build completed with %d failures", merged.Summary.Failed))
+			os.Exit(1)
+		} else if merged.Summary.FailedSignatures > 0 {
+			logger.Error(logging.CATEGORY_BUILD, fmt.Sprintf("Build completed but %d images could not be signed", merged.Summary.FailedSignatures))
+			os.Exit(1)
+		}
+	},
+}
+
+// writeReport writes the build report, logging failures without failing the build
+func writeReport(path string, r report.Report, logger *logging.Logger) {
+	if err := report.Write(path, r); err != nil {
+		logger.Warn(logging.CATEGORY_BUILD, err.Error())
+		return
+	}
+	logger.Info(logging.CATEGORY_BUILD, fmt.Sprintf("Build report written to %s", filepath.Clean(path)))
+}
+
+func init() {
+	reportMergeCmd.Flags().StringVarP(&reportOutput, "output", "o", "", "Write the merged report to this file instead of stdout")
+	reportCmd.AddCommand(reportMergeCmd)
+	rootCmd.AddCommand(reportCmd)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/baseimage"
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/report"
+	"github.com/addy-47/dockerz/internal/shard"
+	"github.com/addy-47/dockerz/internal/tagging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	shardSpec    string
+	shardHistory string
+	shardPlan    string
+	shardCount   int
+)
+
+// newShardPlan splits the services into count shards, weighted by the durations of the
+// --shard-history report. Without one, or before the first report exists, every service weighs
+// the same: runner-local history differs between shards and would make them disagree.
+func newShardPlan(services []discovery.DiscoveredService, count int, logger *logging.Logger) shard.Plan {
+	var durations map[string]time.Duration
+	if shardHistory != "" {
+		r, err := report.Read(shardHistory)
+		if errors.Is(err, os.ErrNotExist) {
+			logger.Warn(logging.CATEGORY_BUILD, fmt.Sprintf("Shard history %s not found; weighting every service the same", shardHistory))
+		} else if err != nil {
+			log.Fatalf("Failed to read shard history: %v", err)
+		}
+		durations = r.Durations()
+	}
+	return shard.NewPlan(services, count, durations)
+}
+
+// applyShard narrows the services to build to those of one shard. Services assigned to other
+// shards get a skip reason naming their shard and are returned so the report can leave them
+// to the shard that builds them. A plan other than --shard-plan stops the build.
+func applyShard(current shard.Shard, services []discovery.DiscoveredService, skipReasons map[string]string, logger *logging.Logger) ([]discovery.DiscoveredService, *report.Shard, map[string]bool) {
+	plan := newShardPlan(services, current.Count, logger)
+	if err := plan.Check(shardPlan); err != nil {
+		log.Fatalf("%v", err)
+	}
+	selected := plan.Select(services, current)
+	elsewhere := make(map[string]bool)
+	for key, index := range plan.Assignments {
+		if index != current.Index {
+			elsewhere[key] = true
+			skipReasons[key] = fmt.Sprintf("assigned to shard %d/%d", index, current.Count)
+		}
+	}
+
+	logger.Info(logging.CATEGORY_BUILD, fmt.Sprintf("Shard %s (plan %s): building %d of %d services, estimated load per shard: %s",
+		current, plan.ID, len(selected), len(services), formatLoads(plan)))
+	for _, service := range selected {
+		logger.Info(logging.CATEGORY_BUILD, fmt.Sprintf("  %s", service.Key()))
+	}
+
+	return selected, &report.Shard{Index: current.Index, Count: current.Count, Plan: plan.ID}, elsewhere
+}
+
+// formatLoads lists the estimated load of each shard; without durations it is a service count
+func formatLoads(plan shard.Plan) string {
+	loads := make([]string, len(plan.Loads))
+	for i, load := range plan.Loads {
+		if !plan.Weighted {
+			loads[i] = fmt.Sprintf("%d", load)
+			continue
+		}
+		loads[i] = (time.Duration(load) * time.Millisecond).Round(time.Second).String()
+	}
+	return strings.Join(loads, ", ")
+}
+
+var reportPlanCmd = &cobra.Command{
+	Use:   "plan --shards N",
+	Short: "Print the shard plan ID a sharded build will use",
+	Long: `Select the services to build the way 'dockerz build' does, split them into N shards
+exactly as 'dockerz build --shard i/N' would and print the plan ID on stdout; the
+assignment of every service is logged to stderr.
+
+Pass the ID to every shard with --shard-plan so a shard that computes a different split
+(another checkout, other selection flags or another --shard-history) fails before building
+instead of building services twice or not at all. Give this command the same selection flags
+and --shard-history as the shards.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger, err := logging.NewLogger("")
+		if err != nil {
+			log.Fatalf("Failed to create logger: %v", err)
+		}
+		// stdout carries the plan ID
+		logger.SetConsoleOutput(os.Stderr)
+
+		if shardCount < 1 {
+			log.Fatalf("Invalid shard count %d: must be at least 1", shardCount)
+		}
+
+		cfg, err := config.ReadConfig(configPath, profileName)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		applyBuildFlags(cmd, cfg)
+
+		defaultTag := cfg.GlobalTag
+		if defaultTag == "" {
+			defaultTag = builder.GetGitCommitID()
+		}
+		inputFile := cfg.InputChangedServices
+		if cmd.Flags().Changed("input-changed-services") {
+			inputFile = inputChangedServices
+		}
+		discoveryResult, err := discovery.DiscoverServices(cfg, defaultTag, inputFile)
+		if err != nil {
+			log.Fatalf("Failed to discover services: %v", err)
+		}
+		for _, discoveryErr := range discoveryResult.Errors {
+			logger.Warn(logging.CATEGORY_DISCOVERY, fmt.Sprintf("Discovery error: %v", discoveryErr))
+		}
+		if inputOnly {
+			if inputFile == "" {
+				log.Fatalf("--input-only requires an input changed services file")
+			}
+			if discoveryResult.Services, err = discovery.SelectListed(discoveryResult.Services, inputFile); err != nil {
+				log.Fatalf("Failed to read input changed services file: %v", err)
+			}
+		}
+		if err := tagging.ApplyTags(cfg, discoveryResult.Services, tagging.LoadGitInfo()); err != nil {
+			log.Fatalf("Failed to resolve image tags: %v", err)
+		}
+		if cfg.BaseImages.Track {
+			store, err := baseimage.Load(baseimage.DefaultDir)
+			if err != nil {
+				logger.Warn(logging.CATEGORY_DISCOVERY, err.Error())
+			}
+			trackBaseImages(cfg, discoveryResult.Services, store, logger)
+		}
+		selected, _, _ := selectServices(cfg, discoveryResult, logger)
+
+		plan := newShardPlan(selected, shardCount, logger)
+		keys := make([]string, 0, len(plan.Assignments))
+		for key := range plan.Assignments {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		logger.Info(logging.CATEGORY_BUILD, fmt.Sprintf("Plan %s: %d services in %d shards, estimated load per shard: %s",
+			plan.ID, len(selected), shardCount, formatLoads(plan)))
+		for _, key := range keys {
+			logger.Info(logging.CATEGORY_BUILD, fmt.Sprintf("  %d/%d %s", plan.Assignments[key], shardCount, key))
+		}
+		fmt.Println(plan.ID)
+	},
+}
+
+func init() {
+	reportCmd.AddCommand(reportPlanCmd)
+
+	reportPlanCmd.Flags().IntVar(&shardCount, "shards", 1, "Number of shards to split the build into")
+	reportPlanCmd.Flags().StringVar(&shardHistory, "shard-history", "", "Weight services by their durations in this build report, e.g. the last merged report")
+	reportPlanCmd.Flags().StringVarP(&configPath, "config", "c", "build.yaml", "Path to the build.yaml configuration file")
+	reportPlanCmd.Flags().StringVar(&profileName, "profile", "", "Configuration profile to apply from the profiles: section")
+	reportPlanCmd.Flags().StringVar(&inputChangedServices, "input-changed-services", "", "Add the services listed in this file")
+	reportPlanCmd.Flags().BoolVar(&inputOnly, "input-only", false, "Plan only the services listed in the input changed services file, ignoring other discovery sources")
+	reportPlanCmd.Flags().StringVar(&servicesDir, "services-dir", "", "Comma-separated list of directories to scan for service definitions (overrides config file)")
+	reportPlanCmd.Flags().StringVar(&globalTag, "global-tag", "", "Global Docker tag to apply to all images (overrides config file and git commit ID)")
+	reportPlanCmd.Flags().BoolVar(&smartEnabled, "smart", false, "Include only the services smart orchestration decides to build")
+	reportPlanCmd.Flags().BoolVar(&gitTrack, "git-track", false, "Enable git change tracking")
+	reportPlanCmd.Flags().IntVar(&depth, "depth", 2, "Git tracking depth (0 for full history, default 2)")
+	reportPlanCmd.Flags().BoolVar(&cacheEnabled, "cache", false, "Use the build cache when deciding what to build")
+	reportPlanCmd.Flags().BoolVar(&forceRebuild, "force", false, "Include every service, ignoring cache and change detection")
+	reportPlanCmd.Flags().BoolVar(&trackBaseImagesFlag, "track-base-images", false, "Rebuild services whose base image digest moved since their last build")
+	reportPlanCmd.Flags().BoolVar(&baseImagesInsecure, "base-images-insecure", false, "Resolve base images over plain HTTP; localhost always is (overrides base_images.insecure)")
+}
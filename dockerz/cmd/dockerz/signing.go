@@ -0,0 +1,313 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/registry"
+	"github.com/addy-47/dockerz/internal/sign"
+	"github.com/addy-47/dockerz/internal/tagging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	signKey           string
+	provenanceEnabled bool
+	signInsecure      bool
+	verifyKey         string
+	verifyProvenance  bool
+	verifySigFormat   string
+	verifySigInsecure bool
+)
+
+var verifySignatureCmd = &cobra.Command{
+	Use:   "verify-signature [image...]",
+	Short: "Verify the signatures and provenance of pushed images",
+	Long: `Verify that images were signed with the private key matching --key.
+
+Images are given as references (localhost:5000/api:v1 or repo@sha256:...) and default
+to the primary tag of every discovered service. Tags are resolved to their digest through
+the registry API; the cosign-compatible signatures stored under the digest's .sig tag must
+include one made with the key. With --provenance, a SLSA provenance attestation signed with
+the key must also be stored under the .att tag; its builder, git commit and build.yaml
+digest are printed.
+
+Exits with status 1 when an image is not verified.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if verifySigFormat != "text" && verifySigFormat != "json" {
+			log.Fatalf("Invalid format '%s': must be text or json", verifySigFormat)
+		}
+		key, err := sign.LoadPublicKey(verifyKey)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		images := args
+		if len(images) == 0 {
+			cfg, err := config.ReadConfig(configPath, profileName)
+			if err != nil {
+				log.Fatalf("Failed to load config: %v", err)
+			}
+			applyBuildFlags(cmd, cfg)
+			defaultTag := cfg.GlobalTag
+			if defaultTag == "" {
+				defaultTag = builder.GetGitCommitID()
+			}
+			discoveryResult, err := discovery.DiscoverServices(cfg, defaultTag)
+			if err != nil {
+				log.Fatalf("Failed to discover services: %v", err)
+			}
+			for _, service := range discoveryResult.Services {
+				images = append(images, builder.ImageReference(cfg, service.ImageName, service.Tag))
+			}
+		}
+
+		client := registry.NewClient(verifySigInsecure)
+		var verifications []sign.Verification
+		failed := 0
+		for _, image := range images {
+			verification := verifyImage(client, key, image)
+			if verification.Error == "" && verification.Signatures == 0 {
+				verification.Error = "no signature made with " + verifyKey
+			}
+			if verification.Error == "" && verifyProvenance && verification.Attestations == 0 {
+				verification.Error = "no provenance attestation made with " + verifyKey
+			}
+			if verification.Error != "" {
+				failed++
+			}
+			verifications = append(verifications, verification)
+		}
+
+		if verifySigFormat == "json" {
+			if verifications == nil {
+				verifications = []sign.Verification{}
+			}
+			data, err := json.MarshalIndent(verifications, "", "  ")
+			if err != nil {
+				log.Fatalf("Failed to encode verifications: %v", err)
+			}
+			os.Stdout.Write(append(data, '\n'))
+		} else {
+			for _, verification := range verifications {
+				printVerification(verification)
+			}
+			fmt.Printf("%d of %d image(s) verified\n", len(verifications)-failed, len(verifications))
+		}
+
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// verifyImage resolves an image to its digest and verifies its signatures and attestations
+func verifyImage(client *registry.Client, key *ecdsa.PublicKey, image string) sign.Verification {
+	verification := sign.Verification{Image: image}
+	ref, err := registry.ParseReference(image)
+	if err == nil && ref.Digest == "" {
+		var exists bool
+		ref.Digest, exists, err = client.HeadManifest(ref)
+		if err == nil && !exists {
+			err = fmt.Errorf("image %s not found", ref)
+		}
+	}
+	if err != nil {
+		verification.Error = err.Error()
+		return verification
+	}
+	result, err := sign.Verify(client, key, ref)
+	result.Image = image
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// printVerification prints the verification of one image
+func printVerification(verification sign.Verification) {
+	status := "verified"
+	if verification.Error != "" {
+		status = "FAILED: " + verification.Error
+	}
+	fmt.Printf("%s\n", verification.Image)
+	if verification.Digest != "" {
+		fmt.Printf("  digest:       %s\n", verification.Digest)
+		fmt.Printf("  signatures:   %d\n", verification.Signatures)
+		fmt.Printf("  attestations: %d\n", verification.Attestations)
+	}
+	if provenance := verification.Provenance; provenance != nil {
+		fmt.Printf("  builder:      %s\n", provenance.Builder.ID)
+		for _, material := range provenance.Materials {
+			for algorithm, digest := range material.Digest {
+				fmt.Printf("  material:     %s (%s:%s)\n", material.URI, algorithm, digest)
+			}
+		}
+	}
+	fmt.Printf("  status:       %s\n", status)
+}
+
+// signImages signs the digests of the images pushed by the build with the configured key
+// and attaches their provenance. It returns the signing status of every pushed service,
+// "signed" or "failed", keyed by service key.
+func signImages(cfg *config.Config, services []discovery.DiscoveredService, results []builder.BuildResult, logger *logging.Logger) map[string]string {
+	var pushed []builder.BuildResult
+	for _, result := range results {
+		if result.PushStatus == "success" {
+			pushed = append(pushed, result)
+		}
+	}
+	statuses := make(map[string]string, len(pushed))
+	if len(pushed) == 0 {
+		return statuses
+	}
+
+	logger.PrintSection("SIGNING")
+	key, err := sign.LoadPrivateKey(cfg.Signing.Key)
+	if err != nil {
+		logger.Error(logging.CATEGORY_BUILD, err.Error())
+		for _, result := range pushed {
+			statuses[result.Service] = "failed"
+		}
+		return statuses
+	}
+
+	byKey := make(map[string]discovery.DiscoveredService)
+	for _, service := range services {
+		byKey[service.Key()] = service
+	}
+	var materials []sign.Material
+	if cfg.Signing.Provenance {
+		materials = sourceMaterials(logger)
+	}
+
+	client := registry.NewClient(cfg.Signing.Insecure)
+	for _, result := range pushed {
+		ref, err := registry.ParseReference(result.Image)
+		if err == nil && result.Digest == "" {
+			err = fmt.Errorf("no pushed digest")
+		}
+		if err == nil {
+			ref = ref.WithDigest(result.Digest)
+			err = sign.Sign(client, key, ref)
+		}
+		if err == nil && cfg.Signing.Provenance {
+			err = sign.Attest(client, key, ref, provenanceFor(cfg, byKey[result.Service], result, materials))
+		}
+		if err != nil {
+			logger.Error(logging.CATEGORY_BUILD, fmt.Sprintf("Failed to sign %s: %v", result.Image, err))
+			statuses[result.Service] = "failed"
+			continue
+		}
+		logger.Info(logging.CATEGORY_BUILD, fmt.Sprintf("Signed %s", ref))
+		statuses[result.Service] = "signed"
+	}
+	return statuses
+}
+
+// sourceMaterials returns the provenance materials shared by every image: the git commit
+// and build.yaml
+func sourceMaterials(logger *logging.Logger) []sign.Material {
+	var materials []sign.Material
+	if info := tagging.LoadGitInfo(); info.SHA != "" {
+		uri := "git+" + gitRemote()
+		if info.Branch != "" {
+			uri += "@refs/heads/" + info.Branch
+		}
+		materials = append(materials, sign.Material{URI: uri, Digest: map[string]string{"sha1": info.SHA}})
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		logger.Warn(logging.CATEGORY_BUILD, fmt.Sprintf("build.yaml not recorded in provenance: %v", err))
+		return materials
+	}
+	return append(materials, sign.Material{URI: configPath, Digest: map[string]string{"sha256": fmt.Sprintf("%x", sha256.Sum256(data))}})
+}
+
+// gitRemote returns the URL of the origin remote, or the repository directory without one
+func gitRemote() string {
+	output, err := exec.Command("git", "config", "--get", "remote.origin.url").Output()
+	if remote := strings.TrimSpace(string(output)); err == nil && remote != "" {
+		return remote
+	}
+	output, err = exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+	return "file://" + strings.TrimSpace(string(output))
+}
+
+// provenanceFor describes how a pushed image was built
+func provenanceFor(cfg *config.Config, service discovery.DiscoveredService, result builder.BuildResult, materials []sign.Material) sign.Provenance {
+	builderID := cfg.Signing.BuilderID
+	if builderID == "" {
+		hostname, _ := os.Hostname()
+		builderID = "urn:dockerz:builder:" + hostname
+	}
+
+	provenance := sign.Provenance{
+		Builder:   sign.Builder{ID: builderID},
+		BuildType: sign.BuildType,
+		Invocation: sign.Invocation{
+			Parameters: map[string]interface{}{
+				"service":    result.Service,
+				"dockerfile": service.Dockerfile,
+				"target":     service.Target,
+				"build_args": service.BuildArgs,
+				"platforms":  service.Platforms,
+				"tags":       service.Tags,
+			},
+			Environment: map[string]interface{}{
+				"dockerz_version": dockerzVersion,
+				"os":              runtime.GOOS,
+				"arch":            runtime.GOARCH,
+			},
+		},
+		Metadata: sign.Metadata{
+			BuildStartedOn:  &result.StartTime,
+			BuildFinishedOn: &result.EndTime,
+			Completeness:    sign.Completeness{Parameters: true},
+		},
+		Materials: append([]sign.Material(nil), materials...),
+	}
+	for _, material := range materials {
+		if strings.HasPrefix(material.URI, "git+") {
+			provenance.Invocation.ConfigSource = sign.ConfigSource{URI: material.URI, Digest: material.Digest, EntryPoint: configPath}
+		}
+	}
+
+	// Base images are known when they are tracked
+	images := make([]string, 0, len(service.BaseImages))
+	for image := range service.BaseImages {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	for _, image := range images {
+		algorithm, hex, _ := strings.Cut(service.BaseImages[image], ":")
+		provenance.Materials = append(provenance.Materials, sign.Material{URI: "pkg:docker/" + image, Digest: map[string]string{algorithm: hex}})
+	}
+	return provenance
+}
+
+func init() {
+	rootCmd.AddCommand(verifySignatureCmd)
+
+	verifySignatureCmd.Flags().StringVarP(&configPath, "config", "c", "build.yaml", "Path to the build.yaml configuration file")
+	verifySignatureCmd.Flags().StringVar(&profileName, "profile", "", "Configuration profile to apply from the profiles: section")
+	verifySignatureCmd.Flags().StringVar(&verifyKey, "key", sign.DefaultPublicKey, "PEM public key the images must be signed with")
+	verifySignatureCmd.Flags().BoolVar(&verifyProvenance, "provenance", false, "Also require a SLSA provenance attestation signed with the key")
+	verifySignatureCmd.Flags().StringVar(&verifySigFormat, "format", "text", "Output format: text or json")
+	verifySignatureCmd.Flags().BoolVar(&verifySigInsecure, "insecure", false, "Use plain HTTP for registries (localhost is always plain HTTP)")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/tracing"
+)
+
+var (
+	traceEndpoint string
+	traceFile     string
+)
+
+// traceEndpointFromEnv returns the OTLP traces endpoint from the standard OpenTelemetry variables,
+// so a collector already configured for the CI job is used without extra dockerz settings
+func traceEndpointFromEnv() string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+}
+
+// exportTraces ends the root span and exports the run's spans when an endpoint or file is configured.
+// Export failures are logged but never fail the build.
+func exportTraces(cfg *config.Config, root *tracing.Span, logger *logging.Logger) {
+	root.End()
+
+	endpoint := cfg.Tracing.Endpoint
+	if endpoint == "" {
+		endpoint = traceEndpointFromEnv()
+	}
+	if endpoint == "" && cfg.Tracing.File == "" {
+		return
+	}
+
+	if err := tracing.Export(endpoint, cfg.Tracing.Headers, cfg.Tracing.File); err != nil {
+		logger.Warn(logging.CATEGORY_PERFORMANCE, err.Error())
+		return
+	}
+	if cfg.Tracing.File != "" {
+		logger.Info(logging.CATEGORY_PERFORMANCE, fmt.Sprintf("Trace written to %s", cfg.Tracing.File))
+	}
+	if endpoint != "" {
+		logger.Info(logging.CATEGORY_PERFORMANCE, fmt.Sprintf("Trace exported to %s", tracing.TracesURL(endpoint)))
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/addy-47/dockerz/internal/validate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateJSON   bool
+	validateSchema bool
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check build.yaml for unknown keys, type errors and inconsistent settings",
+	Long: `Validate the configuration file, its includes and the selected profile.
+
+Checks:
+- Unknown keys (with suggestions for typos) and values of the wrong type, reported as file:line:column
+- Service paths that do not exist or have no Dockerfile
+- Invalid and duplicate image names
+- Inconsistent Google Artifact Registry settings (e.g. push_to_gar without use_gar)
+
+The same checks run before every 'dockerz build'. Exits with status 1 when errors are found.
+
+Use --schema to print the JSON Schema of build.yaml for editor integration.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if validateSchema {
+			schema, err := validate.SchemaJSON()
+			if err != nil {
+				log.Fatalf("Failed to generate schema: %v", err)
+			}
+			os.Stdout.Write(schema)
+			return
+		}
+
+		report := validate.Validate(configPath, profileName, nil)
+
+		if validateJSON {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				log.Fatalf("Failed to encode report: %v", err)
+			}
+			fmt.Println(string(data))
+		} else {
+			report.Print(os.Stdout)
+		}
+
+		if report.HasErrors() {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringVarP(&configPath, "config", "c", "build.yaml", "Path to the build.yaml configuration file")
+	validateCmd.Flags().StringVar(&profileName, "profile", "", "Configuration profile to validate")
+	validateCmd.Flags().BoolVar(&validateJSON, "json", false, "Print the issues as JSON")
+	validateCmd.Flags().BoolVar(&validateSchema, "schema", false, "Print the JSON Schema for build.yaml and exit")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/lock"
+	"github.com/addy-47/dockerz/internal/registry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyFormat   string
+	verifyInsecure bool
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that registry images still match the digests in the lock file",
+	Long: `Check every pushed service recorded in the lock file: each of its tags must still
+resolve to the locked digest in the registry.
+
+Statuses:
+  match     the tag points to the locked digest
+  mismatch  the tag was moved to another image
+  missing   the tag no longer exists
+  error     the registry could not be queried
+
+Services built without pushing have no digest and are not checked. Exits with status 1
+unless every tag matches.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if verifyFormat != "text" && verifyFormat != "json" {
+			log.Fatalf("Invalid format '%s': must be text or json", verifyFormat)
+		}
+
+		cfg, err := config.ReadConfig(configPath, profileName)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		applyBuildFlags(cmd, cfg)
+
+		path := lockPath(cfg)
+		file, err := lock.Read(path)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		checks := lock.Verify(registry.NewClient(verifyInsecure), file)
+		if verifyFormat == "json" {
+			if checks == nil {
+				checks = []lock.Check{}
+			}
+			data, err := json.MarshalIndent(checks, "", "  ")
+			if err != nil {
+				log.Fatalf("Failed to encode checks: %v", err)
+			}
+			os.Stdout.Write(append(data, '\n'))
+		} else {
+			matched := 0
+			for _, check := range checks {
+				fmt.Printf("%s: %s [%s]\n", check.Service, check.Image, check.Status)
+				switch check.Status {
+				case lock.StatusMatch:
+					matched++
+				case lock.StatusMismatch:
+					fmt.Printf("  locked:  %s\n", check.Locked)
+					fmt.Printf("  current: %s\n", check.Current)
+				case lock.StatusError:
+					fmt.Printf("  error:   %s\n", check.Error)
+				}
+			}
+			fmt.Printf("%d of %d locked tag(s) match %s\n", matched, len(checks), path)
+		}
+
+		if lock.Failed(checks) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringVarP(&configPath, "config", "c", "build.yaml", "Path to the build.yaml configuration file")
+	verifyCmd.Flags().StringVar(&profileName, "profile", "", "Configuration profile to apply from the profiles: section")
+	verifyCmd.Flags().StringVar(&lockFile, "lock-file", "", "Lock file path (overrides lock.file; default dockerz.lock)")
+	verifyCmd.Flags().StringVar(&verifyFormat, "format", "text", "Output format: text or json")
+	verifyCmd.Flags().BoolVar(&verifyInsecure, "insecure", false, "Use plain HTTP for registries (localhost is always plain HTTP)")
+}
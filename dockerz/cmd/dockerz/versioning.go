@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/git"
+	"github.com/addy-47/dockerz/internal/logging"
+)
+
+// bumpNames maps version bumps to their log labels
+var bumpNames = map[git.VersionBump]string{
+	git.NoBump:    "none",
+	git.PatchBump: "patch",
+	git.MinorBump: "minor",
+	git.MajorBump: "major",
+}
+
+// applyServiceVersions computes the next semantic version of every service from conventional commits
+func applyServiceVersions(cfg *config.Config, services []discovery.DiscoveredService, logger *logging.Logger) {
+	tracker := git.NewTracker()
+	tracker.SetLogger(logger)
+
+	for i := range services {
+		service := &services[i]
+		prefix := cfg.VersionTagPrefix(service.Name)
+
+		version, err := tracker.ComputeServiceVersion(service.Path, prefix)
+		if err != nil {
+			logger.Warn(logging.CATEGORY_GIT, fmt.Sprintf("Failed to compute version for %s: %v", service.Name, err))
+			continue
+		}
+
+		// Only a bump releases a version; without one the version is already released and its
+		// image tag must not be overwritten by this build
+		next := version.Next.String()
+		if version.Bump != git.NoBump {
+			service.Version = next
+			service.VersionTag = version.GitTag
+		}
+
+		previous := version.PreviousTag
+		if previous == "" {
+			previous = "none"
+		}
+		logger.Info(logging.CATEGORY_GIT, fmt.Sprintf("%s: version %s (previous: %s, bump: %s, commits: %d)",
+			service.Name, next, previous, bumpNames[version.Bump], version.Commits))
+	}
+}
+
+// createVersionTags creates git tags for services whose images were built and pushed successfully
+func createVersionTags(cfg *config.Config, services []discovery.DiscoveredService, results []builder.BuildResult, logger *logging.Logger) {
+	pushed := make(map[string]bool)
+	for _, result := range results {
+		if result.Status == "success" && result.PushStatus == "success" {
+			pushed[result.Service] = true
+		}
+	}
+
+	tracker := git.NewTracker()
+	for _, service := range services {
+		if service.VersionTag == "" {
+			continue
+		}
+		if !pushed[service.Key()] {
+			logger.Warn(logging.CATEGORY_GIT, fmt.Sprintf("Not tagging %s: image was not pushed successfully", service.VersionTag))
+			continue
+		}
+
+		message := fmt.Sprintf("Release %s %s", service.Name, service.Version)
+		if err := tracker.CreateTag(service.VersionTag, message, cfg.Versioning.PushGitTags); err != nil {
+			logger.Warn(logging.CATEGORY_GIT, err.Error())
+			continue
+		}
+		logger.Info(logging.CATEGORY_GIT, fmt.Sprintf("Created git tag %s", service.VersionTag))
+	}
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/builder"
+)
+
+// Docker builds with the agent's Docker daemon, running hooks and pushing like a local build
+type Docker struct{}
+
+// Name returns the backend name
+func (Docker) Name() string {
+	return BackendDocker
+}
+
+// Run builds, hooks and pushes one task
+func (Docker) Run(task builder.BuildTask) builder.BuildResult {
+	var pushManager *builder.PushManager
+	if task.Config.UseGAR && task.Config.PushToGAR {
+		pushManager = builder.NewPushManager(task.Config, 1)
+		pushManager.Start()
+		defer pushManager.Stop()
+	}
+	return builder.RunTask(task, pushManager)
+}
+
+// Name returns the backend name
+func (s Simulator) Name() string {
+	return BackendSimulator
+}
+
+// Run pretends to build one task
+func (s Simulator) Run(task builder.BuildTask) builder.BuildResult {
+	tags := task.Tags
+	if len(tags) == 0 {
+		tags = []string{task.Tag}
+	}
+	result := builder.BuildResult{Service: task.Key(), StartTime: time.Now()}
+	for _, tag := range tags {
+		result.Images = append(result.Images, builder.ImageReference(task.Config, task.ImageName, tag))
+	}
+	result.Image = result.Images[0]
+
+	output := task.Output
+	if output == nil {
+		output = io.Discard
+	}
+	log.Printf("Simulating build of %s: %s", task.Key(), strings.Join(result.Images, ", "))
+
+	steps := []string{"[internal] load build definition from Dockerfile", "[1/3] FROM docker.io/library/alpine", "[2/3] COPY . .", "[3/3] RUN make"}
+	for i, step := range steps {
+		fmt.Fprintf(output, "#%d %s\n", i+1, step)
+		time.Sleep(s.Duration / time.Duration(len(steps)))
+		if i == len(steps)-1 && s.Fail[task.Key()] {
+			fmt.Fprintf(output, "#%d ERROR: simulated failure\n", i+1)
+			result.Status = "failed"
+			result.BuildOutput = "simulated failure"
+			result.FailedStep = step
+			result.EndTime = time.Now()
+			return result
+		}
+		fmt.Fprintf(output, "#%d DONE %.1fs\n", i+1, (s.Duration / time.Duration(len(steps))).Seconds())
+	}
+
+	result.Status = "success"
+	result.ImageID = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(result.Image)))
+	result.CacheMisses = len(steps) - 1
+	result.EndTime = time.Now()
+	return result
+}
@@ -0,0 +1,331 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/tracing"
+)
+
+// errBusy reports an agent that had no free slot; it is not lost
+var errBusy = errors.New("agent is busy")
+
+// NewCoordinator registers the agents at urls, skipping those that cannot be reached.
+// With push off, a service is built on the agent holding the images of its dependencies.
+func NewCoordinator(urls []string, token string, push bool, revision string) (*Coordinator, error) {
+	c := &Coordinator{
+		token:    token,
+		client:   &http.Client{},
+		push:     push,
+		revision: revision,
+		builtOn:  make(map[string]*remote),
+	}
+	c.cond = sync.NewCond(&c.mu)
+
+	var failures []string
+	for _, url := range urls {
+		url = strings.TrimRight(strings.TrimSpace(url), "/")
+		if url == "" {
+			continue
+		}
+		if !strings.Contains(url, "://") {
+			url = "http://" + url
+		}
+		info, err := c.register(url)
+		if err != nil {
+			failures = append(failures, err.Error())
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		c.agents = append(c.agents, &remote{url: url, info: info})
+		log.Printf("Registered agent %s at %s: %s backend, capacity %d, platforms %s",
+			info.Name, url, info.Backend, info.Capacity, strings.Join(info.Platforms, ","))
+	}
+	if len(c.agents) == 0 {
+		return nil, fmt.Errorf("no build agents available: %s", strings.Join(failures, "; "))
+	}
+	return c, nil
+}
+
+// register fetches an agent's advertised capacity and platforms
+func (c *Coordinator) register(url string) (Info, error) {
+	var info Info
+	req, err := http.NewRequest(http.MethodGet, url+"/v1/info", nil)
+	if err != nil {
+		return info, fmt.Errorf("agent %s: %w", url, err)
+	}
+	c.authorize(req)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return info, fmt.Errorf("agent %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return info, fmt.Errorf("agent %s: %s", url, responseError(resp))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return info, fmt.Errorf("agent %s: invalid info: %w", url, err)
+	}
+	if info.Capacity < 1 {
+		info.Capacity = 1
+	}
+	if info.Name == "" {
+		info.Name = url
+	}
+	return info, nil
+}
+
+func (c *Coordinator) authorize(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
+
+// Capacity returns the number of tasks all registered agents run at once
+func (c *Coordinator) Capacity() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	capacity := 0
+	for _, agent := range c.agents {
+		if agent.lost == nil {
+			capacity += agent.info.Capacity
+		}
+	}
+	return capacity
+}
+
+// Run dispatches a task to an agent and waits for its result. A task whose agent is lost
+// is dispatched again, to another agent when one is eligible, up to MaxAttempts times. Agents
+// without a free slot are retried until one accepts the task; that never counts as an attempt.
+func (c *Coordinator) Run(task builder.BuildTask) builder.BuildResult {
+	failed := func(err error) builder.BuildResult {
+		return builder.BuildResult{
+			Service:     task.Key(),
+			Image:       builder.ImageReference(task.Config, task.ImageName, task.Tag),
+			Status:      "failed",
+			BuildOutput: err.Error(),
+			StartTime:   time.Now(),
+			EndTime:     time.Now(),
+		}
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= MaxAttempts; {
+		agent, err := c.acquire(task)
+		if err != nil {
+			return failed(err)
+		}
+		log.Printf("Dispatching %s to agent %s (attempt %d/%d)", task.Key(), agent.info.Name, attempt, MaxAttempts)
+		task.Span.SetAttributes(tracing.String("dockerz.agent", agent.info.Name), tracing.Int("dockerz.agent.attempts", int64(attempt)))
+
+		result, err := c.dispatch(agent, task)
+		c.release(agent, task, err)
+		if err == nil {
+			return result
+		}
+		if errors.Is(err, errBusy) {
+			log.Printf("Agent %s had no free slot for %s, retrying", agent.info.Name, task.Key())
+			time.Sleep(HeartbeatInterval)
+			continue
+		}
+		lastErr = err
+		attempt++
+		log.Printf("Agent %s lost while building %s: %v", agent.info.Name, task.Key(), err)
+	}
+	return failed(fmt.Errorf("gave up after %d attempts: %w", MaxAttempts, lastErr))
+}
+
+// acquire waits for a free slot on an agent eligible for the task, preferring the least busy
+func (c *Coordinator) acquire(task builder.BuildTask) (*remote, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		candidates, err := c.eligible(task)
+		if err != nil {
+			return nil, err
+		}
+		var best *remote
+		for _, agent := range candidates {
+			if agent.running >= agent.info.Capacity {
+				continue
+			}
+			if best == nil || agent.running*best.info.Capacity < best.running*agent.info.Capacity {
+				best = agent
+			}
+		}
+		if best != nil {
+			best.running++
+			return best, nil
+		}
+		c.cond.Wait()
+	}
+}
+
+// eligible returns the live agents that can build the task: they support its platforms and,
+// without pushing, hold the images of its dependencies
+func (c *Coordinator) eligible(task builder.BuildTask) ([]*remote, error) {
+	var live []*remote
+	for _, agent := range c.agents {
+		if agent.lost == nil {
+			live = append(live, agent)
+		}
+	}
+	if len(live) == 0 {
+		return nil, fmt.Errorf("no build agents left")
+	}
+
+	var candidates []*remote
+	for _, agent := range live {
+		if supports(agent.info.Platforms, task.Platforms) {
+			candidates = append(candidates, agent)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no build agent supports platforms %s", strings.Join(task.Platforms, ","))
+	}
+
+	if c.push {
+		return candidates, nil
+	}
+	for _, dependency := range task.DependsOn {
+		holder, ok := c.builtOn[dependency]
+		if !ok {
+			continue
+		}
+		if holder.lost != nil {
+			return nil, fmt.Errorf("the image of dependency %s was built on lost agent %s", dependency, holder.info.Name)
+		}
+		for _, agent := range candidates {
+			if agent == holder {
+				return []*remote{holder}, nil
+			}
+		}
+		return nil, fmt.Errorf("agent %s holding dependency %s does not support platforms %s", holder.info.Name, dependency, strings.Join(task.Platforms, ","))
+	}
+	return candidates, nil
+}
+
+// supports reports whether an agent advertising platforms can build for wanted
+func supports(platforms, wanted []string) bool {
+	for _, platform := range wanted {
+		found := false
+		for _, supported := range platforms {
+			if supported == platform {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// release frees the task's slot, marking the agent lost when dispatching failed
+func (c *Coordinator) release(agent *remote, task builder.BuildTask, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	agent.running--
+	switch {
+	case err == nil:
+		c.builtOn[task.Key()] = agent
+	case !errors.Is(err, errBusy):
+		agent.lost = err
+	}
+	c.cond.Broadcast()
+}
+
+// dispatch runs a task on an agent, copying its build output to the task's output or stdout.
+// An error means the agent was lost or refused the task, never that the build failed.
+func (c *Coordinator) dispatch(agent *remote, task builder.BuildTask) (builder.BuildResult, error) {
+	body, err := json.Marshal(Task{Task: task, Revision: c.revision})
+	if err != nil {
+		return builder.BuildResult{}, fmt.Errorf("failed to encode task: %w", err)
+	}
+
+	// The agent is lost once neither output nor heartbeats arrive for LostAfter
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watchdog := time.AfterFunc(LostAfter, cancel)
+	defer watchdog.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, agent.url+"/v1/tasks", bytes.NewReader(body))
+	if err != nil {
+		return builder.BuildResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.authorize(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return builder.BuildResult{}, lostError(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		return builder.BuildResult{}, errBusy
+	}
+	if resp.StatusCode != http.StatusOK {
+		return builder.BuildResult{}, errors.New(responseError(resp))
+	}
+
+	output := task.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event Event
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return builder.BuildResult{}, lostError(ctx, err)
+		}
+		watchdog.Reset(LostAfter)
+
+		switch event.Type {
+		case EventLog:
+			io.WriteString(output, event.Data)
+		case EventResult:
+			if event.Result == nil {
+				return builder.BuildResult{}, fmt.Errorf("agent sent an empty result")
+			}
+			result := event.Result.BuildResult
+			result.StartTime = event.Result.StartTime
+			result.EndTime = event.Result.EndTime
+			result.PushDuration = event.Result.PushDuration
+			return result, nil
+		}
+	}
+}
+
+// lostError explains a broken connection, naming the missed heartbeats when the watchdog fired
+func lostError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("no heartbeat for %s", LostAfter)
+	}
+	return err
+}
+
+// responseError returns the message of a failed agent response
+func responseError(resp *http.Response) string {
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if text := strings.TrimSpace(string(message)); text != "" {
+		return fmt.Sprintf("%s: %s", resp.Status, text)
+	}
+	return resp.Status
+}
@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+)
+
+// output collects the build output a coordinator streams and signals the first line
+type output struct {
+	mu      sync.Mutex
+	builder strings.Builder
+	started chan struct{}
+	once    sync.Once
+}
+
+func newOutput() *output {
+	return &output{started: make(chan struct{})}
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.once.Do(func() { close(o.started) })
+	return o.builder.Write(p)
+}
+
+func (o *output) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.builder.String()
+}
+
+func newAgent(t *testing.T, name string, capacity int, simulator Simulator) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(NewServer(Info{Name: name, Capacity: capacity, Platforms: []string{"linux/amd64"}}, simulator, "", "").Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTask(path string, out *output) builder.BuildTask {
+	return builder.BuildTask{ServicePath: path, ImageName: path[strings.LastIndex(path, "/")+1:], Tag: "v1", Config: &config.Config{}, Output: out}
+}
+
+func TestCoordinatorRedispatchesTasksOfLostAgent(t *testing.T) {
+	slow := newAgent(t, "slow", 1, Simulator{Duration: 2 * time.Second})
+	fast := newAgent(t, "fast", 2, Simulator{Duration: 40 * time.Millisecond, Fail: map[string]bool{"services/broken": true}})
+
+	coordinator, err := NewCoordinator([]string{slow.URL, fast.URL}, "", true, "")
+	if err != nil {
+		t.Fatalf("NewCoordinator failed: %v", err)
+	}
+	if got := coordinator.Capacity(); got != 3 {
+		t.Fatalf("Expected capacity 3, got %d", got)
+	}
+
+	// The least busy agent is the first registered one, which dies once the build streams output
+	out := newOutput()
+	go func() {
+		<-out.started
+		slow.CloseClientConnections()
+	}()
+	result := coordinator.Run(newTask("services/api", out))
+	if result.Status != "success" {
+		t.Fatalf("Expected the task to succeed on the remaining agent, got %+v", result)
+	}
+	if result.Service != "services/api" || result.Image != "api:v1" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	logs := out.String()
+	if !strings.Contains(logs, "load build definition") || !strings.Contains(logs, "[3/3] RUN make") {
+		t.Errorf("Expected the build output to be streamed, got %q", logs)
+	}
+	if got := coordinator.Capacity(); got != 2 {
+		t.Errorf("Expected the lost agent to leave capacity 2, got %d", got)
+	}
+
+	// Later tasks go to the remaining agent; build failures are results, not lost agents
+	paths := []string{"services/web", "services/worker", "services/broken"}
+	results := make([]builder.BuildResult, len(paths))
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			results[i] = coordinator.Run(newTask(path, newOutput()))
+		}(i, path)
+	}
+	wg.Wait()
+
+	for i, path := range paths {
+		expected := "success"
+		if path == "services/broken" {
+			expected = "failed"
+		}
+		if results[i].Service != path || results[i].Status != expected {
+			t.Errorf("Expected %s to be %s, got %+v", path, expected, results[i])
+		}
+	}
+	if results[2].BuildOutput != "simulated failure" {
+		t.Errorf("Expected the build failure to be reported, got %q", results[2].BuildOutput)
+	}
+}
+
+func TestCoordinatorFailsWithoutAgents(t *testing.T) {
+	agent := newAgent(t, "only", 1, Simulator{Duration: time.Second})
+	coordinator, err := NewCoordinator([]string{agent.URL}, "", true, "")
+	if err != nil {
+		t.Fatalf("NewCoordinator failed: %v", err)
+	}
+
+	out := newOutput()
+	go func() {
+		<-out.started
+		agent.CloseClientConnections()
+	}()
+	result := coordinator.Run(newTask("services/api", out))
+	if result.Status != "failed" || !strings.Contains(result.BuildOutput, "no build agents left") {
+		t.Errorf("Expected the task to fail once its only agent is lost, got %+v", result)
+	}
+}
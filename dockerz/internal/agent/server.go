@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/builder"
+)
+
+// NewServer creates an agent running tasks on backend; an empty token accepts every coordinator
+func NewServer(info Info, backend Backend, token, revision string) *Server {
+	if info.Capacity < 1 {
+		info.Capacity = 1
+	}
+	info.Backend = backend.Name()
+	if revision == "unknown" {
+		revision = ""
+	}
+	return &Server{info: info, backend: backend, token: token, slots: make(chan struct{}, info.Capacity), revision: revision}
+}
+
+// Handler returns the agent's HTTP API:
+// GET /v1/info registers the agent and POST /v1/tasks runs a task, streaming its events
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/info", s.authorized(s.handleInfo))
+	mux.HandleFunc("/v1/tasks", s.authorized(s.handleTask))
+	return mux
+}
+
+// authorized rejects requests without the agent's token
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.token)) != 1 {
+			http.Error(w, "invalid agent token", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	info := s.info
+	info.Running = len(s.slots)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var task Task
+	if err := json.NewDecoder(r.Body).Decode(&task); err != nil || task.Task.Config == nil {
+		http.Error(w, fmt.Sprintf("invalid task: %v", err), http.StatusBadRequest)
+		return
+	}
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	default:
+		http.Error(w, fmt.Sprintf("agent is running %d of %d tasks", len(s.slots), cap(s.slots)), http.StatusServiceUnavailable)
+		return
+	}
+
+	stream := newEventStream(w)
+	defer stream.close()
+
+	build := task.Task
+	if s.revision != "" && task.Revision != "" && task.Revision != "unknown" && task.Revision != s.revision {
+		log.Printf("Refusing %s: checkout is at %s, the build at %s", build.Key(), s.revision, task.Revision)
+		stream.result(builder.BuildResult{
+			Service:     build.Key(),
+			Image:       builder.ImageReference(build.Config, build.ImageName, build.Tag),
+			Status:      "failed",
+			BuildOutput: fmt.Sprintf("agent %s has commit %s checked out, the build is at %s", s.info.Name, s.revision, task.Revision),
+			StartTime:   time.Now(),
+			EndTime:     time.Now(),
+		})
+		return
+	}
+
+	log.Printf("Running %s", build.Key())
+	build.Output = stream
+	result := s.backend.Run(build)
+	log.Printf("Finished %s (status: %s)", build.Key(), result.Status)
+	stream.result(result)
+}
+
+// eventStream writes task events as newline-delimited JSON, with heartbeats while the task is quiet
+type eventStream struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	flusher http.Flusher
+	done    chan struct{}
+	closed  bool
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	stream := &eventStream{encoder: json.NewEncoder(w), done: make(chan struct{})}
+	stream.flusher, _ = w.(http.Flusher)
+	stream.send(Event{Type: EventHeartbeat})
+
+	go func() {
+		ticker := time.NewTicker(HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				stream.send(Event{Type: EventHeartbeat})
+			case <-stream.done:
+				return
+			}
+		}
+	}()
+	return stream
+}
+
+// send writes one event; a coordinator that went away is not the task's concern
+func (s *eventStream) send(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if err := s.encoder.Encode(event); err != nil {
+		return
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+}
+
+// Write streams build output as log events
+func (s *eventStream) Write(p []byte) (int, error) {
+	s.send(Event{Type: EventLog, Data: string(p)})
+	return len(p), nil
+}
+
+func (s *eventStream) result(result builder.BuildResult) {
+	s.send(Event{Type: EventResult, Result: &Result{BuildResult: result, StartTime: result.StartTime, EndTime: result.EndTime, PushDuration: result.PushDuration}})
+}
+
+// close stops heartbeats; the response must not be written once the handler returns
+func (s *eventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	close(s.done)
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/builder"
+)
+
+const (
+	// DefaultListen is the address agents listen on; only local coordinators can reach it
+	DefaultListen = "127.0.0.1:7700"
+	// EnvToken holds the shared token agents and coordinators authenticate with
+	EnvToken = "DOCKERZ_AGENT_TOKEN"
+	// HeartbeatInterval is how often an agent signals a running task is alive
+	HeartbeatInterval = 2 * time.Second
+	// LostAfter is how long a coordinator waits for a heartbeat before declaring the agent lost
+	LostAfter = 5 * HeartbeatInterval
+	// MaxAttempts is how often a task is dispatched before agent loss fails it
+	MaxAttempts = 3
+)
+
+// Backend names
+const (
+	BackendDocker    = "docker"
+	BackendSimulator = "simulator"
+)
+
+// Event types streamed while an agent runs a task
+const (
+	EventLog       = "log"
+	EventHeartbeat = "heartbeat"
+	EventResult    = "result"
+)
+
+// Info is what an agent advertises when a coordinator registers it
+type Info struct {
+	Name      string   `json:"name"`
+	Version   string   `json:"version"`
+	Backend   string   `json:"backend"`
+	Capacity  int      `json:"capacity"`
+	Platforms []string `json:"platforms"`
+	Running   int      `json:"running"`
+}
+
+// Task is a build task sent to an agent
+type Task struct {
+	Task builder.BuildTask `json:"task"`
+	// Revision is the coordinator's git commit; an agent checked out elsewhere refuses the task
+	Revision string `json:"revision,omitempty"`
+}
+
+// Event is one line of the newline-delimited JSON stream answering a task
+type Event struct {
+	Type   string  `json:"type"`
+	Data   string  `json:"data,omitempty"`
+	Result *Result `json:"result,omitempty"`
+}
+
+// Result is a build result with the timings BuildResult leaves out of its JSON
+type Result struct {
+	builder.BuildResult
+	StartTime    time.Time     `json:"start_time"`
+	EndTime      time.Time     `json:"end_time"`
+	PushDuration time.Duration `json:"push_duration"`
+}
+
+// Backend runs build tasks on an agent
+type Backend interface {
+	Name() string
+	Run(task builder.BuildTask) builder.BuildResult
+}
+
+// Simulator is a backend that pretends to build: it writes BuildKit-like output for
+// Duration and succeeds, except for the services in Fail. It needs no Docker daemon.
+type Simulator struct {
+	Duration time.Duration
+	Fail     map[string]bool
+}
+
+// Server is a build agent serving tasks over HTTP
+type Server struct {
+	info    Info
+	backend Backend
+	token   string
+	slots   chan struct{}
+	// revision is the git commit of the agent's checkout, empty when unknown
+	revision string
+}
+
+// Coordinator dispatches build tasks to agents; it is the builder.Runner of distributed builds
+type Coordinator struct {
+	agents   []*remote
+	token    string
+	client   *http.Client
+	push     bool
+	revision string
+	mu       sync.Mutex
+	cond     *sync.Cond
+	// builtOn remembers which agent holds the image of each built service
+	builtOn map[string]*remote
+}
+
+// remote is a registered agent as the coordinator tracks it
+type remote struct {
+	url     string
+	info    Info
+	running int
+	lost    error
+}
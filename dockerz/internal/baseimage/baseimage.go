@@ -0,0 +1,212 @@
+package baseimage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/dockerfile"
+	"github.com/addy-47/dockerz/internal/registry"
+)
+
+// storeFile is the file inside the store directory
+const storeFile = "digests.json"
+
+// Bases returns the external images a Dockerfile builds from: the FROM images of its
+// stages and images copied from with COPY --from, without build stages, scratch and
+// images whose name depends on build arguments without defaults
+func Bases(file *dockerfile.Dockerfile) []string {
+	seen := make(map[string]bool)
+	var bases []string
+	add := func(image string) {
+		if image == "" || strings.EqualFold(image, "scratch") || strings.Contains(image, "$") || seen[image] {
+			return
+		}
+		seen[image] = true
+		bases = append(bases, image)
+	}
+
+	stages := make(map[string]bool)
+	for _, stage := range file.Stages {
+		if stage.Parent < 0 {
+			add(stage.Base)
+		}
+		if stage.Name != "" {
+			stages[strings.ToLower(stage.Name)] = true
+		}
+		for _, instruction := range stage.Instructions {
+			if instruction.Command != "COPY" {
+				continue
+			}
+			// --from names a stage, a stage index or an image
+			if from, ok := instruction.Flag("from"); ok && !stages[strings.ToLower(from)] && strings.Trim(from, "0123456789") != "" {
+				add(file.Expand(from))
+			}
+		}
+	}
+	return bases
+}
+
+// Ignored reports whether an image matches one of the patterns, e.g. "gcr.io/my-project/*"
+func Ignored(image string, patterns []string) bool {
+	name := image
+	if index := strings.LastIndex(name, ":"); index > strings.LastIndex(name, "/") {
+		name = name[:index]
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, image); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// NewResolver creates a resolver using the registry client
+func NewResolver(client *registry.Client) *Resolver {
+	return &Resolver{client: client, cache: make(map[string]resolved)}
+}
+
+// Resolve returns the digest the image's tag currently points to; for multi-platform
+// images that is the digest of the index. Images pinned by digest resolve to that digest.
+func (r *Resolver) Resolve(image string) (string, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[image]
+	r.mu.Unlock()
+	if ok {
+		return cached.digest, cached.err
+	}
+
+	ref, err := registry.ParseReference(image)
+	var digest string
+	if err == nil && ref.Digest != "" {
+		digest = ref.Digest
+	} else if err == nil {
+		var exists bool
+		digest, exists, err = r.client.HeadManifest(ref)
+		if err == nil && !exists {
+			err = fmt.Errorf("image %s not found", ref)
+		}
+	}
+
+	r.mu.Lock()
+	r.cache[image] = resolved{digest: digest, err: err}
+	r.mu.Unlock()
+	return digest, err
+}
+
+// Load reads the recorded digests from a directory; a missing store is empty
+func Load(dir string) (*Store, error) {
+	store := &Store{Services: make(map[string]Record), path: filepath.Join(dir, storeFile)}
+	data, err := os.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return store, fmt.Errorf("failed to read base image digests: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return &Store{Services: make(map[string]Record), path: store.path}, fmt.Errorf("failed to parse base image digests %s: %w", store.path, err)
+	}
+	if store.Services == nil {
+		store.Services = make(map[string]Record)
+	}
+	return store, nil
+}
+
+// Compare returns the state of each of a service's base images given their current
+// digests; images missing from current could not be resolved
+func (s *Store) Compare(service string, images []string, current map[string]string) []Base {
+	record, recorded := s.Services[service]
+	bases := make([]Base, 0, len(images))
+	for _, image := range images {
+		base := Base{Service: service, Image: image, Current: current[image]}
+		if recorded {
+			base.Recorded = record.Bases[image]
+		}
+		switch {
+		case base.Current == "":
+			base.Status = StatusUnknown
+		case base.Recorded == "":
+			base.Status = StatusNew
+		case base.Recorded != base.Current:
+			base.Status = StatusUpdated
+		default:
+			base.Status = StatusCurrent
+		}
+		bases = append(bases, base)
+	}
+	return bases
+}
+
+// Record stores the digests a service was built from. Images that could not be resolved
+// keep their previous digest.
+func (s *Store) Record(service string, digests map[string]string, at time.Time) {
+	bases := make(map[string]string, len(digests))
+	for image, digest := range s.Services[service].Bases {
+		bases[image] = digest
+	}
+	for image, digest := range digests {
+		bases[image] = digest
+	}
+	s.Services[service] = Record{Bases: bases, UpdatedAt: at}
+}
+
+// Baseline records the digests of a service seen for the first time, so later moves are noticed
+func (s *Store) Baseline(service string, digests map[string]string, at time.Time) {
+	if _, recorded := s.Services[service]; !recorded && len(digests) > 0 {
+		s.Record(service, digests, at)
+	}
+}
+
+// Save writes the store, replacing the file atomically
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create base image directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write base image digests: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write base image digests: %w", err)
+	}
+	return nil
+}
+
+// Updated returns the images whose digest moved, sorted
+func Updated(bases []Base) []string {
+	var images []string
+	for _, base := range bases {
+		if base.Status == StatusUpdated {
+			images = append(images, base.Image)
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// Services returns the distinct services with a base image in the given status, sorted
+func Services(bases []Base, status string) []string {
+	seen := make(map[string]bool)
+	var services []string
+	for _, base := range bases {
+		if base.Status == status && !seen[base.Service] {
+			seen[base.Service] = true
+			services = append(services, base.Service)
+		}
+	}
+	sort.Strings(services)
+	return services
+}
@@ -0,0 +1,57 @@
+package baseimage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/registry"
+)
+
+// DefaultDir is where recorded base image digests are kept, relative to the project root
+const DefaultDir = ".dockerz/base-images"
+
+// Record holds the base image digests a service was last built from
+type Record struct {
+	// Bases maps base image references, as written in the Dockerfile, to their digests
+	Bases     map[string]string `json:"bases"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Store holds the base image record of every service, keyed by service key
+type Store struct {
+	Services map[string]Record `json:"services"`
+
+	path string
+}
+
+// Resolver looks up the current digests of images, asking the registry once per image
+type Resolver struct {
+	client *registry.Client
+	mu     sync.Mutex
+	cache  map[string]resolved
+}
+
+// resolved is a cached registry answer
+type resolved struct {
+	digest string
+	err    error
+}
+
+// Status of a base image compared with its record
+const (
+	StatusCurrent = "current"
+	StatusUpdated = "updated"
+	StatusNew     = "new"
+	StatusUnknown = "unknown"
+)
+
+// Base is the state of one base image of a service
+type Base struct {
+	Service  string `json:"service"`
+	Image    string `json:"image"`
+	Recorded string `json:"recorded,omitempty"`
+	Current  string `json:"current,omitempty"`
+	// Status is current, updated (the digest moved), new (not recorded yet) or unknown (not resolvable)
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
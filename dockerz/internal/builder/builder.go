@@ -2,11 +2,19 @@ package builder
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/tracing"
 )
 
 // GetGitCommitID fetches the short Git commit ID for default tagging
@@ -26,10 +34,57 @@ func CheckGARAuth() error {
 	return cmd.Run()
 }
 
+// ImageReference returns the full image reference for an image name and tag
+func ImageReference(cfg *config.Config, imageName, tag string) string {
+	if prefix := cfg.RegistryPrefix(); prefix != "" {
+		return fmt.Sprintf("%s/%s:%s", prefix, imageName, tag)
+	}
+	return fmt.Sprintf("%s:%s", imageName, tag)
+}
+
+// InspectImageID returns the local image ID (sha256:...) of a built image
+func InspectImageID(image string) string {
+	output, err := exec.Command("docker", "image", "inspect", "--format", "{{.Id}}", image).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// InspectImageSize returns the size in bytes of a local image, 0 when unknown
+func InspectImageSize(image string) int64 {
+	output, err := exec.Command("docker", "image", "inspect", "--format", "{{.Size}}", image).Output()
+	if err != nil {
+		return 0
+	}
+	size, _ := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+	return size
+}
+
+// InspectRepoDigest returns the registry digest of a pushed image
+func InspectRepoDigest(image string) string {
+	output, err := exec.Command("docker", "image", "inspect", "--format", "{{range .RepoDigests}}{{println .}}{{end}}", image).Output()
+	if err != nil {
+		return ""
+	}
+
+	// RepoDigests holds one "repo@sha256:..." entry per repository the image was pushed to
+	repository := image
+	if idx := strings.LastIndex(image, ":"); idx > strings.LastIndex(image, "/") {
+		repository = image[:idx]
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if name, digest, found := strings.Cut(strings.TrimSpace(line), "@"); found && name == repository {
+			return digest
+		}
+	}
+	return ""
+}
+
 // BuildDockerImage builds a single Docker image
 func BuildDockerImage(task BuildTask) BuildResult {
 	result := BuildResult{
-		Service:   task.ServicePath,
+		Service:   task.Key(),
 		StartTime: time.Now(),
 	}
 
@@ -37,51 +92,136 @@ func BuildDockerImage(task BuildTask) BuildResult {
 	if !task.NeedsBuild {
 		result.Status = "skipped"
 		result.EndTime = time.Now()
-		log.Printf("Skipping build for %s (smart orchestration)", task.ServicePath)
+		log.Printf("Skipping build for %s (smart orchestration)", task.Key())
 		return result
 	}
 
-	// Construct full image name
-	var imageFullName string
-	if task.Config.UseGAR {
-		imageFullName = fmt.Sprintf("%s-docker.pkg.dev/%s/%s/%s:%s",
-			task.Config.Region, task.Config.Project, task.Config.GAR, task.ImageName, task.Tag)
-	} else {
-		imageFullName = fmt.Sprintf("%s:%s", task.ImageName, task.Tag)
+	// Construct full image names, one per tag; the first is the primary image
+	tags := task.Tags
+	if len(tags) == 0 {
+		tags = []string{task.Tag}
 	}
+	images := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		images = append(images, ImageReference(task.Config, task.ImageName, tag))
+	}
+	imageFullName := images[0]
 
 	result.Image = imageFullName
+	result.Images = images
+
+	log.Printf("Building image for %s: %s", task.Key(), strings.Join(images, ", "))
+
+	span := tracing.Start("docker.build", task.Span, tracing.String("dockerz.service", task.Key()), tracing.String("dockerz.image", imageFullName))
+	defer span.End()
+
+	// Apply every tag and build arg in a single build
+	var buildFlags []string
+	for _, image := range images {
+		buildFlags = append(buildFlags, "-t", image)
+	}
+	buildFlags = append(buildFlags, buildArgs(task)...)
+	if task.Target != "" {
+		buildFlags = append(buildFlags, "--target", task.Target)
+	}
+	if len(task.Platforms) > 0 {
+		buildFlags = append(buildFlags, "--platform", strings.Join(task.Platforms, ","))
+	}
 
-	log.Printf("Building image for %s: %s", task.ServicePath, imageFullName)
+	// The build runs from the service directory; a custom context is passed relative to it
+	contextPath := "."
+	if task.Context != "" {
+		relative, err := filepath.Rel(task.ServicePath, task.Context)
+		if err != nil {
+			relative = task.Context
+		}
+		contextPath = relative
+	}
+	dockerfile := task.Dockerfile
+	if dockerfile == "" {
+		dockerfile = discovery.DefaultDockerfile
+	}
+	if contextPath != "." || dockerfile != discovery.DefaultDockerfile {
+		buildFlags = append(buildFlags, "-f", dockerfile)
+	}
 
 	// Build the image
 	var buildCmd *exec.Cmd
 	if task.Config.EnableBuildKit {
 		// Use BuildKit for better caching and performance
-		buildCmd = exec.Command("docker", "build", "--progress=plain", "--cache-from=type=registry,ref="+imageFullName, "-t", imageFullName, ".")
+		args := append([]string{"build", "--progress=plain", "--cache-from=type=registry,ref=" + imageFullName}, buildFlags...)
+		buildCmd = exec.Command("docker", append(args, contextPath)...)
 		buildCmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1", "BUILDKIT_PROGRESS=plain")
 		log.Printf("Building %s with BuildKit enabled", imageFullName)
 	} else {
 		// Use traditional docker build
-		buildCmd = exec.Command("docker", "build", "-t", imageFullName, ".")
+		args := append([]string{"build"}, buildFlags...)
+		buildCmd = exec.Command("docker", append(args, contextPath)...)
 		log.Printf("Building %s with traditional docker build", imageFullName)
 	}
 	
 	buildCmd.Dir = task.ServicePath
-	buildCmd.Stdout = os.Stdout
-	buildCmd.Stderr = os.Stderr
+	// BuildKit attaches its own spans to the build span through TRACEPARENT
+	if traceParent := span.TraceParent(); traceParent != "" {
+		if buildCmd.Env == nil {
+			buildCmd.Env = os.Environ()
+		}
+		buildCmd.Env = append(buildCmd.Env, tracing.EnvTraceParent+"="+traceParent)
+	}
+	// Build output is passed through and scanned for layer cache hits
+	steps := &stepCounter{dockerfile: dockerfile}
+	if task.Output != nil {
+		buildCmd.Stdout = io.MultiWriter(task.Output, steps)
+		buildCmd.Stderr = buildCmd.Stdout
+	} else {
+		buildCmd.Stdout = io.MultiWriter(os.Stdout, steps)
+		buildCmd.Stderr = io.MultiWriter(os.Stderr, steps)
+	}
 
 	if err := buildCmd.Run(); err != nil {
 		log.Printf("Failed to build %s", imageFullName)
+		span.SetError(err)
 		result.Status = "failed"
 		result.BuildOutput = err.Error()
+		var message string
+		result.FailedStep, result.FailedLine, message = steps.Failure()
+		if message != "" {
+			result.BuildOutput = message
+		}
 		result.EndTime = time.Now()
 		return result
 	}
 
 	log.Printf("Successfully built %s", imageFullName)
 	result.Status = "success"
+	result.ImageID = InspectImageID(imageFullName)
+	result.ImageSize = InspectImageSize(imageFullName)
+	result.CacheHits, result.CacheMisses = steps.Counts()
 	result.EndTime = time.Now()
+	span.SetAttributes(
+		tracing.Int("dockerz.cache.hits", int64(result.CacheHits)),
+		tracing.Int("dockerz.cache.misses", int64(result.CacheMisses)),
+		tracing.Int("dockerz.image.size", result.ImageSize))
 
 	return result
 }
+
+// buildArgs returns --build-arg flags for the task's build args in a stable order
+func buildArgs(task BuildTask) []string {
+	keys := make([]string, 0, len(task.BuildArgs))
+	for key := range task.BuildArgs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var args []string
+	for _, key := range keys {
+		args = append(args, "--build-arg", key+"="+task.BuildArgs[key])
+	}
+	return args
+}
+
+// Key returns the service key of the task (see discovery.DiscoveredService.Key)
+func (t BuildTask) Key() string {
+	return discovery.DiscoveredService{Path: t.ServicePath, Dockerfile: t.Dockerfile, Target: t.Target}.Key()
+}
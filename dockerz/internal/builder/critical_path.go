@@ -0,0 +1,354 @@
+package builder
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/history"
+)
+
+// taskQueue hands out tasks whose dependencies in this build have finished, the task with
+// the longest critical path first: its own expected duration plus that of the longest chain
+// of services waiting on it. Ties go to the task with more dependents, then queue order.
+type taskQueue struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	tasks      []BuildTask
+	estimates  []time.Duration
+	priorities []time.Duration
+	dependents []int // transitive dependents of each task
+
+	index     map[string]int   // service key -> first task with that key
+	waitingOn []int            // unfinished in-build dependencies per task
+	children  map[string][]int // service key -> tasks depending on it
+	ready     []int
+	readyAt   map[int]time.Time
+	started   map[int]time.Time
+	done      map[string]bool
+	failed    map[string]bool
+	handedOut int
+	finished  int
+}
+
+// newTaskQueue orders tasks by critical path using duration estimates from the build history
+func newTaskQueue(tasks []BuildTask, store *history.Store) *taskQueue {
+	q := &taskQueue{
+		tasks:     tasks,
+		index:     make(map[string]int, len(tasks)),
+		waitingOn: make([]int, len(tasks)),
+		children:  make(map[string][]int),
+		readyAt:   make(map[int]time.Time),
+		started:   make(map[int]time.Time),
+		done:      make(map[string]bool),
+		failed:    make(map[string]bool),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	for i, task := range tasks {
+		if _, ok := q.index[task.Key()]; !ok {
+			q.index[task.Key()] = i
+		}
+	}
+
+	for i, task := range tasks {
+		for _, dependency := range task.DependsOn {
+			if _, inBuild := q.index[dependency]; inBuild && dependency != task.Key() {
+				q.waitingOn[i]++
+				q.children[dependency] = append(q.children[dependency], i)
+			}
+		}
+	}
+
+	q.estimates = estimateDurations(tasks, store)
+	q.priorities, q.dependents = q.criticalPaths()
+
+	now := time.Now()
+	for i := range tasks {
+		if q.waitingOn[i] == 0 {
+			q.ready = append(q.ready, i)
+			q.readyAt[i] = now
+		}
+	}
+	return q
+}
+
+// estimateDurations returns the expected duration of each task. Services without history
+// are expected to take the average of those with history; skipped services take no time.
+func estimateDurations(tasks []BuildTask, store *history.Store) []time.Duration {
+	estimates := make([]time.Duration, len(tasks))
+	known := make([]bool, len(tasks))
+	var total time.Duration
+	count := 0
+	for i, task := range tasks {
+		if store == nil || !task.NeedsBuild {
+			continue
+		}
+		if estimate, ok := store.Estimate(task.Key()); ok {
+			estimates[i], known[i] = estimate, true
+			total += estimate
+			count++
+		}
+	}
+
+	if count > 0 {
+		average := total / time.Duration(count)
+		for i, task := range tasks {
+			if !known[i] && task.NeedsBuild {
+				estimates[i] = average
+			}
+		}
+	}
+	return estimates
+}
+
+// criticalPaths returns each task's critical path length and number of transitive dependents
+func (q *taskQueue) criticalPaths() ([]time.Duration, []int) {
+	priorities := make([]time.Duration, len(q.tasks))
+	dependents := make([]int, len(q.tasks))
+	state := make([]int, len(q.tasks)) // 0 unvisited, 1 visiting, 2 done
+
+	var visit func(i int) map[int]bool
+	reach := make([]map[int]bool, len(q.tasks))
+	visit = func(i int) map[int]bool {
+		if state[i] == 2 {
+			return reach[i]
+		}
+		reach[i] = make(map[int]bool)
+		if state[i] == 1 {
+			// Cycles are rejected by discovery; never recurse forever
+			return reach[i]
+		}
+		state[i] = 1
+		var longest time.Duration
+		for _, child := range q.children[q.tasks[i].Key()] {
+			childReach := visit(child)
+			reach[i][child] = true
+			for j := range childReach {
+				reach[i][j] = true
+			}
+			if priorities[child] > longest {
+				longest = priorities[child]
+			}
+		}
+		priorities[i] = q.estimates[i] + longest
+		dependents[i] = len(reach[i])
+		state[i] = 2
+		return reach[i]
+	}
+	for i := range q.tasks {
+		visit(i)
+	}
+	return priorities, dependents
+}
+
+// next blocks until a task is ready and returns it, the time it became ready and the first
+// of its dependencies that failed, if any; ok is false when every task has been handed out
+func (q *taskQueue) next() (task BuildTask, readyAt time.Time, failedDependency string, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.ready) == 0 && q.handedOut < len(q.tasks) {
+		if q.handedOut == q.finished {
+			// Nothing running can release a task: an unresolved cycle. Release the rest in order.
+			for i := range q.tasks {
+				if _, started := q.started[i]; !started && q.waitingOn[i] > 0 {
+					q.waitingOn[i] = 0
+					q.ready = append(q.ready, i)
+					q.readyAt[i] = time.Now()
+				}
+			}
+			break
+		}
+		q.cond.Wait()
+	}
+	if len(q.ready) == 0 {
+		return BuildTask{}, time.Time{}, "", false
+	}
+
+	sort.SliceStable(q.ready, func(a, b int) bool {
+		i, j := q.ready[a], q.ready[b]
+		if q.priorities[i] != q.priorities[j] {
+			return q.priorities[i] > q.priorities[j]
+		}
+		if q.dependents[i] != q.dependents[j] {
+			return q.dependents[i] > q.dependents[j]
+		}
+		return i < j
+	})
+	i := q.ready[0]
+	q.ready = q.ready[1:]
+	q.handedOut++
+	q.started[i] = time.Now()
+
+	task = q.tasks[i]
+	for _, dependency := range task.DependsOn {
+		if q.failed[dependency] {
+			return task, q.readyAt[i], dependency, true
+		}
+	}
+	return task, q.readyAt[i], "", true
+}
+
+// finish records a task's outcome and releases its dependents
+func (q *taskQueue) finish(task BuildTask, buildFailed bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.finished++
+	for i, started := range q.started {
+		if q.tasks[i].Key() == task.Key() && !started.IsZero() {
+			q.started[i] = time.Time{}
+			break
+		}
+	}
+	if buildFailed {
+		q.failed[task.Key()] = true
+	}
+	// A service listed twice only releases its dependents once
+	if !q.done[task.Key()] {
+		q.done[task.Key()] = true
+		for _, child := range q.children[task.Key()] {
+			q.waitingOn[child]--
+			if q.waitingOn[child] == 0 {
+				q.ready = append(q.ready, child)
+				q.readyAt[child] = time.Now()
+			}
+		}
+	}
+	q.cond.Broadcast()
+}
+
+// eta estimates the time until every task has finished with the given number of workers
+func (q *taskQueue) eta(workers int) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	remaining := make(map[int]time.Duration)
+	for i := range q.tasks {
+		started, handedOut := q.started[i]
+		switch {
+		case !handedOut:
+			remaining[i] = q.estimates[i]
+		case !started.IsZero():
+			// Running: what is left of its estimate
+			left := q.estimates[i] - now.Sub(started)
+			if left < 0 {
+				left = 0
+			}
+			remaining[i] = left
+		}
+	}
+	return q.simulate(remaining, workers)
+}
+
+// simulate list-schedules the remaining tasks by priority and returns the makespan.
+// Tasks already running are placed first. Callers hold q.mu.
+func (q *taskQueue) simulate(remaining map[int]time.Duration, workers int) time.Duration {
+	if workers < 1 {
+		workers = 1
+	}
+	waiting := make(map[int]int, len(remaining))
+	for i := range remaining {
+		for _, dependency := range q.tasks[i].DependsOn {
+			if j, inBuild := q.index[dependency]; inBuild && j != i {
+				if _, pending := remaining[j]; pending {
+					waiting[i]++
+				}
+			}
+		}
+	}
+
+	type slot struct {
+		task int
+		end  time.Duration
+	}
+	var running []slot
+	var ready []int
+	for i := range remaining {
+		if waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	// Running tasks go first
+	for i := range q.tasks {
+		if started, ok := q.started[i]; ok && !started.IsZero() {
+			running = append(running, slot{task: i, end: remaining[i]})
+			ready = removeIndex(ready, i)
+		}
+	}
+
+	var clock time.Duration
+	done := 0
+	for done < len(remaining) {
+		sort.Slice(ready, func(a, b int) bool {
+			if q.priorities[ready[a]] != q.priorities[ready[b]] {
+				return q.priorities[ready[a]] > q.priorities[ready[b]]
+			}
+			return ready[a] < ready[b]
+		})
+		for len(running) < workers && len(ready) > 0 {
+			running = append(running, slot{task: ready[0], end: clock + remaining[ready[0]]})
+			ready = ready[1:]
+		}
+		if len(running) == 0 {
+			// Only an unresolved cycle is left
+			break
+		}
+
+		sort.Slice(running, func(a, b int) bool { return running[a].end < running[b].end })
+		next := running[0]
+		running = running[1:]
+		clock = next.end
+		done++
+		for _, child := range q.children[q.tasks[next.task].Key()] {
+			if _, pending := remaining[child]; pending {
+				waiting[child]--
+				if waiting[child] == 0 {
+					ready = append(ready, child)
+				}
+			}
+		}
+	}
+	return clock
+}
+
+// removeIndex removes a value from a slice of task indexes
+func removeIndex(indexes []int, value int) []int {
+	for i, index := range indexes {
+		if index == value {
+			return append(indexes[:i], indexes[i+1:]...)
+		}
+	}
+	return indexes
+}
+
+// criticalPath returns the keys of the longest dependency chain and its expected duration
+func (q *taskQueue) criticalPath() ([]string, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	start := -1
+	for i := range q.tasks {
+		if q.waitingOn[i] == 0 && (start == -1 || q.priorities[i] > q.priorities[start]) {
+			start = i
+		}
+	}
+	if start == -1 {
+		return nil, 0
+	}
+
+	var path []string
+	for i := start; i != -1; {
+		path = append(path, q.tasks[i].Key())
+		next := -1
+		for _, child := range q.children[q.tasks[i].Key()] {
+			if next == -1 || q.priorities[child] > q.priorities[next] {
+				next = child
+			}
+		}
+		i = next
+	}
+	return path, q.priorities[start]
+}
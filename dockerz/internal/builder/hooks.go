@@ -0,0 +1,130 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/config"
+)
+
+// Lifecycle events hooks can be attached to
+const (
+	HookPreBuild  = "pre_build"
+	HookPostBuild = "post_build"
+	HookPostPush  = "post_push"
+	HookOnFailure = "on_failure"
+)
+
+// defaultHookTimeout bounds hooks that do not configure a timeout
+const defaultHookTimeout = 5 * time.Minute
+
+// HookContext is the information passed to a hook as env vars and JSON on stdin
+type HookContext struct {
+	Event       string   `json:"event"`
+	Service     string   `json:"service"`
+	ServicePath string   `json:"service_path"`
+	Image       string   `json:"image"`
+	Images      []string `json:"images,omitempty"`
+	Tag         string   `json:"tag"`
+	Digest      string   `json:"digest,omitempty"`
+	Status      string   `json:"status"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// hooksFor returns the global hooks followed by the service's own hooks for an event
+func hooksFor(global, service config.HooksConfig, event string) []config.Hook {
+	pick := func(h config.HooksConfig) []config.Hook {
+		switch event {
+		case HookPreBuild:
+			return h.PreBuild
+		case HookPostBuild:
+			return h.PostBuild
+		case HookPostPush:
+			return h.PostPush
+		case HookOnFailure:
+			return h.OnFailure
+		}
+		return nil
+	}
+
+	var hooks []config.Hook
+	hooks = append(hooks, pick(global)...)
+	hooks = append(hooks, pick(service)...)
+	return hooks
+}
+
+// RunHooks runs every hook for an event in order. It returns an error for the first
+// failing hook whose policy is "fail"; failures of "ignore" hooks are only logged.
+func RunHooks(task BuildTask, event string, hookCtx HookContext) error {
+	hooks := hooksFor(task.Config.Hooks, task.Hooks, event)
+	hookCtx.Event = event
+
+	for i, hook := range hooks {
+		name := hook.Name
+		if name == "" {
+			name = fmt.Sprintf("%s[%d]", event, i)
+		}
+
+		log.Printf("Running hook %s for %s", name, task.ServicePath)
+		output, err := runHook(hook, task.ServicePath, hookCtx)
+		if output != "" {
+			log.Printf("Hook %s output:\n%s", name, output)
+		}
+		if err == nil {
+			continue
+		}
+
+		if hook.Policy == "ignore" {
+			log.Printf("Warning: hook %s failed for %s (ignored): %v", name, task.ServicePath, err)
+			continue
+		}
+		return fmt.Errorf("hook %s failed: %w", name, err)
+	}
+
+	return nil
+}
+
+// runHook executes a single hook through the shell with the hook context
+func runHook(hook config.Hook, servicePath string, hookCtx HookContext) (string, error) {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	payload, err := json.Marshal(hookCtx)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode hook payload: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	cmd.Dir = servicePath
+	// Don't wait on background processes that keep the output pipe open after a timeout
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"DOCKERZ_HOOK="+hookCtx.Event,
+		"DOCKERZ_SERVICE="+hookCtx.Service,
+		"DOCKERZ_SERVICE_PATH="+hookCtx.ServicePath,
+		"DOCKERZ_IMAGE="+hookCtx.Image,
+		"DOCKERZ_IMAGES="+strings.Join(hookCtx.Images, " "),
+		"DOCKERZ_TAG="+hookCtx.Tag,
+		"DOCKERZ_DIGEST="+hookCtx.Digest,
+		"DOCKERZ_STATUS="+hookCtx.Status,
+		"DOCKERZ_ERROR="+hookCtx.Error,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return strings.TrimSpace(string(output)), fmt.Errorf("timed out after %v", timeout)
+	}
+	return strings.TrimSpace(string(output)), err
+}
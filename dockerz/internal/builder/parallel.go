@@ -3,12 +3,17 @@ package builder
 import (
 	"fmt"
 	"log"
+	"math"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/addy-47/dockerz/internal/cgroup"
 	"github.com/addy-47/dockerz/internal/config"
 	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/history"
+	"github.com/addy-47/dockerz/internal/tracing"
 )
 
 // ResourceAwareConfig holds configuration for resource-aware scheduling
@@ -22,7 +27,15 @@ type ResourceAwareConfig struct {
 
 // BuildImages builds Docker images for discovered services in parallel
 func BuildImages(cfg *config.Config, discoveryResult *discovery.DiscoveryResult, maxProcesses int) ([]BuildResult, Summary) {
+	return BuildImagesWith(cfg, discoveryResult, maxProcesses, nil)
+}
+
+// BuildImagesWith builds like BuildImages but hands every task to runner, which then
+// owns hooks and pushes; maxProcesses is the runner's capacity. A nil runner builds locally.
+func BuildImagesWith(cfg *config.Config, discoveryResult *discovery.DiscoveryResult, maxProcesses int, runner Runner) ([]BuildResult, Summary) {
 	startTime := time.Now()
+	span := tracing.Start("build", nil, tracing.Int("dockerz.services", int64(len(discoveryResult.Services))), tracing.Int("dockerz.max_processes", int64(maxProcesses)))
+	defer span.End()
 
 	// Create build.log file
 	logFile, err := os.OpenFile("build.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
@@ -48,7 +61,7 @@ func BuildImages(cfg *config.Config, discoveryResult *discovery.DiscoveryResult,
 
 	// Initialize resource monitor
 	var resourceMonitor *ResourceMonitor
-	if resourceConfig.EnableResourceMonitoring {
+	if resourceConfig.EnableResourceMonitoring && runner == nil {
 		monitorConfig := ResourceMonitorConfig{
 			MaxCPUThreshold:    resourceConfig.MaxCPUThreshold,
 			MaxMemoryThreshold: resourceConfig.MaxMemoryThreshold,
@@ -56,32 +69,53 @@ func BuildImages(cfg *config.Config, discoveryResult *discovery.DiscoveryResult,
 			CheckInterval:      resourceConfig.MonitorInterval,
 		}
 		resourceMonitor = NewResourceMonitor(monitorConfig)
+
+		log.Printf("Resource-aware scheduling enabled: CPU<%.0f%%, Memory<%.0f%%, Disk<%.0f%% (%s)",
+			resourceConfig.MaxCPUThreshold, resourceConfig.MaxMemoryThreshold, resourceConfig.MaxDiskThreshold, resourceMonitor.DiskPath())
+		log.Printf("System info: %s", GetSystemInfo(resourceMonitor.DiskPath()))
+	}
+
+	if limits := cgroup.Read(); runner == nil && limits.CPUs > 0 && float64(maxProcesses) > math.Ceil(limits.CPUs) {
+		log.Printf("WARNING: max_processes=%d exceeds the cgroup CPU quota of %.2f CPUs", maxProcesses, limits.CPUs)
+	}
+
+	// Parallelism adapts to resource pressure when monitoring is enabled, up to maxProcesses
+	scheduler := NewAdaptiveScheduler(maxProcesses)
+	if resourceMonitor != nil {
+		scheduler.Follow(resourceMonitor)
 		resourceMonitor.Start()
 		defer resourceMonitor.Stop()
-
-		log.Printf("Resource-aware scheduling enabled: CPU<%.0f%%, Memory<%.0f%%, Disk<%.0f%%",
-			resourceConfig.MaxCPUThreshold, resourceConfig.MaxMemoryThreshold, resourceConfig.MaxDiskThreshold)
-		log.Printf("System info: %s", GetSystemInfo())
 	}
 
-	// Initialize PushManager if push to GAR is enabled
+	// Initialize PushManager if push to GAR is enabled; a runner pushes where it builds
 	var pushManager *PushManager
-	if cfg.UseGAR && cfg.PushToGAR {
+	if cfg.UseGAR && cfg.PushToGAR && runner == nil {
 		pushManager = NewPushManager(cfg, maxProcesses/2) // Use half the processes for pushes
 		pushManager.Start()
 		defer pushManager.Stop()
 	}
 
-	// Prepare build tasks
+	// Prepare build tasks, queuing dependencies before the services that depend on them
 	tasks := make([]BuildTask, 0, len(discoveryResult.Services))
-	for _, service := range discoveryResult.Services {
+	for _, service := range discovery.SortByDependencies(discoveryResult.Services) {
 		task := BuildTask{
 			ServicePath: service.Path,
+			Dockerfile:  service.Dockerfile,
+			Target:      service.Target,
+			Platforms:   service.Platforms,
 			ImageName:   service.ImageName,
 			Tag:         service.Tag,
+			Tags:        service.Tags,
+			ServiceName: service.Name,
 			Config:      cfg,
+			Context:     service.Context,
+			BuildArgs:   service.BuildArgs,
+			DependsOn:   service.DependsOn,
 			NeedsBuild:  service.NeedsBuild,
 		}
+		if serviceCfg, ok := cfg.ServiceConfig(service.Key()); ok {
+			task.Hooks = serviceCfg.Hooks
+		}
 		tasks = append(tasks, task)
 	}
 
@@ -93,18 +127,25 @@ func BuildImages(cfg *config.Config, discoveryResult *discovery.DiscoveryResult,
 	// Channel to receive results
 	resultsChan := make(chan BuildResult, len(tasks))
 
-	// Semaphore to limit concurrent goroutines
-	sem := make(chan struct{}, maxProcesses)
-
 	// WaitGroup to wait for all goroutines to complete
 	var wg sync.WaitGroup
 
-	// Task queue for resource-aware scheduling
-	taskQueue := make(chan BuildTask, len(tasks))
-	for _, task := range tasks {
-		taskQueue <- task
+	// Ready tasks are handed out longest critical path first, using durations from past runs
+	buildHistory, err := history.Load(history.DefaultDir)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	queue := newTaskQueue(tasks, buildHistory)
+	estimated := queue.eta(maxProcesses)
+	if path, length := queue.criticalPath(); estimated > 0 {
+		log.Printf("Estimated build time: %s (critical path %s: %s)", estimated.Round(time.Second), length.Round(time.Second), strings.Join(path, " -> "))
+		if logFile != nil {
+			fmt.Fprintf(logFile, "Estimated build time: %s\n", estimated.Round(time.Second))
+		}
 	}
-	close(taskQueue)
+
+	var completedMu sync.Mutex
+	completed := 0
 
 	// Worker pool with resource-aware scheduling
 	for i := 0; i < maxProcesses; i++ {
@@ -112,43 +153,72 @@ func BuildImages(cfg *config.Config, discoveryResult *discovery.DiscoveryResult,
 		go func(workerID int) {
 			defer wg.Done()
 
-			for task := range taskQueue {
-				// Resource-aware scheduling: wait for resources to be available
-				if resourceMonitor != nil {
-					for {
-						if resourceMonitor.CanSchedule() {
-							break
-						}
-						time.Sleep(500 * time.Millisecond) // Wait before retrying
+			for {
+				task, readyAt, dependency, ok := queue.next()
+				if !ok {
+					return
+				}
+				task.Span = tracing.Start("build.service", span,
+					tracing.String("dockerz.service", task.Key()),
+					tracing.String("dockerz.service.name", task.ServiceName),
+					tracing.String("dockerz.image", ImageReference(cfg, task.ImageName, task.Tag)))
+				if dependency != "" {
+					log.Printf("Worker %d: Not building %s because dependency %s failed", workerID, task.Key(), dependency)
+					task.Span.SetError(fmt.Errorf("dependency %s failed", dependency))
+					task.Span.End()
+					result := BuildResult{
+						Service:     task.Key(),
+						Image:       ImageReference(cfg, task.ImageName, task.Tag),
+						Status:      "failed",
+						BuildOutput: fmt.Sprintf("dependency %s failed", dependency),
+						StartTime:   time.Now(),
+						EndTime:     time.Now(),
 					}
+					queue.finish(task, true)
+					resultsChan <- result
+					continue
 				}
 
-				sem <- struct{}{} // Acquire semaphore
-
-				log.Printf("Worker %d: Starting build for %s", workerID, task.ServicePath)
-				result := BuildDockerImage(task)
-
-				// If push to GAR is enabled and build was successful, queue the push
-				if pushManager != nil && result.Status == "success" {
-					log.Printf("Queueing push to GAR: %s", result.Image)
-					resultChan := pushManager.QueuePush(result.Image, task.ServicePath)
-
-					// Wait for push result and update result status
-					pushResult := <-resultChan
-					if pushResult.Status == "success" {
-						result.PushStatus = "success"
-						log.Printf("Successfully pushed %s", result.Image)
-					} else {
-						result.PushStatus = "failed"
-						result.PushOutput = pushResult.Output
-						log.Printf("Failed to push %s: %v", result.Image, pushResult.Output)
-					}
+				// Wait for a slot under the current, possibly throttled, parallelism
+				scheduler.Acquire()
+
+				log.Printf("Worker %d: Starting build for %s", workerID, task.Key())
+				started := time.Now()
+				var result BuildResult
+				if runner != nil {
+					result = runner.Run(task)
+				} else {
+					result = RunTask(task, pushManager)
 				}
+				result.Duration = time.Since(started)
+				result.QueueWait = started.Sub(readyAt)
+
+				scheduler.Release()
 
-				<-sem // Release semaphore
+				task.Span.SetAttributes(
+					tracing.String("dockerz.status", result.Status),
+					tracing.Float("dockerz.queue_wait_seconds", result.QueueWait.Seconds()))
+				if result.PushStatus != "" {
+					task.Span.SetAttributes(tracing.String("dockerz.push.status", result.PushStatus), tracing.Int("dockerz.push.retries", int64(result.PushRetries)))
+				}
+				if result.Status == "failed" {
+					task.Span.SetError(fmt.Errorf("%s", result.BuildOutput))
+				} else if result.PushStatus == "failed" {
+					task.Span.SetError(fmt.Errorf("push failed: %s", result.PushOutput))
+				}
+				task.Span.End()
+
+				queue.finish(task, result.Status == "failed")
 
 				resultsChan <- result
-				log.Printf("Worker %d: Completed build for %s (status: %s)", workerID, task.ServicePath, result.Status)
+				log.Printf("Worker %d: Completed build for %s (status: %s)", workerID, task.Key(), result.Status)
+
+				completedMu.Lock()
+				completed++
+				if estimated > 0 && completed < len(tasks) {
+					log.Printf("Progress: %d/%d services, ETA %s", completed, len(tasks), queue.eta(maxProcesses).Round(time.Second))
+				}
+				completedMu.Unlock()
 			}
 		}(i)
 	}
@@ -166,6 +236,9 @@ func BuildImages(cfg *config.Config, discoveryResult *discovery.DiscoveryResult,
 		// Log individual build result to file
 		if logFile != nil {
 			fmt.Fprintf(logFile, "[%s] Service: %s, Image: %s, Status: %s", time.Now().Format("15:04:05"), result.Service, result.Image, result.Status)
+			if len(result.Images) > 1 {
+				fmt.Fprintf(logFile, ", Tags: %s", strings.Join(result.Images, " "))
+			}
 			if result.Status == "failed" {
 				fmt.Fprintf(logFile, ", Build Output: %s", result.BuildOutput)
 			}
@@ -179,6 +252,18 @@ func BuildImages(cfg *config.Config, discoveryResult *discovery.DiscoveryResult,
 		}
 	}
 
+	// Remember how long each service took for the next run's scheduling
+	var sequential time.Duration
+	for _, result := range results {
+		sequential += result.Duration
+		if result.Status != "skipped" && result.Duration > 0 {
+			buildHistory.Add(result.Service, result.Status, result.Duration, result.EndTime)
+		}
+	}
+	if err := buildHistory.Save(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
 	// Calculate summary
 	totalDuration := time.Since(startTime)
 	successfulBuilds := 0
@@ -196,12 +281,29 @@ func BuildImages(cfg *config.Config, discoveryResult *discovery.DiscoveryResult,
 		}
 	}
 
+	span.SetAttributes(tracing.Int("dockerz.builds.success", int64(successfulBuilds)), tracing.Int("dockerz.builds.failed", int64(failedBuilds)))
+	if failedBuilds > 0 {
+		span.SetError(fmt.Errorf("%d builds failed", failedBuilds))
+	}
+
 	summary := Summary{
 		TotalServices:    len(tasks),
 		SuccessfulBuilds: successfulBuilds,
 		FailedBuilds:     failedBuilds,
 		FailedPushes:     failedPushes,
 		Duration:         totalDuration,
+		Estimated:        estimated,
+		Sequential:       sequential,
+	}
+
+	if resourceMonitor != nil {
+		minLimit, throttles, increases := scheduler.Stats()
+		log.Printf("Adaptive scheduler: throttled %d times, raised %d times, parallelism between %d and %d",
+			throttles, increases, minLimit, maxProcesses)
+		if logFile != nil {
+			fmt.Fprintf(logFile, "Adaptive scheduler: throttled %d times, raised %d times, parallelism between %d and %d\n",
+				throttles, increases, minLimit, maxProcesses)
+		}
 	}
 
 	// Print summary
@@ -253,4 +355,97 @@ func BuildImages(cfg *config.Config, discoveryResult *discovery.DiscoveryResult,
 	}
 
 	return results, summary
-}
\ No newline at end of file
+}
+
+// RunTask builds a service, pushes it when configured and runs its lifecycle hooks:
+// pre_build, post_build and post_push around the work, on_failure when any step fails
+func RunTask(task BuildTask, pushManager *PushManager) BuildResult {
+	hookCtx := HookContext{
+		Service:     task.ServiceName,
+		ServicePath: task.ServicePath,
+		Image:       ImageReference(task.Config, task.ImageName, task.Tag),
+		Tag:         task.Tag,
+		Status:      "pending",
+	}
+
+	// fail marks the result failed and runs on_failure hooks, whose own failures are only logged
+	fail := func(result BuildResult, err error) BuildResult {
+		result.Status = "failed"
+		if result.BuildOutput == "" {
+			result.BuildOutput = err.Error()
+		}
+		result.EndTime = time.Now()
+
+		hookCtx.Status = "failed"
+		hookCtx.Error = result.BuildOutput
+		if err := RunHooks(task, HookOnFailure, hookCtx); err != nil {
+			log.Printf("Warning: on_failure hooks for %s: %v", task.ServicePath, err)
+		}
+		return result
+	}
+
+	if task.NeedsBuild {
+		if err := RunHooks(task, HookPreBuild, hookCtx); err != nil {
+			return fail(BuildResult{Service: task.Key(), Image: hookCtx.Image, StartTime: time.Now()}, err)
+		}
+	}
+
+	result := BuildDockerImage(task)
+	if result.Status == "skipped" {
+		return result
+	}
+
+	hookCtx.Image = result.Image
+	hookCtx.Images = result.Images
+	hookCtx.Digest = result.ImageID
+	if result.Status != "success" {
+		return fail(result, fmt.Errorf("build failed"))
+	}
+
+	hookCtx.Status = "built"
+	if err := RunHooks(task, HookPostBuild, hookCtx); err != nil {
+		return fail(result, err)
+	}
+
+	// If push to GAR is enabled and build was successful, queue a push per tag
+	if pushManager != nil {
+		result.PushStatus = "success"
+		pushStarted := time.Now()
+		for _, image := range result.Images {
+			log.Printf("Queueing push to GAR: %s", image)
+			resultChan := pushManager.QueuePush(image, task.ServicePath, task.Span)
+
+			// Wait for push result and update result status
+			pushResult := <-resultChan
+			if pushResult.RetryCount > 1 {
+				result.PushRetries += pushResult.RetryCount - 1
+			}
+			if pushResult.Status == "success" {
+				log.Printf("Successfully pushed %s", image)
+			} else {
+				result.PushStatus = "failed"
+				result.PushOutput = pushResult.Output
+				log.Printf("Failed to push %s: %v", image, pushResult.Output)
+			}
+		}
+		result.PushDuration = time.Since(pushStarted)
+
+		if result.PushStatus != "success" {
+			hookCtx.Status = "push_failed"
+			hookCtx.Error = result.PushOutput
+			if err := RunHooks(task, HookOnFailure, hookCtx); err != nil {
+				log.Printf("Warning: on_failure hooks for %s: %v", task.ServicePath, err)
+			}
+			return result
+		}
+
+		result.Digest = InspectRepoDigest(result.Image)
+		hookCtx.Digest = result.Digest
+		hookCtx.Status = "pushed"
+		if err := RunHooks(task, HookPostPush, hookCtx); err != nil {
+			return fail(result, err)
+		}
+	}
+
+	return result
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/addy-47/dockerz/internal/config"
 	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/tracing"
 )
 
 // PushManager handles throttled and retried Docker image pushes
@@ -29,6 +30,8 @@ type PushTask struct {
 	ImageName   string
 	ServicePath string
 	ResultChan  chan<- PushResult
+	// Span is the parent of the push attempt spans
+	Span *tracing.Span
 }
 
 // PushResult represents the result of a push operation
@@ -104,11 +107,15 @@ func (pm *PushManager) pushWithRetry(task PushTask) PushResult {
 			log.Printf("Attempt %d/%d: Pushing image to GAR: %s", attempt, pm.maxRetries, task.ImageName)
 		}
 
+		span := tracing.Start("docker.push", task.Span, tracing.String("dockerz.image", task.ImageName), tracing.Int("dockerz.push.attempt", int64(attempt)))
 		pushCmd := exec.Command("docker", "push", task.ImageName)
 		pushCmd.Stdout = os.Stdout
 		pushCmd.Stderr = os.Stderr
 
-		if err := pushCmd.Run(); err != nil {
+		err := pushCmd.Run()
+		span.SetError(err)
+		span.End()
+		if err != nil {
 			result.Status = "failed"
 			result.Output = err.Error()
 			result.RetryCount = attempt
@@ -146,14 +153,15 @@ func (pm *PushManager) pushWithRetry(task PushTask) PushResult {
 	return result
 }
 
-// QueuePush adds a push task to the queue
-func (pm *PushManager) QueuePush(imageName, servicePath string) chan PushResult {
+// QueuePush adds a push task to the queue; push attempts are traced under span
+func (pm *PushManager) QueuePush(imageName, servicePath string, span *tracing.Span) chan PushResult {
 	resultChan := make(chan PushResult, 1)
 	
 	pm.pushQueue <- PushTask{
 		ImageName:   imageName,
 		ServicePath: servicePath,
 		ResultChan:  resultChan,
+		Span:        span,
 	}
 	
 	return resultChan
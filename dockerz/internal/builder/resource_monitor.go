@@ -3,10 +3,14 @@ package builder
 import (
 	"fmt"
 	"log"
+	"os"
+	"os/exec"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/addy-47/dockerz/internal/cgroup"
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/mem"
@@ -23,6 +27,25 @@ type ResourceMonitor struct {
 	currentLoad       float64
 	currentMemory     float64
 	currentDisk       float64
+	diskPath          string
+	onSample          []func(ResourceSample)
+
+	// When the cgroup limits CPU or memory, usage is measured against those limits
+	cgroup        cgroup.Limits
+	lastUsage     cgroup.Usage
+	lastUsageTime time.Time
+}
+
+// ResourceSample is one measurement of CPU, memory and Docker data-root disk usage in percent
+type ResourceSample struct {
+	CPU    float64
+	Memory float64
+	Disk   float64
+}
+
+// String formats the sample for logs
+func (s ResourceSample) String() string {
+	return fmt.Sprintf("CPU=%.1f%%, Memory=%.1f%%, Disk=%.1f%%", s.CPU, s.Memory, s.Disk)
 }
 
 // NewResourceMonitor creates a new resource monitor
@@ -56,7 +79,63 @@ func NewResourceMonitor(config ResourceMonitorConfig) *ResourceMonitor {
 		currentLoad:       0,
 		currentMemory:     0,
 		currentDisk:       0,
+		diskPath:          DockerDataRoot(),
+		cgroup:            cgroup.Read(),
+	}
+}
+
+// DockerDataRoot returns the directory holding Docker's images and build cache, whose
+// filesystem is the one builds fill up. It falls back to "/" when the daemon is remote
+// or cannot be asked.
+func DockerDataRoot() string {
+	output, err := exec.Command("docker", "info", "--format", "{{.DockerRootDir}}").Output()
+	if err == nil {
+		if root := strings.TrimSpace(string(output)); root != "" {
+			if _, err := os.Stat(root); err == nil {
+				return root
+			}
+			log.Printf("Docker data root %s is not on this machine, measuring disk usage on /", root)
+			return "/"
+		}
+	}
+	if _, err := os.Stat("/var/lib/docker"); err == nil {
+		return "/var/lib/docker"
+	}
+	return "/"
+}
+
+// OnSample registers a function called with every new sample
+func (rm *ResourceMonitor) OnSample(fn func(ResourceSample)) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.onSample = append(rm.onSample, fn)
+}
+
+// Sample returns the latest measurement
+func (rm *ResourceMonitor) Sample() ResourceSample {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return ResourceSample{CPU: rm.currentLoad, Memory: rm.currentMemory, Disk: rm.currentDisk}
+}
+
+// Pressure returns why a sample is over the thresholds, or nil when it is not
+func (rm *ResourceMonitor) Pressure(sample ResourceSample) []string {
+	var reasons []string
+	if sample.CPU >= rm.maxCPUThreshold {
+		reasons = append(reasons, fmt.Sprintf("CPU %.1f%% >= %.0f%%", sample.CPU, rm.maxCPUThreshold))
+	}
+	if sample.Memory >= rm.maxMemoryThreshold {
+		reasons = append(reasons, fmt.Sprintf("memory %.1f%% >= %.0f%%", sample.Memory, rm.maxMemoryThreshold))
 	}
+	if sample.Disk >= rm.maxDiskThreshold {
+		reasons = append(reasons, fmt.Sprintf("disk %.1f%% on %s >= %.0f%%", sample.Disk, rm.diskPath, rm.maxDiskThreshold))
+	}
+	return reasons
+}
+
+// DiskPath returns the path whose filesystem usage is measured
+func (rm *ResourceMonitor) DiskPath() string {
+	return rm.diskPath
 }
 
 // Start begins monitoring system resources
@@ -97,52 +176,106 @@ func (rm *ResourceMonitor) updateResourceMetrics() {
 		memInfo = &mem.VirtualMemoryStat{UsedPercent: 0}
 	}
 
-	// Get disk usage
-	diskInfo, err := disk.Usage("/")
+	// Get disk usage of the filesystem holding Docker's data
+	diskInfo, err := disk.Usage(rm.diskPath)
 	if err != nil {
 		log.Printf("Warning: Failed to get disk usage: %v", err)
 		diskInfo = &disk.UsageStat{UsedPercent: 0}
 	}
 
 	rm.mu.Lock()
-	defer rm.mu.Unlock()
-	
 	if len(cpuPercent) > 0 {
 		rm.currentLoad = cpuPercent[0]
 	}
 	rm.currentMemory = memInfo.UsedPercent
+	rm.applyCgroupUsage()
 	rm.currentDisk = diskInfo.UsedPercent
+	sample := ResourceSample{CPU: rm.currentLoad, Memory: rm.currentMemory, Disk: rm.currentDisk}
+	callbacks := rm.onSample
+	rm.mu.Unlock()
 
-	log.Printf("Resource Monitor: CPU=%.1f%%, Memory=%.1f%%, Disk=%.1f%%", 
-		rm.currentLoad, rm.currentMemory, rm.currentDisk)
+	log.Printf("Resource Monitor: %s", sample)
+	for _, fn := range callbacks {
+		fn(sample)
+	}
+}
+
+// applyCgroupUsage replaces host-wide CPU and memory usage with usage relative to the
+// cgroup's CPU quota and memory limit. Callers hold rm.mu.
+func (rm *ResourceMonitor) applyCgroupUsage() {
+	if !rm.cgroup.Limited() {
+		return
+	}
+	usage, ok := rm.cgroup.ReadUsage()
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if rm.cgroup.CPUs > 0 && !rm.lastUsageTime.IsZero() && usage.CPUTime >= rm.lastUsage.CPUTime {
+		elapsed := float64(now.Sub(rm.lastUsageTime).Microseconds())
+		if elapsed > 0 {
+			rm.currentLoad = float64(usage.CPUTime-rm.lastUsage.CPUTime) / (elapsed * rm.cgroup.CPUs) * 100
+		}
+	}
+	rm.lastUsage, rm.lastUsageTime = usage, now
+
+	if rm.cgroup.MemoryLimit > 0 {
+		rm.currentMemory = float64(usage.MemoryWorkingSet) / float64(rm.cgroup.MemoryLimit) * 100
+	}
 }
 
 // CanSchedule returns true if resources are available for another build
 func (rm *ResourceMonitor) CanSchedule() bool {
-	rm.mu.Lock()
-	defer rm.mu.Unlock()
+	return len(rm.Pressure(rm.Sample())) == 0
+}
 
-	cpuOK := rm.currentLoad < rm.maxCPUThreshold
-	memoryOK := rm.currentMemory < rm.maxMemoryThreshold
-	diskOK := rm.currentDisk < rm.maxDiskThreshold
+// HostResources describes the host's CPUs and memory
+func HostResources() string {
+	memInfo, err := mem.VirtualMemory()
+	if err != nil {
+		memInfo = &mem.VirtualMemoryStat{}
+	}
+	return fmt.Sprintf("%d CPUs, %.1fGB memory", runtime.NumCPU(), float64(memInfo.Total)/1024/1024/1024)
+}
 
-	return cpuOK && memoryOK && diskOK
+// CgroupResources describes the CPU quota and memory limit of dockerz's cgroup
+func CgroupResources(limits cgroup.Limits) string {
+	if limits.Version == 0 {
+		return "not available"
+	}
+	cpus, memory := "unlimited CPU", "unlimited memory"
+	if limits.CPUs > 0 {
+		cpus = fmt.Sprintf("%.2f CPUs", limits.CPUs)
+	}
+	if limits.MemoryLimit > 0 {
+		memory = fmt.Sprintf("%.1fGB memory", float64(limits.MemoryLimit)/1024/1024/1024)
+	}
+	return fmt.Sprintf("v%d, %s, %s", limits.Version, cpus, memory)
 }
 
-// GetSystemInfo returns basic system information
-func GetSystemInfo() string {
+// GetSystemInfo returns basic system information; disk usage is that of diskPath
+func GetSystemInfo(diskPath string) string {
 	cpuCount := runtime.NumCPU()
 	goMaxProcs := runtime.GOMAXPROCS(0)
 
-	memInfo, _ := mem.VirtualMemory()
-	diskInfo, _ := disk.Usage("/")
+	memInfo, err := mem.VirtualMemory()
+	if err != nil {
+		memInfo = &mem.VirtualMemoryStat{}
+	}
+	diskInfo, err := disk.Usage(diskPath)
+	if err != nil {
+		diskInfo = &disk.UsageStat{}
+	}
 
-	return fmt.Sprintf("System: %d CPUs, GOMAXPROCS=%d, Memory=%.1fGB/%.1fGB (%.1f%% used), Disk=%.1fGB/%.1fGB (%.1f%% used)",
+	return fmt.Sprintf("System: %d CPUs, GOMAXPROCS=%d, Memory=%.1fGB/%.1fGB (%.1f%% used), Disk %s=%.1fGB/%.1fGB (%.1f%% used), cgroup %s",
 		cpuCount, goMaxProcs,
 		float64(memInfo.Used)/1024/1024/1024,
 		float64(memInfo.Total)/1024/1024/1024,
 		memInfo.UsedPercent,
+		diskPath,
 		float64(diskInfo.Used)/1024/1024/1024,
 		float64(diskInfo.Total)/1024/1024/1024,
-		diskInfo.UsedPercent)
+		diskInfo.UsedPercent,
+		CgroupResources(cgroup.Read()))
 }
\ No newline at end of file
@@ -0,0 +1,109 @@
+package builder
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// AdaptiveScheduler limits how many builds run at once. The limit starts at max_processes
+// and follows resource pressure AIMD-style: it is halved when CPU, memory or disk cross
+// their thresholds and raised by one per healthy sample while builds are waiting.
+type AdaptiveScheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int
+	max     int
+	running int
+	waiting int
+
+	// Stats for the build summary
+	minLimit  int
+	throttles int
+	increases int
+}
+
+// NewAdaptiveScheduler creates a scheduler allowing up to max concurrent builds
+func NewAdaptiveScheduler(max int) *AdaptiveScheduler {
+	if max < 1 {
+		max = 1
+	}
+	s := &AdaptiveScheduler{limit: max, max: max, minLimit: max}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Acquire blocks until a build slot is free under the current limit
+func (s *AdaptiveScheduler) Acquire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waiting++
+	for s.running >= s.limit {
+		s.cond.Wait()
+	}
+	s.waiting--
+	s.running++
+}
+
+// Release frees a build slot
+func (s *AdaptiveScheduler) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	s.cond.Broadcast()
+}
+
+// Limit returns the current concurrency limit
+func (s *AdaptiveScheduler) Limit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit
+}
+
+// Adjust updates the limit from a resource sample and returns a description of the change, or "".
+// A decrease waits until the previous one has taken effect, since running builds keep the
+// load up until they finish.
+func (s *AdaptiveScheduler) Adjust(sample ResourceSample, reasons []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.limit
+	switch {
+	case len(reasons) > 0:
+		if s.limit == 1 || s.running > s.limit {
+			return ""
+		}
+		s.limit = s.limit / 2
+		if s.limit < 1 {
+			s.limit = 1
+		}
+		s.throttles++
+		if s.limit < s.minLimit {
+			s.minLimit = s.limit
+		}
+		return fmt.Sprintf("throttling, parallelism %d -> %d: %s (%s)", previous, s.limit, strings.Join(reasons, ", "), sample)
+	case s.waiting > 0 && s.limit < s.max:
+		s.limit++
+		s.increases++
+		s.cond.Broadcast()
+		return fmt.Sprintf("resources available, parallelism %d -> %d (%s)", previous, s.limit, sample)
+	}
+	return ""
+}
+
+// Stats returns the lowest limit reached and the number of decreases and increases
+func (s *AdaptiveScheduler) Stats() (minLimit, throttles, increases int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.minLimit, s.throttles, s.increases
+}
+
+// Follow adjusts the limit on every sample of a resource monitor, logging each change
+func (s *AdaptiveScheduler) Follow(monitor *ResourceMonitor) {
+	monitor.OnSample(func(sample ResourceSample) {
+		if change := s.Adjust(sample, monitor.Pressure(sample)); change != "" {
+			log.Printf("Adaptive scheduler: %s", change)
+		}
+	})
+}
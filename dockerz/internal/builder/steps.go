@@ -0,0 +1,127 @@
+package builder
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// BuildKit plain progress: "#5 [2/3] RUN ..." or "#5 [build 2/3] RUN ...", then "#5 CACHED"
+	buildkitStep   = regexp.MustCompile(`^#(\d+) \[[^\]]*\d+/\d+\]`)
+	buildkitCached = regexp.MustCompile(`^#(\d+) CACHED`)
+	// Classic builder: "Step 2/3 : RUN ..." and " ---> Using cache"
+	classicStep = regexp.MustCompile(`^Step \d+/\d+ :`)
+	// BuildKit failure report: " > [2/3] RUN ...:", then the Dockerfile location "Dockerfile:7"
+	buildkitFailedStep = regexp.MustCompile(`^> (\[[^\]]*\d+/\d+\] .*):$`)
+	dockerfileLocation = regexp.MustCompile(`^(\S+):(\d+)$`)
+	// Classic builder failure: "The command '/bin/sh -c ...' returned a non-zero code: 1"
+	classicFailure = regexp.MustCompile(`^The command .* returned a non-zero code`)
+)
+
+// stepCounter scans docker build output for Dockerfile steps and layer cache hits,
+// and for the step, Dockerfile line and error message of a failed build
+type stepCounter struct {
+	mu      sync.Mutex
+	partial []byte
+	steps   map[string]bool
+	cached  map[string]bool
+	classic int
+	hits    int
+
+	// dockerfile is the name of the Dockerfile being built, as BuildKit reports locations in it
+	dockerfile string
+	lastStep   string
+	failedStep string
+	failedLine int
+	failure    string
+}
+
+// Write implements io.Writer
+func (c *stepCounter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.partial = append(c.partial, p...)
+	for {
+		idx := bytes.IndexByte(c.partial, '\n')
+		if idx == -1 {
+			break
+		}
+		c.scan(strings.TrimSpace(string(c.partial[:idx])))
+		c.partial = c.partial[idx+1:]
+	}
+	return len(p), nil
+}
+
+// scan counts a single output line
+func (c *stepCounter) scan(line string) {
+	if c.steps == nil {
+		c.steps = make(map[string]bool)
+		c.cached = make(map[string]bool)
+	}
+	c.scanFailure(line)
+
+	// Pulling a base image is not a layer of this build
+	if strings.Contains(line, "] FROM ") || (classicStep.MatchString(line) && strings.Contains(line, ": FROM ")) {
+		return
+	}
+	switch {
+	case buildkitStep.MatchString(line):
+		c.steps[buildkitStep.FindStringSubmatch(line)[1]] = true
+	case buildkitCached.MatchString(line):
+		c.cached[buildkitCached.FindStringSubmatch(line)[1]] = true
+	case classicStep.MatchString(line):
+		c.classic++
+		c.lastStep = strings.TrimSpace(line[strings.Index(line, ":")+1:])
+	case strings.HasPrefix(line, "---> Using cache"):
+		c.hits++
+	}
+}
+
+// Counts returns the number of steps served from the layer cache and the number rebuilt
+func (c *stepCounter) Counts() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.steps) > 0 {
+		for id := range c.steps {
+			if c.cached[id] {
+				hits++
+			}
+		}
+		return hits, len(c.steps) - hits
+	}
+	if c.hits > c.classic {
+		return c.hits, 0
+	}
+	return c.hits, c.classic - c.hits
+}
+
+// scanFailure records where and why a build failed
+func (c *stepCounter) scanFailure(line string) {
+	switch {
+	case buildkitFailedStep.MatchString(line):
+		c.failedStep = buildkitFailedStep.FindStringSubmatch(line)[1]
+	case dockerfileLocation.MatchString(line):
+		match := dockerfileLocation.FindStringSubmatch(line)
+		if c.dockerfile != "" && filepath.Base(match[1]) == filepath.Base(c.dockerfile) {
+			c.failedLine, _ = strconv.Atoi(match[2])
+		}
+	case strings.HasPrefix(line, "ERROR: ") || strings.HasPrefix(line, "error: "):
+		c.failure = line[len("ERROR: "):]
+	case classicFailure.MatchString(line):
+		c.failure = line
+		c.failedStep = c.lastStep
+	}
+}
+
+// Failure returns the failed step, its Dockerfile line (0 when unknown) and docker's error message
+func (c *stepCounter) Failure() (step string, line int, message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.failedStep, c.failedLine, c.failure
+}
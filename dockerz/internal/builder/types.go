@@ -1,32 +1,70 @@
 package builder
 
 import (
+	"io"
 	"time"
 
 	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/tracing"
 )
 
 // BuildTask represents a single build task
 type BuildTask struct {
 	ServicePath string
+	Dockerfile  string
+	Target      string
+	Platforms   []string
 	ImageName   string
 	Tag         string
+	Tags        []string
+	ServiceName string
 	Config      *config.Config
+	Hooks       config.HooksConfig
+	Context     string
+	BuildArgs   map[string]string
+	DependsOn   []string
 	CurrentHash string
 	ChangedFiles []string
 	NeedsBuild   bool
+	// Span is the task's trace span; build and push spans are recorded under it
+	Span *tracing.Span `json:"-"`
+	// Output receives the docker build output; nil passes it through to stdout and stderr
+	Output io.Writer `json:"-"`
+}
+
+// Runner executes build tasks away from the local Docker daemon, e.g. on build agents.
+// Run blocks until the task finished and runs its hooks and pushes like a local build.
+type Runner interface {
+	Run(task BuildTask) BuildResult
 }
 
 // BuildResult represents the result of a build operation
 type BuildResult struct {
 	Service     string    `json:"service"`
 	Image       string    `json:"image"`
+	Images      []string  `json:"images,omitempty"`
+	ImageID     string    `json:"image_id,omitempty"`
+	Digest      string    `json:"digest,omitempty"`
 	Status      string    `json:"status"`
 	BuildOutput string    `json:"build_output,omitempty"`
 	PushStatus  string    `json:"push_status,omitempty"`
 	PushOutput  string    `json:"push_output,omitempty"`
+	// FailedStep and FailedLine locate the Dockerfile instruction a failed build stopped at
+	FailedStep string `json:"failed_step,omitempty"`
+	FailedLine int    `json:"failed_line,omitempty"`
 	StartTime   time.Time `json:"-"`
 	EndTime     time.Time `json:"-"`
+	// Duration covers the whole task: hooks, build and push
+	Duration time.Duration `json:"-"`
+	// PushDuration covers pushing every tag, QueueWait the time between the task's
+	// dependencies finishing and a build slot becoming free
+	PushDuration time.Duration `json:"-"`
+	QueueWait    time.Duration `json:"-"`
+	PushRetries  int           `json:"push_retries,omitempty"`
+	// CacheHits and CacheMisses count the Dockerfile steps served from and missing in the layer cache
+	CacheHits   int   `json:"cache_hits,omitempty"`
+	CacheMisses int   `json:"cache_misses,omitempty"`
+	ImageSize   int64 `json:"image_size,omitempty"`
 }
 
 // Summary represents the build summary
@@ -36,4 +74,8 @@ type Summary struct {
 	FailedBuilds     int
 	FailedPushes     int
 	Duration         time.Duration
+	// Estimated is the build time predicted from history, 0 without history
+	Estimated time.Duration
+	// Sequential is the sum of all task durations, the time a one-by-one build would take
+	Sequential time.Duration
 }
\ No newline at end of file
@@ -0,0 +1,241 @@
+package cgroup
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Root is where the cgroup filesystem is mounted
+const Root = "/sys/fs/cgroup"
+
+// unlimitedMemory is the smallest memory.limit_in_bytes treated as "no limit" on cgroup v1,
+// which reports a page-aligned max int64 instead of a marker
+const unlimitedMemory = 1 << 62
+
+// Read returns the limits of the cgroup the current process runs in
+func Read() Limits {
+	return ReadFrom(Root, "/proc/self/cgroup")
+}
+
+// ReadFrom reads the limits from a cgroup mount and a /proc/<pid>/cgroup file
+func ReadFrom(root, procFile string) Limits {
+	paths := readProcCgroup(procFile)
+
+	// cgroup v2: a single unified hierarchy
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		limits := Limits{Version: 2}
+		dir := resolveDir(root, paths[""], "cpu.max")
+		limits.cpuDir = dir
+		limits.memoryDir = resolveDir(root, paths[""], "memory.max")
+		limits.CPUs = minCPUs(root, dir, func(dir string) float64 {
+			fields := strings.Fields(readString(filepath.Join(dir, "cpu.max")))
+			if len(fields) != 2 || fields[0] == "max" {
+				return 0
+			}
+			return quota(fields[0], fields[1])
+		})
+		limits.MemoryLimit = minMemory(root, limits.memoryDir, func(dir string) uint64 {
+			value := readString(filepath.Join(dir, "memory.max"))
+			if value == "max" {
+				return 0
+			}
+			limit, _ := strconv.ParseUint(value, 10, 64)
+			return limit
+		})
+		return limits
+	}
+
+	// cgroup v1: one hierarchy per controller
+	cpuRoot := firstExisting(filepath.Join(root, "cpu,cpuacct"), filepath.Join(root, "cpu"))
+	memoryRoot := filepath.Join(root, "memory")
+	if cpuRoot == "" && !exists(memoryRoot) {
+		return Limits{}
+	}
+
+	limits := Limits{Version: 1}
+	if cpuRoot != "" {
+		cpuPath := paths["cpu"]
+		if cpuPath == "" {
+			cpuPath = paths["cpu,cpuacct"]
+		}
+		limits.cpuDir = resolveDir(cpuRoot, cpuPath, "cpu.cfs_quota_us")
+		limits.CPUs = minCPUs(cpuRoot, limits.cpuDir, func(dir string) float64 {
+			return quota(readString(filepath.Join(dir, "cpu.cfs_quota_us")), readString(filepath.Join(dir, "cpu.cfs_period_us")))
+		})
+	}
+	// cpuacct is mounted with cpu on most hosts, as its own hierarchy on others
+	if cpuacctRoot := firstExisting(filepath.Join(root, "cpu,cpuacct"), filepath.Join(root, "cpuacct")); cpuacctRoot != "" {
+		limits.cpuacctDir = resolveDir(cpuacctRoot, paths["cpuacct"], "cpuacct.usage")
+	}
+	if exists(memoryRoot) {
+		limits.memoryDir = resolveDir(memoryRoot, paths["memory"], "memory.limit_in_bytes")
+		limits.MemoryLimit = minMemory(memoryRoot, limits.memoryDir, func(dir string) uint64 {
+			limit, _ := strconv.ParseUint(readString(filepath.Join(dir, "memory.limit_in_bytes")), 10, 64)
+			if limit >= unlimitedMemory {
+				return 0
+			}
+			return limit
+		})
+	}
+	return limits
+}
+
+// Limited reports whether the cgroup limits CPU or memory
+func (l Limits) Limited() bool {
+	return l.CPUs > 0 || l.MemoryLimit > 0
+}
+
+// ReadUsage returns the cgroup's current CPU time and memory working set
+func (l Limits) ReadUsage() (Usage, bool) {
+	var usage Usage
+	switch l.Version {
+	case 2:
+		if l.cpuDir == "" {
+			return usage, false
+		}
+		usage.CPUTime = readStat(filepath.Join(l.cpuDir, "cpu.stat"), "usage_usec")
+		current, _ := strconv.ParseUint(readString(filepath.Join(l.memoryDir, "memory.current")), 10, 64)
+		usage.MemoryWorkingSet = workingSet(current, readStat(filepath.Join(l.memoryDir, "memory.stat"), "inactive_file"))
+	case 1:
+		if l.cpuacctDir == "" {
+			return usage, false
+		}
+		nanoseconds, _ := strconv.ParseUint(readString(filepath.Join(l.cpuacctDir, "cpuacct.usage")), 10, 64)
+		usage.CPUTime = nanoseconds / 1000
+		current, _ := strconv.ParseUint(readString(filepath.Join(l.memoryDir, "memory.usage_in_bytes")), 10, 64)
+		usage.MemoryWorkingSet = workingSet(current, readStat(filepath.Join(l.memoryDir, "memory.stat"), "total_inactive_file"))
+	default:
+		return usage, false
+	}
+	return usage, true
+}
+
+// readProcCgroup maps each controller list ("" for cgroup v2) to the process's cgroup path
+func readProcCgroup(procFile string) map[string]string {
+	paths := make(map[string]string)
+	file, err := os.Open(procFile)
+	if err != nil {
+		return paths
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		paths[parts[1]] = parts[2]
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+	return paths
+}
+
+// resolveDir returns the cgroup directory of a path under a mount. Inside a container with
+// its own cgroup namespace the mount root already is the process's cgroup.
+func resolveDir(mount, path, file string) string {
+	if path != "" {
+		dir := filepath.Join(mount, path)
+		if exists(filepath.Join(dir, file)) {
+			return dir
+		}
+	}
+	return mount
+}
+
+// minCPUs returns the lowest CPU quota from dir up to the mount root; quotas nest
+func minCPUs(mount, dir string, read func(string) float64) float64 {
+	var lowest float64
+	for _, d := range ancestors(mount, dir) {
+		if cpus := read(d); cpus > 0 && (lowest == 0 || cpus < lowest) {
+			lowest = cpus
+		}
+	}
+	return lowest
+}
+
+// minMemory returns the lowest memory limit from dir up to the mount root
+func minMemory(mount, dir string, read func(string) uint64) uint64 {
+	var lowest uint64
+	for _, d := range ancestors(mount, dir) {
+		if limit := read(d); limit > 0 && (lowest == 0 || limit < lowest) {
+			lowest = limit
+		}
+	}
+	return lowest
+}
+
+// ancestors returns dir and its parents up to and including mount
+func ancestors(mount, dir string) []string {
+	mount = filepath.Clean(mount)
+	dirs := []string{}
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		dirs = append(dirs, d)
+		if d == mount || !strings.HasPrefix(d, mount) || d == filepath.Dir(d) {
+			return dirs
+		}
+	}
+}
+
+// quota converts a CFS quota and period into cores; a negative quota means unlimited
+func quota(quotaValue, periodValue string) float64 {
+	q, err := strconv.ParseFloat(quotaValue, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	period, err := strconv.ParseFloat(periodValue, 64)
+	if err != nil || period <= 0 {
+		return 0
+	}
+	return q / period
+}
+
+// workingSet subtracts inactive page cache from memory usage
+func workingSet(usage, inactiveFile uint64) uint64 {
+	if inactiveFile > usage {
+		return 0
+	}
+	return usage - inactiveFile
+}
+
+// readStat returns a value from a "key value" stat file
+func readStat(file, key string) uint64 {
+	for _, line := range strings.Split(readString(file), "\n") {
+		if name, value, found := strings.Cut(line, " "); found && name == key {
+			n, _ := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// readString returns a file's trimmed content, or "" when it cannot be read
+func readString(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// firstExisting returns the first path that exists, or ""
+func firstExisting(paths ...string) string {
+	for _, path := range paths {
+		if exists(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// exists reports whether a path exists
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
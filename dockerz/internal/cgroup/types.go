@@ -0,0 +1,24 @@
+package cgroup
+
+// Limits are the CPU and memory limits of the cgroup dockerz runs in.
+// Zero values mean unlimited.
+type Limits struct {
+	// Version is 1 or 2, or 0 when no cgroup filesystem was found
+	Version int
+	// CPUs is the CPU quota in cores, e.g. 1.5 for a 150000/100000 quota
+	CPUs float64
+	// MemoryLimit is the memory limit in bytes
+	MemoryLimit uint64
+
+	cpuDir     string
+	cpuacctDir string
+	memoryDir  string
+}
+
+// Usage is a reading of the cgroup's resource usage
+type Usage struct {
+	// CPUTime is the cumulative CPU time in microseconds
+	CPUTime uint64
+	// MemoryWorkingSet is the memory in use minus inactive page cache, as counted by the OOM killer
+	MemoryWorkingSet uint64
+}
@@ -0,0 +1,303 @@
+package ci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/logging"
+)
+
+// Resolve returns the provider for a ci.provider setting; "auto" or empty detects it from the environment
+func Resolve(setting string) (Provider, error) {
+	switch setting {
+	case "", ProviderAuto:
+		return Detect(), nil
+	case string(ProviderGitHub), string(ProviderGitLab), string(ProviderNone):
+		return Provider(setting), nil
+	}
+	return ProviderNone, fmt.Errorf("unknown CI provider '%s' (expected auto, github, gitlab or none)", setting)
+}
+
+// Detect returns the CI system the process runs in, ProviderNone outside CI
+func Detect() Provider {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return ProviderGitHub
+	case os.Getenv("GITLAB_CI") == "true":
+		return ProviderGitLab
+	}
+	return ProviderNone
+}
+
+// Built returns the keys of the services the run built, successfully or not
+func (r Run) Built() []string {
+	var keys []string
+	for _, service := range r.Services {
+		if service.Status != "skipped" {
+			keys = append(keys, service.Key)
+		}
+	}
+	return keys
+}
+
+// Failed returns the keys of the services whose build or push failed
+func (r Run) Failed() []string {
+	var keys []string
+	for _, service := range r.Services {
+		if service.Status == "failed" || service.PushFailed {
+			keys = append(keys, service.Key)
+		}
+	}
+	return keys
+}
+
+// Images returns every image tag built successfully
+func (r Run) Images() []string {
+	var images []string
+	for _, service := range r.Services {
+		if service.Status == "success" {
+			images = append(images, service.Images...)
+		}
+	}
+	return images
+}
+
+// Digests returns the pushed digest of every image tag
+func (r Run) Digests() map[string]string {
+	digests := make(map[string]string)
+	for _, service := range r.Services {
+		if service.Digest == "" {
+			continue
+		}
+		for _, image := range service.Images {
+			digests[image] = service.Digest
+		}
+	}
+	return digests
+}
+
+// Sections returns the collapsible log section markers of the provider, nil if it has none
+func Sections(provider Provider) logging.Sections {
+	switch provider {
+	case ProviderGitHub:
+		return githubSections{}
+	case ProviderGitLab:
+		return gitlabSections{}
+	}
+	return nil
+}
+
+// githubSections renders workflow command log groups; groups cannot nest, so each ends the previous one
+type githubSections struct{}
+
+func (githubSections) Begin(title string) string { return "::group::" + title }
+func (githubSections) End(string) string         { return "::endgroup::" }
+
+// gitlabSections renders GitLab job log sections, collapsed by default
+type gitlabSections struct{}
+
+var sectionNameInvalid = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+func gitlabSectionName(title string) string {
+	return "dockerz_" + strings.Trim(sectionNameInvalid.ReplaceAllString(strings.ToLower(title), "_"), "_")
+}
+
+func (gitlabSections) Begin(title string) string {
+	return fmt.Sprintf("\x1b[0Ksection_start:%d:%s[collapsed=true]\r\x1b[0K%s", time.Now().Unix(), gitlabSectionName(title), title)
+}
+
+func (gitlabSections) End(title string) string {
+	return fmt.Sprintf("\x1b[0Ksection_end:%d:%s\r\x1b[0K", time.Now().Unix(), gitlabSectionName(title))
+}
+
+// WriteGitHub writes step outputs to GITHUB_OUTPUT, a results table to GITHUB_STEP_SUMMARY
+// and error annotations for failed services to annotations (the job log)
+func WriteGitHub(run Run, annotations io.Writer) error {
+	for _, service := range run.Services {
+		if message := annotation(service); message != "" {
+			fmt.Fprintln(annotations, message)
+		}
+	}
+
+	if path := os.Getenv("GITHUB_OUTPUT"); path != "" {
+		var builder strings.Builder
+		writeOutput(&builder, "changed_services", run.Built())
+		writeOutput(&builder, "failed_services", run.Failed())
+		writeOutput(&builder, "images", run.Images())
+		writeOutput(&builder, "digests", run.Digests())
+		fmt.Fprintf(&builder, "has_changes=%t\n", len(run.Built()) > 0)
+		if err := appendFile(path, builder.String()); err != nil {
+			return fmt.Errorf("failed to write GITHUB_OUTPUT: %w", err)
+		}
+	}
+
+	if path := os.Getenv("GITHUB_STEP_SUMMARY"); path != "" {
+		if err := appendFile(path, Markdown(run)); err != nil {
+			return fmt.Errorf("failed to write GITHUB_STEP_SUMMARY: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeOutput writes a step output as single-line JSON, ready for fromJSON()
+func writeOutput(builder *strings.Builder, name string, value interface{}) {
+	if list, ok := value.([]string); ok && list == nil {
+		value = []string{}
+	}
+	encoded, _ := json.Marshal(value)
+	fmt.Fprintf(builder, "%s=%s\n", name, encoded)
+}
+
+// annotation returns the ::error workflow command for a failed service, pointing at the
+// failed Dockerfile line when the build output reported it
+func annotation(service Service) string {
+	switch {
+	case service.Status == "failed":
+		// Failures outside the Dockerfile, e.g. of a dependency or a hook, are not attached to it
+		var properties []string
+		if service.Dockerfile != "" && (service.FailedStep != "" || service.FailedLine > 0) {
+			properties = append(properties, "file="+escapeProperty(service.Dockerfile))
+			if service.FailedLine > 0 {
+				properties = append(properties, fmt.Sprintf("line=%d", service.FailedLine))
+			}
+		}
+		properties = append(properties, "title="+escapeProperty("Build failed: "+service.Key))
+		message := service.Error
+		if service.FailedStep != "" {
+			message = service.FailedStep + ": " + message
+		}
+		return "::error " + strings.Join(properties, ",") + "::" + escapeData(message)
+	case service.PushFailed:
+		return "::error title=" + escapeProperty("Push failed: "+service.Key) + "::" + escapeData(service.Error)
+	}
+	return ""
+}
+
+// escapeData escapes a workflow command message
+func escapeData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+// escapeProperty escapes a workflow command property value
+func escapeProperty(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(value)
+}
+
+// Markdown renders the run as a markdown results table
+func Markdown(run Run) string {
+	var builder strings.Builder
+	counts := map[string]int{}
+	for _, service := range run.Services {
+		counts[service.Status]++
+	}
+	fmt.Fprintf(&builder, "### Dockerz build\n\n%d built, %d failed, %d skipped in %s\n\n",
+		counts["success"], counts["failed"], counts["skipped"], run.Duration.Round(time.Second))
+	builder.WriteString("| Service | Image | Status | Duration | Digest / reason |\n|---|---|---|---|---|\n")
+
+	services := append([]Service(nil), run.Services...)
+	sort.SliceStable(services, func(i, j int) bool { return statusOrder(services[i]) < statusOrder(services[j]) })
+	for _, service := range services {
+		status, detail, duration := service.Status, service.Digest, ""
+		switch {
+		case service.Status == "failed":
+			status, detail = "failed", service.Error
+		case service.PushFailed:
+			status, detail = "push failed", service.Error
+		case service.Status == "success":
+			status = "built"
+		case service.Status == "skipped":
+			status, detail = "skipped", service.Reason
+		}
+		if service.Status != "skipped" {
+			duration = service.Duration.Round(100 * time.Millisecond).String()
+		}
+		fmt.Fprintf(&builder, "| `%s` | `%s` | %s | %s | %s |\n", service.Key, service.Image, status, duration, escapeCell(detail))
+	}
+	builder.WriteString("\n")
+	return builder.String()
+}
+
+// statusOrder lists failures first and skipped services last
+func statusOrder(service Service) int {
+	switch {
+	case service.Status == "failed":
+		return 0
+	case service.PushFailed:
+		return 1
+	case service.Status == "skipped":
+		return 3
+	}
+	return 2
+}
+
+// escapeCell keeps a value inside one markdown table cell
+func escapeCell(value string) string {
+	return strings.NewReplacer("|", "\\|", "\r", " ", "\n", " ").Replace(value)
+}
+
+// WriteGitLab writes a dotenv report for GitLab's artifacts:reports:dotenv, exposing
+// the results to later jobs as variables. Per-service variables are named after the image
+// name; services whose names map to the same variable are left out and reported.
+func WriteGitLab(run Run, path string) error {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "DOCKERZ_CHANGED_SERVICES=%s\n", strings.Join(run.Built(), ","))
+	fmt.Fprintf(&builder, "DOCKERZ_FAILED_SERVICES=%s\n", strings.Join(run.Failed(), ","))
+	fmt.Fprintf(&builder, "DOCKERZ_IMAGES=%s\n", strings.Join(run.Images(), ","))
+
+	owners := make(map[string][]string)
+	for _, service := range run.Services {
+		name := VariableName(service.ImageName)
+		owners[name] = append(owners[name], service.Key)
+	}
+	var collisions []string
+	for _, service := range run.Services {
+		name := VariableName(service.ImageName)
+		if keys := owners[name]; len(keys) > 1 {
+			if keys[0] == service.Key {
+				collisions = append(collisions, fmt.Sprintf("DOCKERZ_IMAGE_%s (%s)", name, strings.Join(keys, ", ")))
+			}
+			continue
+		}
+		if service.Status != "success" {
+			continue
+		}
+		fmt.Fprintf(&builder, "DOCKERZ_IMAGE_%s=%s\n", name, service.Image)
+		if service.Digest != "" {
+			fmt.Fprintf(&builder, "DOCKERZ_DIGEST_%s=%s\n", name, service.Digest)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(builder.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write dotenv report: %w", err)
+	}
+	if len(collisions) > 0 {
+		return fmt.Errorf("dotenv report written to %s without ambiguous variables: %s", path, strings.Join(collisions, "; "))
+	}
+	return nil
+}
+
+var variableNameInvalid = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// VariableName turns an image or service name into an environment variable name suffix (api-worker -> API_WORKER)
+func VariableName(name string) string {
+	return strings.Trim(variableNameInvalid.ReplaceAllString(strings.ToUpper(name), "_"), "_")
+}
+
+// appendFile appends content to a file, as GitHub expects for its command files
+func appendFile(path, content string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
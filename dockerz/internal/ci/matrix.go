@@ -0,0 +1,181 @@
+package ci
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var jobNameInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Jobs returns one job per service, in the given (dependency) order. Each job writes the
+// service key to its own input file and runs buildCommand with --input-changed-services
+// and --input-only, so it builds exactly that service. A job needs the jobs of the nearest
+// dependencies in the matrix, following dependencies through services outside it.
+func Jobs(services []MatrixService, dependencies map[string][]string, buildCommand string) []Job {
+	jobNames := make(map[string]string, len(services))
+	used := make(map[string]bool)
+	for _, service := range services {
+		name := "build-" + strings.Trim(jobNameInvalid.ReplaceAllString(strings.ToLower(service.Name), "-"), "-")
+		unique := name
+		for i := 2; used[unique]; i++ {
+			unique = fmt.Sprintf("%s-%d", name, i)
+		}
+		used[unique] = true
+		jobNames[service.Key] = unique
+	}
+
+	graph := make(map[string][]string, len(dependencies)+len(services))
+	for key, dependsOn := range dependencies {
+		graph[key] = dependsOn
+	}
+	for _, service := range services {
+		graph[service.Key] = service.DependsOn
+	}
+
+	levels := make(map[string]int, len(services))
+	jobs := make([]Job, 0, len(services))
+	for _, service := range services {
+		job := Job{
+			Job:       jobNames[service.Key],
+			Service:   service.Key,
+			Name:      service.Name,
+			Image:     service.Image,
+			Needs:     []string{},
+			InputFile: "dockerz-" + jobNames[service.Key] + ".txt",
+		}
+		for _, need := range nearestInMatrix(service.Key, graph, jobNames) {
+			job.Needs = append(job.Needs, jobNames[need])
+			if levels[need]+1 > job.Level {
+				job.Level = levels[need] + 1
+			}
+		}
+		levels[service.Key] = job.Level
+		job.Command = fmt.Sprintf("printf '%%s\\n' %s > %s && %s --input-changed-services %s --input-only",
+			ShellQuote(service.Key), job.InputFile, buildCommand, job.InputFile)
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// nearestInMatrix returns the dependencies of a service that are in the matrix, looking
+// through dependencies that are not
+func nearestInMatrix(key string, dependencies map[string][]string, inMatrix map[string]string) []string {
+	var found []string
+	seen := map[string]bool{key: true}
+	var visit func(string)
+	visit = func(current string) {
+		for _, dependency := range dependencies[current] {
+			if seen[dependency] {
+				continue
+			}
+			seen[dependency] = true
+			if _, ok := inMatrix[dependency]; ok {
+				found = append(found, dependency)
+			} else {
+				visit(dependency)
+			}
+		}
+	}
+	visit(key)
+	return found
+}
+
+// RenderMatrix renders jobs in a matrix format; level selects the jobs of one dependency
+// level for github-matrix (-1 for all)
+func RenderMatrix(format string, jobs []Job, level int, image string) ([]byte, error) {
+	switch format {
+	case FormatGitHubMatrix:
+		return GitHubMatrix(jobs, level)
+	case FormatGitLabChild:
+		return GitLabChild(jobs, image)
+	case FormatJSON:
+		return encodeJSON(map[string][]Job{"jobs": jobs}, "  ")
+	}
+	return nil, fmt.Errorf("unknown format '%s' (expected %s)", format, strings.Join(MatrixFormats, ", "))
+}
+
+// GitHubMatrix renders a strategy.matrix value as single-line JSON, ready for fromJSON().
+// A matrix cannot order its own entries, so each entry carries its needs and level; chained
+// jobs can each take one level.
+func GitHubMatrix(jobs []Job, level int) ([]byte, error) {
+	include := make([]Job, 0, len(jobs))
+	for _, job := range jobs {
+		if level < 0 || job.Level == level {
+			include = append(include, job)
+		}
+	}
+	return encodeJSON(map[string][]Job{"include": include}, "")
+}
+
+// encodeJSON encodes a value without escaping the shell operators in job commands
+func encodeJSON(value interface{}, indent string) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// gitlabJob is a job of a GitLab child pipeline
+type gitlabJob struct {
+	Stage  string   `yaml:"stage"`
+	Image  string   `yaml:"image,omitempty"`
+	Needs  []string `yaml:"needs"`
+	Script []string `yaml:"script"`
+}
+
+// GitLabChild renders a child pipeline with one job per service, ordered by needs.
+// A pipeline without jobs is invalid, so an empty matrix gets a job reporting that.
+func GitLabChild(jobs []Job, image string) ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	add := func(key string, value interface{}) error {
+		var node yaml.Node
+		if err := node.Encode(value); err != nil {
+			return err
+		}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &node)
+		return nil
+	}
+
+	if err := add("stages", []string{"build"}); err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		if err := add("dockerz-no-changes", gitlabJob{Stage: "build", Image: image, Needs: []string{}, Script: []string{"echo 'No services to build'"}}); err != nil {
+			return nil, err
+		}
+	}
+	for _, job := range jobs {
+		if err := add(job.Job, gitlabJob{Stage: "build", Image: image, Needs: job.Needs, Script: []string{job.Command}}); err != nil {
+			return nil, err
+		}
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("# Generated by dockerz ci-matrix\n")
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// ShellQuote quotes a value for POSIX shells when it contains anything but safe characters
+func ShellQuote(value string) string {
+	if value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@") == "" {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,80 @@
+package ci
+
+import (
+	"time"
+)
+
+// Provider is a CI system dockerz writes integration outputs for
+type Provider string
+
+const (
+	ProviderNone   Provider = "none"
+	ProviderGitHub Provider = "github"
+	ProviderGitLab Provider = "gitlab"
+)
+
+// ProviderAuto detects the provider from the environment
+const ProviderAuto = "auto"
+
+// DefaultDotenv is the GitLab dotenv report written when ci.dotenv is not set
+const DefaultDotenv = "dockerz.env"
+
+// Service is the outcome of one service in a build run
+type Service struct {
+	Key       string
+	Name      string
+	ImageName string
+	Image     string
+	// Images holds every tag of the image
+	Images []string
+	Digest string
+	// Status is "success", "failed" or "skipped"
+	Status     string
+	Reason     string
+	Error      string
+	PushFailed bool
+	Duration   time.Duration
+	// Dockerfile is the path of the service's Dockerfile; FailedLine and FailedStep locate a build failure in it
+	Dockerfile string
+	FailedLine int
+	FailedStep string
+}
+
+// Run is the outcome of a build run
+type Run struct {
+	Services []Service
+	Duration time.Duration
+}
+
+// Matrix output formats
+const (
+	FormatGitHubMatrix = "github-matrix"
+	FormatGitLabChild  = "gitlab-child"
+	FormatJSON         = "json"
+)
+
+// MatrixFormats lists the supported matrix output formats
+var MatrixFormats = []string{FormatGitHubMatrix, FormatGitLabChild, FormatJSON}
+
+// MatrixService is a service to build in its own CI job
+type MatrixService struct {
+	Key   string
+	Name  string
+	Image string
+	// DependsOn holds service keys, including services not in the matrix
+	DependsOn []string
+}
+
+// Job is one CI job of a build matrix, building a single service
+type Job struct {
+	Job     string `json:"job"`
+	Service string `json:"service"`
+	Name    string `json:"name"`
+	Image   string `json:"image"`
+	// Needs lists the jobs building the service's dependencies; Level is the job's depth in
+	// the dependency graph, 0 for jobs without needs
+	Needs     []string `json:"needs"`
+	Level     int      `json:"level"`
+	InputFile string   `json:"input_file"`
+	Command   string   `json:"command"`
+}
@@ -2,12 +2,21 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/addy-47/dockerz/internal/cgroup"
 	"github.com/spf13/viper"
 )
 
+// DefaultDockerfilePatterns are the Dockerfile names discovered when discovery.dockerfiles is not set
+var DefaultDockerfilePatterns = []string{"Dockerfile"}
+
+// DefaultIgnorePatterns are the file names never treated as Dockerfiles, in addition to discovery.ignore
+var DefaultIgnorePatterns = []string{"*.bak", "*.backup", "*.orig", "*.old", "*.swp", "*~"}
+
 // ValidateTxtFile validates that the file path has a .txt extension
 func ValidateTxtFile(filePath string) error {
 	if filePath == "" {
@@ -19,34 +28,110 @@ func ValidateTxtFile(filePath string) error {
 	return nil
 }
 
-// LoadConfig loads configuration from file and environment variables
-func LoadConfig(configPath string) (*Config, error) {
+// LoadConfig loads configuration from file, includes, the selected profile and environment variables.
+// Precedence (highest first): CLI flags (applied by the caller) > DOCKERZ_* env vars > profile > base file,
+// where the base file itself overrides anything it includes.
+func LoadConfig(configPath string, profile ...string) (*Config, error) {
+	config, err := readConfig(configPath, true, profile...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate hook definitions
+	if err := validateHooks("hooks", config.Hooks); err != nil {
+		return nil, err
+	}
+	for _, service := range config.Services {
+		if err := validateHooks(fmt.Sprintf("services[%s].hooks", service.Name), service.Hooks); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate required fields for GAR if enabled
+	if config.UseGAR {
+		if config.Project == "" || config.GAR == "" || config.Region == "" {
+			return nil, fmt.Errorf("missing required fields for GAR: project, gar, region")
+		}
+	}
+
+	// Validate changed services file paths
+	if err := ValidateTxtFile(config.InputChangedServices); err != nil {
+		return nil, fmt.Errorf("invalid input_changed_services: %w", err)
+	}
+	if err := ValidateTxtFile(config.OutputChangedServices); err != nil {
+		return nil, fmt.Errorf("invalid output_changed_services: %w", err)
+	}
+
+	return config, nil
+}
+
+// ReadConfig resolves the configuration like LoadConfig and applies defaults, without validating
+// it or printing progress
+func ReadConfig(configPath string, profile ...string) (*Config, error) {
+	return readConfig(configPath, false, profile...)
+}
+
+// readConfig resolves and unmarshals the configuration and applies defaults
+func readConfig(configPath string, verbose bool, profile ...string) (*Config, error) {
 	// Validate that the config file exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("config file %s does not exist", configPath)
 	}
 
-	// Set up viper
-	viper.SetConfigFile(configPath)
-	viper.SetConfigType("yaml")
+	// Read the file and everything it includes
+	raw, err := readConfigFile(configPath, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	// Overlay the selected profile (--profile, then DOCKERZ_PROFILE)
+	selectedProfile := os.Getenv(ProfileEnv)
+	if len(profile) > 0 && profile[0] != "" {
+		selectedProfile = profile[0]
+	}
+	if err := applyProfile(raw, selectedProfile); err != nil {
+		return nil, fmt.Errorf("failed to apply profile: %w", err)
+	}
+
+	// Expand ${VAR} and ${VAR:-default} in every string value
+	interpolateValues(raw)
 
-	// Environment variables not supported - use CLI flags instead
+	// Viper lower-cases every map key, so services (whose build_args keys are case-sensitive)
+	// are decoded from the raw YAML tree before it is handed over
+	services, err := decodeServices(raw["services"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse services in %s: %w", configPath, err)
+	}
 
-	// Read config file
-	if err := viper.ReadInConfig(); err != nil {
+	// Set up viper
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.MergeConfigMap(raw); err != nil {
 		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
 	}
 
+	// DOCKERZ_* environment variables override file and profile values
+	envOverrides := bindEnvOverrides(v)
+
 	// Log successful config file loading
-	fmt.Printf("✓ Loaded configuration from: %s\n", configPath)
+	if verbose {
+		fmt.Printf("✓ Loaded configuration from: %s\n", configPath)
+		if selectedProfile != "" {
+			fmt.Printf("✓ Applied profile: %s\n", selectedProfile)
+		}
+		if len(envOverrides) > 0 {
+			fmt.Printf("✓ Environment overrides: %s\n", strings.Join(envOverrides, ", "))
+		}
+	}
 
 	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
+	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	config.Services = services
 
 	// Handle backward compatibility for services_dir (can be string or []string)
-	if servicesDirRaw := viper.Get("services_dir"); servicesDirRaw != nil {
+	if servicesDirRaw := v.Get("services_dir"); servicesDirRaw != nil {
 		switch v := servicesDirRaw.(type) {
 		case string:
 			// Handle comma-separated string or single directory
@@ -70,11 +155,9 @@ func LoadConfig(configPath string) (*Config, error) {
 		}
 	}
 
-	// No environment variable overrides for use_gar and push_to_gar - use CLI flags instead
-
 	// Set defaults
 	if config.MaxProcesses == 0 {
-		config.MaxProcesses = 4 // Default to 4 parallel processes
+		config.MaxProcesses = DefaultMaxProcesses()
 	}
 	
 	// Set defaults for resource-aware scheduling
@@ -93,27 +176,76 @@ func LoadConfig(configPath string) (*Config, error) {
 		config.EnableBuildKit = true
 	}
 
+	// Default to plain Dockerfiles
+	if len(config.Discovery.Dockerfiles) == 0 {
+		config.Discovery.Dockerfiles = DefaultDockerfilePatterns
+	}
+
+	// Default versioning tags to "<service>/vX.Y.Z"
+	if config.Versioning.TagPrefix == "" {
+		config.Versioning.TagPrefix = "{service}/v"
+	}
+
 	// Ensure smart features are disabled by default for basic builds
 	if !config.Smart {
 		config.Smart = false
 	}
 
-	// Validate required fields for GAR if enabled
-	if config.UseGAR {
-		if config.Project == "" || config.GAR == "" || config.Region == "" {
-			return nil, fmt.Errorf("missing required fields for GAR: project, gar, region")
+	return &config, nil
+}
+
+// validateHooks checks that every hook has a command and a known policy
+func validateHooks(field string, hooks HooksConfig) error {
+	events := map[string][]Hook{
+		"pre_build":  hooks.PreBuild,
+		"post_build": hooks.PostBuild,
+		"post_push":  hooks.PostPush,
+		"on_failure": hooks.OnFailure,
+	}
+	for event, list := range events {
+		for i, hook := range list {
+			if strings.TrimSpace(hook.Command) == "" {
+				return fmt.Errorf("%s.%s[%d]: command is required", field, event, i)
+			}
+			if hook.Policy != "" && hook.Policy != "fail" && hook.Policy != "ignore" {
+				return fmt.Errorf("%s.%s[%d]: policy must be 'fail' or 'ignore', got '%s'", field, event, i, hook.Policy)
+			}
 		}
 	}
+	return nil
+}
 
-	// Validate changed services file paths
-	if err := ValidateTxtFile(config.InputChangedServices); err != nil {
-		return nil, fmt.Errorf("invalid input_changed_services: %w", err)
+// DefaultMaxProcesses is 4 parallel processes, or fewer when a cgroup CPU quota (e.g. a
+// Kubernetes CPU limit) leaves less capacity
+func DefaultMaxProcesses() int {
+	processes := 4
+	if cpus := cgroup.Read().CPUs; cpus > 0 && int(math.Ceil(cpus)) < processes {
+		processes = int(math.Ceil(cpus))
 	}
-	if err := ValidateTxtFile(config.OutputChangedServices); err != nil {
-		return nil, fmt.Errorf("invalid output_changed_services: %w", err)
+	return processes
+}
+
+// ServiceConfig returns the explicit configuration for a service path or Dockerfile path, if any
+func (c *Config) ServiceConfig(servicePath string) (*Service, bool) {
+	for i := range c.Services {
+		if filepath.Clean(c.Services[i].Name) == filepath.Clean(servicePath) {
+			return &c.Services[i], true
+		}
 	}
+	return nil, false
+}
 
-	return &config, nil
+// RegistryPrefix returns the registry path images are pushed under ("" for local images)
+func (c *Config) RegistryPrefix() string {
+	if c.UseGAR {
+		return fmt.Sprintf("%s-docker.pkg.dev/%s/%s", c.Region, c.Project, c.GAR)
+	}
+	return ""
+}
+
+// VersionTagPrefix returns the git tag prefix used to version a service
+func (c *Config) VersionTagPrefix(serviceName string) string {
+	return strings.ReplaceAll(c.Versioning.TagPrefix, "{service}", serviceName)
 }
 
 // SaveSampleConfig creates a sample build.yaml file
@@ -128,6 +260,30 @@ func SaveSampleConfig(filename string) error {
 # Note: Auto-discovery excludes common build/dependency directories like debian/, node_modules/, .git/, etc.
 services_dir:
 
+# Dockerfile name patterns; every matching file is built as its own image
+# A directory api/ with Dockerfile and Dockerfile.worker yields the images api and api-worker
+# Backup and editor files (*.bak, *.backup, *.orig, *.old, *.swp, *~) are never built
+# discovery:
+#   dockerfiles: ["Dockerfile", "Dockerfile.*", "*.Dockerfile"]   # default: ["Dockerfile"]
+#   ignore: ["Dockerfile.dev"]                                    # additional file patterns to skip
+#   exclude: ["tests/**"]           # path globs never scanned
+#   include: [".docker"]            # path globs scanned despite built-in exclusions or .gitignore
+#   gitignore: true                 # skip paths ignored by .gitignore files
+#   naming:                         # how image names are derived from service paths
+#     strategy: path                # basename (backend/api -> api, default), path (backend-api) or template
+#     separator: "/"                # path strategy only: "-" (default) or "/" for nested repositories
+#     trim_prefix: services         # leading path removed before naming
+#     template: "{{.Parent}}-{{.Base}}"   # template strategy: .Path, .Base, .Parent, .Segments, .Variant, .Name
+# Two services resolving to the same image name stop the build with an error
+# Run 'dockerz discover --explain' to see why each path was scanned or skipped
+
+# docker-compose files whose build sections are imported as services
+# (build.context, dockerfile, args, target, platforms and image); build.yaml services entries override them
+# compose_files: [docker-compose.yml]
+
+# Target platforms for every image, passed to docker build --platform (requires buildx)
+# platforms: [linux/amd64, linux/arm64]
+
 # ===== GOOGLE CLOUD CONFIGURATION =====
 # Configure your Google Cloud Platform settings for Artifact Registry
 
@@ -150,6 +306,27 @@ region: us-central1
 # Override with --tag flag
 global_tag: latest
 
+# Additional tags applied to every image, written as Go templates
+# The primary tag above is always applied first; empty results are skipped
+# Available fields: .Service .Image .Tag .SHA .ShortSHA .Branch .IsMain .GitTag .Semver .ContentHash .Date
+# Example:
+#   tags:
+#     - "{{.ShortSHA}}"
+#     - "{{.Branch}}-{{.ShortSHA}}"
+#     - "{{if .IsMain}}latest{{end}}"
+#     - "{{.Semver}}"
+tags: []
+
+# Per-service semantic versioning from conventional commits
+# Reads the last "<service>/vX.Y.Z" git tag, inspects commits touching the service since then
+# and bumps the version (feat -> minor, fix/perf -> patch, "!" or BREAKING CHANGE -> major).
+# The computed version is added as an image tag. Use --versioning flag to enable
+versioning:
+  enabled: false
+  tag_prefix: "{service}/v"     # git tag prefix; {service} is replaced with the service name
+  create_git_tags: false        # create the new git tag after a successful push
+  push_git_tags: false          # push created git tags to origin
+
 # Maximum number of parallel Docker builds (0 = use CPU core count / 2)
 # Override with --max-processes flag
 max_processes: 4
@@ -165,6 +342,25 @@ use_gar: true
 # Override with --push-to-gar flag
 push_to_gar: true
 
+# ===== LIFECYCLE HOOKS =====
+# Shell commands run around each service build, from the service directory.
+# Events: pre_build, post_build, post_push, on_failure
+# Each hook receives DOCKERZ_SERVICE, DOCKERZ_SERVICE_PATH, DOCKERZ_IMAGE, DOCKERZ_IMAGES,
+# DOCKERZ_TAG, DOCKERZ_DIGEST, DOCKERZ_STATUS and DOCKERZ_ERROR env vars, plus the same data as JSON on stdin.
+# policy: fail (default, a failing hook fails the build) or ignore (log and continue)
+# Services can define their own hooks, which run after the global ones.
+hooks:
+  # pre_build:
+  #   - name: codegen
+  #     command: make generate
+  #     timeout: 2m
+  # post_push:
+  #   - name: scan
+  #     command: trivy image "$DOCKERZ_IMAGE"
+  #     policy: ignore
+  # on_failure:
+  #   - command: ./notify.sh
+
 # ===== SMART BUILD FEATURES (v2.0) =====
 # Advanced features for optimizing CI/CD pipelines - disabled by default
 
@@ -202,6 +398,70 @@ input_changed_services:
 # Useful for subsequent CI/CD steps or debugging
 output_changed_services:
 
+# ===== BUILD METRICS =====
+# Export per-service build/push durations, queue wait, push retries, layer cache hits,
+# image sizes and skip reasons (use --metrics-file / --pushgateway to override)
+# metrics:
+#   textfile: dockerz.prom                          # OpenMetrics file for node_exporter's textfile collector
+#   pushgateway: https://pushgateway.example.com    # Pushgateway-compatible endpoint
+#   job: dockerz
+#   labels:
+#     pipeline: main
+
+# ===== BUILD TRACING =====
+# OpenTelemetry spans for config loading, discovery, git analysis, builds and pushes,
+# joined to the CI pipeline trace when TRACEPARENT is set (use --trace-endpoint / --trace-file to override)
+# tracing:
+#   endpoint: http://otel-collector:4318            # OTLP/HTTP collector (default: OTEL_EXPORTER_OTLP_ENDPOINT)
+#   file: dockerz-trace.json                         # OTLP JSON file for offline viewing
+#   headers:
+#     Authorization: Bearer ${OTEL_TOKEN}
+
+# ===== CI INTEGRATION =====
+# GitHub Actions: GITHUB_OUTPUT values, a GITHUB_STEP_SUMMARY table and ::error annotations
+# GitLab CI: a dotenv report (declare it under artifacts:reports:dotenv) and collapsible log sections
+# ci:
+#   provider: auto          # auto (detect from the environment), github, gitlab or none (--ci)
+#   dotenv: dockerz.env     # GitLab dotenv report file
+
+# ===== DOCKERFILE LINT =====
+# 'dockerz lint' checks every service's Dockerfile; with a gate, 'dockerz build' does too
+# Rules: latest-tag, apt-cleanup, missing-user, add-url, missing-healthcheck
+# "# dockerz:ignore [rule,...]" above an instruction suppresses findings on it
+# lint:
+#   gate: error             # error, warning, info or off (default): stop builds at this severity (--lint-gate)
+#   ignore: [missing-healthcheck]
+#   severity:
+#     latest-tag: error     # error, warning, info or off
+
+# ===== BASE IMAGE TRACKING =====
+# Smart builds rebuild services whose base image digest moved since their last build,
+# e.g. a patched python:3.11-slim; 'dockerz outdated' lists them
+# Digests are resolved through the registry API and recorded in .dockerz/base-images/
+# base_images:
+#   track: true             # also --track-base-images
+#   ignore: [gcr.io/my-project/*]
+#   insecure: false         # plain HTTP registry; localhost always is (--base-images-insecure)
+
+# ===== LOCK FILE =====
+# dockerz.lock records per service the base image digests, content hash, build args,
+# tags and pushed digest; commit it with the release
+# 'dockerz build --locked' fails when anything resolves differently, 'dockerz verify'
+# checks that the registry still serves the locked digests
+# lock:
+#   enabled: true           # update the lock file after successful builds (--lock)
+#   file: dockerz.lock
+
+# ===== IMAGE SIGNING =====
+# Signs pushed image digests with a local key (cosign-compatible, stored as .sig/.att tags
+# next to the image); 'dockerz generate-key-pair' creates dockerz.key and dockerz.pub
+# Check with 'dockerz verify-signature --key dockerz.pub' or 'cosign verify --key dockerz.pub'
+# signing:
+#   key: dockerz.key        # also --sign-key; keep it out of the repository
+#   provenance: true        # SLSA provenance: git commit, build.yaml digest, build args (--provenance)
+#   builder_id: https://github.com/my-org/my-repo/.github/workflows/build.yml
+#   insecure: false         # plain HTTP registry; localhost always is (--sign-insecure)
+
 # ===== SERVICE DEFINITIONS =====
 # Explicitly define services to build (leave empty for auto-discovery)
 # Auto-discovery scans services_dir for directories containing Dockerfiles
@@ -216,9 +476,20 @@ output_changed_services:
 # This prevents conflicts in CI/CD where you can't modify this config file
 #
 # Each service can have:
-# - name: Path to service directory (relative to project root)
+# - name: Path to service directory, or to one of its Dockerfiles (relative to project root)
 # - image_name: Custom Docker image name (optional, defaults to service name)
 # - tag: Service-specific tag (optional, overrides global_tag)
+# - tags: Service-specific tag templates (optional, replaces the global tags list)
+# - hooks: Service-specific lifecycle hooks (optional, run after the global hooks)
+# - context: Build context relative to the service directory (optional)
+# - build_args: Docker build arguments (optional)
+# - depends_on: Services built first; their rebuilds also rebuild this service (optional)
+# - watch: Extra paths whose changes trigger a rebuild, relative to the service directory (optional)
+# - platforms: Target platforms (optional, replaces the global platforms list)
+# - lint_ignore: Dockerfile lint rules not reported for this service (optional)
+#
+# Services can also describe themselves in a dockerz.service.yaml next to their Dockerfile
+# (image_name, tag, tags, context, build_args, depends_on, watch, platforms). Entries here override it.
 
 services:
   # Examples (uncomment and modify as needed):
@@ -230,8 +501,35 @@ services:
   # - name: services/web-frontend
   #   image_name: my-web-app        # Optional custom image name
 
+  # - name: services/api/Dockerfile.worker
+  #   image_name: my-api-worker     # Settings for a single Dockerfile variant
+
   # - name: microservices/user-service
 
+# ===== PROFILES, INCLUDES AND ENVIRONMENT =====
+# Precedence (highest first): CLI flags > DOCKERZ_* env vars > selected profile > this file > includes
+#
+# include: pull shared settings from other files (paths relative to this file)
+#   include: ../shared/dockerz-base.yaml
+#
+# ${VAR} references are expanded from the environment in every string value:
+#   ${VAR:-default} uses default when VAR is unset or empty, ${VAR-default} only when unset,
+#   $${ produces a literal ${
+#   project: ${GCP_PROJECT:-my-gcp-project}
+#
+# Any scalar key can be overridden with DOCKERZ_<KEY>, nested keys joined by "_":
+#   DOCKERZ_MAX_PROCESSES=8 DOCKERZ_VERSIONING_ENABLED=true DOCKERZ_TAGS=latest,stable
+#
+# profiles: named overlays selected with --profile <name> or DOCKERZ_PROFILE
+profiles:
+  # ci:
+  #   smart: true
+  #   git_track: true
+  #   cache: true
+  # prod:
+  #   project: my-prod-project
+  #   push_to_gar: true
+
 # ===== USAGE EXAMPLES =====
 #
 # Basic build (auto-discover all services):
@@ -248,6 +546,9 @@ services:
 #
 # Force rebuild everything:
 #   dockerz build --force
+#
+# Build with the ci profile:
+#   dockerz build --profile ci
 `
 
 	if err := os.WriteFile(filename, []byte(sampleYAML), 0644); err != nil {
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of environment variables that override config keys
+const EnvPrefix = "DOCKERZ_"
+
+// ProfileEnv selects a profile when --profile is not given
+const ProfileEnv = "DOCKERZ_PROFILE"
+
+// interpolation matches $${...} escapes and ${VAR}, ${VAR:-default} and ${VAR-default} references
+var interpolation = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?-)([^}]*))?\}`)
+
+// readConfigFile reads a YAML file and merges its includes underneath it.
+// Included paths are relative to the including file; later includes override earlier ones.
+func readConfigFile(path string, seen map[string]bool) (map[string]interface{}, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	if seen[absPath] {
+		return nil, fmt.Errorf("include cycle detected at %s", path)
+	}
+	seen[absPath] = true
+	defer delete(seen, absPath)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	raw := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if raw == nil {
+		raw = make(map[string]interface{})
+	}
+
+	includes, err := stringList(raw["include"])
+	if err != nil {
+		return nil, fmt.Errorf("%s: include: %w", path, err)
+	}
+	delete(raw, "include")
+
+	merged := make(map[string]interface{})
+	for _, include := range includes {
+		includePath := Interpolate(include)
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(path), includePath)
+		}
+		included, err := readConfigFile(includePath, seen)
+		if err != nil {
+			return nil, err
+		}
+		mergeMaps(merged, included)
+	}
+	mergeMaps(merged, raw)
+
+	return merged, nil
+}
+
+// stringList accepts a single string or a list of strings
+func stringList(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string, got %v", item)
+			}
+			list = append(list, str)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("expected a string or list of strings")
+	}
+}
+
+// mergeMaps deep-merges src into dst; maps merge recursively, everything else is replaced
+func mergeMaps(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeMaps(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// Interpolate expands ${VAR}, ${VAR:-default} (default when unset or empty) and
+// ${VAR-default} (default when unset); $${ produces a literal ${
+func Interpolate(value string) string {
+	return interpolation.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		parts := interpolation.FindStringSubmatch(match)
+		name, operator, fallback := parts[1], parts[2], parts[3]
+
+		envValue, set := os.LookupEnv(name)
+		switch operator {
+		case ":-":
+			if envValue == "" {
+				return fallback
+			}
+		case "-":
+			if !set {
+				return fallback
+			}
+		}
+		return envValue
+	})
+}
+
+// interpolateValues expands variables in every string of a decoded YAML tree
+func interpolateValues(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return Interpolate(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = interpolateValues(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = interpolateValues(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// applyProfile overlays the selected profile onto the base configuration
+func applyProfile(raw map[string]interface{}, profile string) error {
+	profiles, _ := raw["profiles"].(map[string]interface{})
+	delete(raw, "profiles")
+
+	if profile == "" {
+		return nil
+	}
+
+	overlay, exists := profiles[profile]
+	if !exists {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("profile '%s' not found (available: %s)", profile, strings.Join(names, ", "))
+	}
+
+	overlayMap, ok := overlay.(map[string]interface{})
+	if !ok {
+		if overlay == nil {
+			return nil
+		}
+		return fmt.Errorf("profile '%s' must be a mapping", profile)
+	}
+	mergeMaps(raw, overlayMap)
+	return nil
+}
+
+// envKeys returns every scalar or string-list config key, e.g. "max_processes" or "versioning.enabled"
+func envKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + tag
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			keys = append(keys, envKeys(field.Type, key+".")...)
+		case reflect.Slice:
+			// Lists of structs (services, hooks) cannot be expressed as a single variable
+			if field.Type.Elem().Kind() == reflect.String {
+				keys = append(keys, key)
+			}
+		case reflect.Map:
+			continue
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// EnvVarName returns the environment variable that overrides a config key
+func EnvVarName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// bindEnvOverrides binds DOCKERZ_* variables that are set to their config keys
+func bindEnvOverrides(v *viper.Viper) []string {
+	var applied []string
+	for _, key := range envKeys(reflect.TypeOf(Config{}), "") {
+		name := EnvVarName(key)
+		if _, set := os.LookupEnv(name); set {
+			v.BindEnv(key, name)
+			applied = append(applied, name)
+		}
+	}
+	return applied
+}
+
+// decodeServices decodes the services list from the raw YAML tree, preserving key case
+func decodeServices(value interface{}) ([]Service, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var services []Service
+	if err := yaml.Unmarshal(data, &services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
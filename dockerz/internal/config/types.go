@@ -9,15 +9,140 @@ type Service struct {
 	Name      string `yaml:"name" mapstructure:"name"`
 	ImageName string `yaml:"image_name,omitempty" mapstructure:"image_name"`
 	Tag       string `yaml:"tag,omitempty" mapstructure:"tag"`
+	Tags      []string `yaml:"tags,omitempty" mapstructure:"tags"`
+	Hooks     HooksConfig `yaml:"hooks,omitempty" mapstructure:"hooks"`
+
+	// Context and Watch paths are relative to the service directory
+	Context   string            `yaml:"context,omitempty" mapstructure:"context"`
+	BuildArgs map[string]string `yaml:"build_args,omitempty" mapstructure:"build_args"`
+	DependsOn []string          `yaml:"depends_on,omitempty" mapstructure:"depends_on"`
+	Watch     []string          `yaml:"watch,omitempty" mapstructure:"watch"`
+	Platforms []string          `yaml:"platforms,omitempty" mapstructure:"platforms"`
+	// LintIgnore lists Dockerfile lint rules not reported for this service
+	LintIgnore []string `yaml:"lint_ignore,omitempty" mapstructure:"lint_ignore"`
+}
+
+// Hook represents a command run at a point in the build lifecycle
+type Hook struct {
+	Name    string        `yaml:"name,omitempty" mapstructure:"name"`
+	Command string        `yaml:"command" mapstructure:"command"`
+	Timeout time.Duration `yaml:"timeout,omitempty" mapstructure:"timeout"`
+	// Policy is "fail" (a failing hook fails the build) or "ignore" (log and continue)
+	Policy string `yaml:"policy,omitempty" mapstructure:"policy"`
+}
+
+// HooksConfig represents the hooks for each lifecycle event
+type HooksConfig struct {
+	PreBuild  []Hook `yaml:"pre_build,omitempty" mapstructure:"pre_build"`
+	PostBuild []Hook `yaml:"post_build,omitempty" mapstructure:"post_build"`
+	PostPush  []Hook `yaml:"post_push,omitempty" mapstructure:"post_push"`
+	OnFailure []Hook `yaml:"on_failure,omitempty" mapstructure:"on_failure"`
+}
+
+// DiscoveryConfig controls which paths service discovery scans, which files it treats as
+// Dockerfiles and how images are named. Dockerfile patterns are matched against file names (e.g. "Dockerfile.*", "*.Dockerfile").
+// Include and Exclude are path globs relative to the project root.
+type DiscoveryConfig struct {
+	Dockerfiles []string     `yaml:"dockerfiles,omitempty" mapstructure:"dockerfiles"`
+	Ignore      []string     `yaml:"ignore,omitempty" mapstructure:"ignore"`
+	Include     []string     `yaml:"include,omitempty" mapstructure:"include"`
+	Exclude     []string     `yaml:"exclude,omitempty" mapstructure:"exclude"`
+	Gitignore   bool         `yaml:"gitignore,omitempty" mapstructure:"gitignore"`
+	Naming      NamingConfig `yaml:"naming,omitempty" mapstructure:"naming"`
+}
+
+// NamingConfig controls how image names are derived from service paths.
+// Strategy is basename (default), path or template.
+type NamingConfig struct {
+	Strategy   string `yaml:"strategy,omitempty" mapstructure:"strategy"`
+	Separator  string `yaml:"separator,omitempty" mapstructure:"separator"`
+	Template   string `yaml:"template,omitempty" mapstructure:"template"`
+	TrimPrefix string `yaml:"trim_prefix,omitempty" mapstructure:"trim_prefix"`
+}
+
+// VersioningConfig represents per-service semantic versioning configuration
+type VersioningConfig struct {
+	Enabled       bool   `yaml:"enabled" mapstructure:"enabled"`
+	TagPrefix     string `yaml:"tag_prefix,omitempty" mapstructure:"tag_prefix"`
+	CreateGitTags bool   `yaml:"create_git_tags,omitempty" mapstructure:"create_git_tags"`
+	PushGitTags   bool   `yaml:"push_git_tags,omitempty" mapstructure:"push_git_tags"`
+}
+
+// MetricsConfig represents build metrics export configuration
+type MetricsConfig struct {
+	Textfile    string            `yaml:"textfile,omitempty" mapstructure:"textfile"`
+	Pushgateway string            `yaml:"pushgateway,omitempty" mapstructure:"pushgateway"`
+	Job         string            `yaml:"job,omitempty" mapstructure:"job"`
+	Labels      map[string]string `yaml:"labels,omitempty" mapstructure:"labels"`
+}
+
+// TracingConfig represents OpenTelemetry trace export configuration
+type TracingConfig struct {
+	Endpoint string            `yaml:"endpoint,omitempty" mapstructure:"endpoint"`
+	File     string            `yaml:"file,omitempty" mapstructure:"file"`
+	Headers  map[string]string `yaml:"headers,omitempty" mapstructure:"headers"`
+}
+
+// CIConfig represents CI system integration configuration
+type CIConfig struct {
+	// Provider is auto (detect from the environment), github, gitlab or none
+	Provider string `yaml:"provider,omitempty" mapstructure:"provider"`
+	Dotenv   string `yaml:"dotenv,omitempty" mapstructure:"dotenv"`
+}
+
+// LintConfig represents Dockerfile lint configuration
+type LintConfig struct {
+	// Gate is the lowest severity that stops 'dockerz build' before building: error, warning, info or off (default)
+	Gate string `yaml:"gate,omitempty" mapstructure:"gate"`
+	// Ignore lists rules not reported for any service
+	Ignore []string `yaml:"ignore,omitempty" mapstructure:"ignore"`
+	// Severity overrides rule severities; off disables a rule
+	Severity map[string]string `yaml:"severity,omitempty" mapstructure:"severity"`
+}
+
+// BaseImagesConfig represents base image digest tracking configuration
+type BaseImagesConfig struct {
+	// Track resolves base image digests on every build; a moved digest rebuilds the service
+	Track bool `yaml:"track,omitempty" mapstructure:"track"`
+	// Ignore lists base image patterns never tracked, e.g. gcr.io/my-project/*
+	Ignore []string `yaml:"ignore,omitempty" mapstructure:"ignore"`
+	// Insecure resolves base images over plain HTTP; localhost registries always are
+	Insecure bool `yaml:"insecure,omitempty" mapstructure:"insecure"`
+}
+
+// LockConfig represents the reproducibility lock file configuration
+type LockConfig struct {
+	// Enabled updates the lock file with every successful build
+	Enabled bool `yaml:"enabled,omitempty" mapstructure:"enabled"`
+	// File is the lock file path (default dockerz.lock)
+	File string `yaml:"file,omitempty" mapstructure:"file"`
+}
+
+// SigningConfig represents image signing and provenance configuration
+type SigningConfig struct {
+	// Key is the PEM private key pushed image digests are signed with; empty disables signing
+	Key string `yaml:"key,omitempty" mapstructure:"key"`
+	// Provenance also attaches a signed SLSA provenance attestation
+	Provenance bool `yaml:"provenance,omitempty" mapstructure:"provenance"`
+	// BuilderID identifies the builder in provenance (default urn:dockerz:builder:<hostname>)
+	BuilderID string `yaml:"builder_id,omitempty" mapstructure:"builder_id"`
+	// Insecure reaches the registry over plain HTTP; localhost always is
+	Insecure bool `yaml:"insecure,omitempty" mapstructure:"insecure"`
 }
 
 // Config represents the main configuration structure
 type Config struct {
 	ServicesDir  []string  `yaml:"services_dir" mapstructure:"services_dir"`
+	Discovery    DiscoveryConfig `yaml:"discovery,omitempty" mapstructure:"discovery"`
+	ComposeFiles []string  `yaml:"compose_files,omitempty" mapstructure:"compose_files"`
 	Project      string    `yaml:"project" mapstructure:"project"`
 	GAR          string    `yaml:"gar" mapstructure:"gar"`
 	Region       string    `yaml:"region" mapstructure:"region"`
 	GlobalTag    string    `yaml:"global_tag,omitempty" mapstructure:"global_tag"`
+	Tags         []string  `yaml:"tags,omitempty" mapstructure:"tags"`
+	Platforms    []string  `yaml:"platforms,omitempty" mapstructure:"platforms"`
+	Versioning   VersioningConfig `yaml:"versioning,omitempty" mapstructure:"versioning"`
+	Hooks        HooksConfig `yaml:"hooks,omitempty" mapstructure:"hooks"`
 	MaxProcesses int       `yaml:"max_processes,omitempty" mapstructure:"max_processes"`
 	
 	// Resource-aware scheduling configuration
@@ -40,6 +165,27 @@ type Config struct {
 	
 	// BuildKit configuration
 	EnableBuildKit bool `yaml:"enable_buildkit,omitempty" mapstructure:"enable_buildkit"`
+
+	// Build metrics export
+	Metrics MetricsConfig `yaml:"metrics,omitempty" mapstructure:"metrics"`
+
+	// Build tracing export
+	Tracing TracingConfig `yaml:"tracing,omitempty" mapstructure:"tracing"`
+
+	// CI system integration outputs
+	CI CIConfig `yaml:"ci,omitempty" mapstructure:"ci"`
+
+	// Dockerfile linting
+	Lint LintConfig `yaml:"lint,omitempty" mapstructure:"lint"`
+
+	// Base image digest tracking
+	BaseImages BaseImagesConfig `yaml:"base_images,omitempty" mapstructure:"base_images"`
+
+	// Reproducibility lock file
+	Lock LockConfig `yaml:"lock,omitempty" mapstructure:"lock"`
+
+	// Image signing and provenance
+	Signing SigningConfig `yaml:"signing,omitempty" mapstructure:"signing"`
 }
 
 // BuildResult represents the result of a build operation
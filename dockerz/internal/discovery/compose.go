@@ -0,0 +1,215 @@
+package discovery
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// composeFile is the part of a docker-compose file dockerz reads
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+// composeService is a compose service; only services with a build section are imported
+type composeService struct {
+	Image string        `yaml:"image"`
+	Build *composeBuild `yaml:"build"`
+}
+
+// composeBuild is a compose build section, written either as a context path or a mapping
+type composeBuild struct {
+	Context    string
+	Dockerfile string
+	Args       map[string]string
+	Target     string
+	Platforms  []string
+}
+
+// UnmarshalYAML accepts both "build: ./dir" and the long form; args may be a mapping or a KEY=value list
+func (b *composeBuild) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		b.Context = node.Value
+		return nil
+	}
+
+	var long struct {
+		Context    string    `yaml:"context"`
+		Dockerfile string    `yaml:"dockerfile"`
+		Args       yaml.Node `yaml:"args"`
+		Target     string    `yaml:"target"`
+		Platforms  []string  `yaml:"platforms"`
+	}
+	if err := node.Decode(&long); err != nil {
+		return err
+	}
+	b.Context, b.Dockerfile, b.Target, b.Platforms = long.Context, long.Dockerfile, long.Target, long.Platforms
+
+	switch long.Args.Kind {
+	case 0:
+	case yaml.SequenceNode:
+		var list []string
+		if err := long.Args.Decode(&list); err != nil {
+			return err
+		}
+		b.Args = make(map[string]string, len(list))
+		for _, entry := range list {
+			key, value, found := strings.Cut(entry, "=")
+			if !found {
+				// A bare key takes its value from the environment, as in compose
+				value = os.Getenv(key)
+			}
+			b.Args[key] = value
+		}
+	default:
+		var args map[string]*string
+		if err := long.Args.Decode(&args); err != nil {
+			return err
+		}
+		b.Args = make(map[string]string, len(args))
+		for key, value := range args {
+			if value == nil {
+				b.Args[key] = os.Getenv(key)
+			} else {
+				b.Args[key] = *value
+			}
+		}
+	}
+	return nil
+}
+
+// discoverFromCompose imports the services with a build section from docker-compose files.
+// Paths are relative to each compose file; ${VAR} references are interpolated.
+func discoverFromCompose(files []string, defaultTag string, filter *PathFilter) ([]DiscoveredService, []error) {
+	var services []DiscoveredService
+	var errors []error
+
+	for _, file := range files {
+		found, fileErrors := loadComposeServices(file, defaultTag, filter)
+		services = append(services, found...)
+		errors = append(errors, fileErrors...)
+	}
+	return services, errors
+}
+
+// LoadComposeFile parses a docker-compose file and returns the services it builds
+func LoadComposeFile(file, defaultTag string) ([]DiscoveredService, []error) {
+	return loadComposeServices(file, defaultTag, nil)
+}
+
+// loadComposeServices reads a single compose file
+func loadComposeServices(file, defaultTag string, filter *PathFilter) ([]DiscoveredService, []error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to read compose file %s: %w", file, err)}
+	}
+	var compose composeFile
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return nil, []error{fmt.Errorf("failed to parse compose file %s: %w", file, err)}
+	}
+
+	names := make([]string, 0, len(compose.Services))
+	for name := range compose.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var services []DiscoveredService
+	var errors []error
+	baseDir := filepath.Dir(file)
+	for _, name := range names {
+		composeSvc := compose.Services[name]
+		if composeSvc.Build == nil {
+			continue
+		}
+
+		discovered, err := composeToService(name, composeSvc, baseDir, defaultTag)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("compose file %s: service %s: %w", file, name, err))
+			if filter != nil {
+				filter.Record(file+"#"+name, false, err.Error())
+			}
+			continue
+		}
+		discovered.Compose = file
+		if filter != nil {
+			filter.Record(discovered.Key(), true, "compose file "+file)
+		}
+		services = append(services, discovered)
+	}
+	return services, errors
+}
+
+// composeToService maps a compose service onto a discovered service
+func composeToService(name string, composeSvc composeService, baseDir, defaultTag string) (DiscoveredService, error) {
+	build := composeSvc.Build
+	contextPath := config.Interpolate(build.Context)
+	if contextPath == "" {
+		contextPath = "."
+	}
+	if strings.Contains(contextPath, "://") || strings.HasPrefix(contextPath, "git@") {
+		return DiscoveredService{}, fmt.Errorf("remote build context %s is not supported", contextPath)
+	}
+	if !filepath.IsAbs(contextPath) {
+		contextPath = filepath.Join(baseDir, contextPath)
+	}
+	contextPath = filepath.Clean(contextPath)
+
+	dockerfile := config.Interpolate(build.Dockerfile)
+	if dockerfile == "" {
+		dockerfile = DefaultDockerfile
+	}
+	if _, err := os.Stat(filepath.Join(contextPath, dockerfile)); err != nil {
+		return DiscoveredService{}, fmt.Errorf("no Dockerfile found at %s", filepath.Join(contextPath, dockerfile))
+	}
+
+	service := DiscoveredService{
+		Path:       contextPath,
+		Dockerfile: filepath.Clean(dockerfile),
+		Name:       name,
+		ImageName:  NormalizeImageName(name),
+		Tag:        defaultTag,
+		Target:     config.Interpolate(build.Target),
+		Platforms:  build.Platforms,
+	}
+
+	if image := config.Interpolate(composeSvc.Image); image != "" {
+		repository, tag := splitComposeImage(image)
+		service.ImageName = NormalizeImagePath(repository)
+		if tag != "" {
+			service.Tag = tag
+		}
+	}
+	if err := ValidateImageName(service.ImageName); err != nil {
+		return DiscoveredService{}, err
+	}
+
+	if len(build.Args) > 0 {
+		service.BuildArgs = make(map[string]string, len(build.Args))
+		for key, value := range build.Args {
+			service.BuildArgs[key] = config.Interpolate(value)
+		}
+	}
+	return service, nil
+}
+
+// splitComposeImage splits a compose image into its repository, without registry host, and tag
+func splitComposeImage(image string) (string, string) {
+	image, _, _ = strings.Cut(image, "@")
+
+	tag := ""
+	if idx := strings.LastIndex(image, ":"); idx != -1 && idx > strings.LastIndex(image, "/") {
+		image, tag = image[:idx], image[idx+1:]
+	}
+
+	// The registry comes from dockerz's own settings
+	if host, rest, found := strings.Cut(image, "/"); found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		image = rest
+	}
+	return image, tag
+}
@@ -24,12 +24,14 @@ func NormalizeImageName(serviceName string) string {
 	reg := regexp.MustCompile(`[^a-z0-9.-]`)
 	name = reg.ReplaceAllString(name, "-")
 
-	// Remove multiple consecutive hyphens
+	// Remove multiple consecutive hyphens, and mixed or repeated separators such as "-." or ".."
 	reg = regexp.MustCompile(`-+`)
 	name = reg.ReplaceAllString(name, "-")
+	reg = regexp.MustCompile(`[.-]{2,}`)
+	name = reg.ReplaceAllString(name, "-")
 
-	// Remove leading/trailing hyphens
-	name = strings.Trim(name, "-")
+	// Remove leading/trailing hyphens and periods (.docker -> docker)
+	name = strings.Trim(name, "-.")
 
 	// Ensure it starts with alphanumeric
 	if name == "" || !regexp.MustCompile(`^[a-z0-9]`).MatchString(name) {
@@ -39,8 +41,12 @@ func NormalizeImageName(serviceName string) string {
 	return name
 }
 
-// ValidateDockerfile checks if a Dockerfile exists in the service directory
+// ValidateDockerfile checks if a Dockerfile exists in the service directory, or that servicePath
+// is itself a Dockerfile
 func ValidateDockerfile(servicePath string) error {
+	if info, err := os.Stat(servicePath); err == nil && !info.IsDir() {
+		return nil
+	}
 	dockerfilePath := filepath.Join(servicePath, "Dockerfile")
 	if _, err := os.Stat(dockerfilePath); os.IsNotExist(err) {
 		return fmt.Errorf("no Dockerfile found in %s", servicePath)
@@ -48,15 +54,14 @@ func ValidateDockerfile(servicePath string) error {
 	return nil
 }
 
+// ImageNamePattern matches Docker repository names: "/"-separated components of lowercase
+// letters and digits joined by a single '.', '_', "__" or a run of '-'
+var ImageNamePattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+
 // ValidateImageName validates that the image name is Docker-compatible
 func ValidateImageName(imageName string) error {
-	// Docker image names must be lowercase, alphanumeric, with hyphens, underscores, or periods
-	matched, err := regexp.MatchString(`^[a-z0-9][a-z0-9._-]*$`, imageName)
-	if err != nil {
-		return fmt.Errorf("error validating image name: %w", err)
-	}
-	if !matched {
-		return fmt.Errorf("invalid image name '%s': must be lowercase and contain only alphanumeric, hyphens, underscores, or periods", imageName)
+	if !ImageNamePattern.MatchString(imageName) {
+		return fmt.Errorf("invalid image name '%s': use lowercase letters, digits and single '.', '_' or '-' separators, with slashes between components", imageName)
 	}
 	return nil
 }
@@ -100,9 +105,10 @@ func isExcludedDirectory(dirName string) bool {
 }
 
 // discoverExplicitServices discovers services explicitly listed in YAML configuration
-func discoverExplicitServices(cfg *config.Config, defaultTag string) ([]DiscoveredService, []error) {
+func discoverExplicitServices(cfg *config.Config, defaultTag string, filter *PathFilter) ([]DiscoveredService, []error) {
 	var services []DiscoveredService
 	var errors []error
+	matcher := NewDockerfileMatcher(cfg)
 
 	for _, service := range cfg.Services {
 		if service.Name == "" {
@@ -110,42 +116,55 @@ func discoverExplicitServices(cfg *config.Config, defaultTag string) ([]Discover
 			continue
 		}
 
-		if err := ValidateDockerfile(service.Name); err != nil {
+		servicePath, dockerfiles, err := matcher.Resolve(service.Name)
+		if err != nil {
+			filter.Record(service.Name, false, err.Error())
 			errors = append(errors, err)
 			continue
 		}
-
-		imageName := service.ImageName
-		if imageName == "" {
-			imageName = filepath.Base(service.Name)
+		if servicePath == filepath.Clean(service.Name) {
+			// Keep the path as written so build.yaml lookups match
+			servicePath = service.Name
 		}
-		// Normalize to kebab-case for Docker/GAR compatibility
-		imageName = NormalizeImageName(imageName)
 
-		if err := ValidateImageName(imageName); err != nil {
-			errors = append(errors, err)
-			continue
-		}
+		for _, dockerfile := range dockerfiles {
+			// A Dockerfile listed as its own entry is discovered from that entry
+			key := ServiceKey(servicePath, dockerfile)
+			if _, listed := cfg.ServiceConfig(key); listed && filepath.Clean(key) != filepath.Clean(service.Name) {
+				continue
+			}
 
-		tag := service.Tag
-		if tag == "" {
-			tag = defaultTag
-		}
+			discovered, err := newService(servicePath, dockerfile, defaultTag)
+			if err != nil {
+				errors = append(errors, err)
+				continue
+			}
 
-		discovered := DiscoveredService{
-			Path:      service.Name,
-			Name:      filepath.Base(service.Name),
-			ImageName: imageName,
-			Tag:       tag,
+			// Image name and tag belong to the Dockerfile the entry names
+			if filepath.Clean(discovered.Key()) == filepath.Clean(service.Name) {
+				if service.ImageName != "" {
+					// Normalize to kebab-case for Docker/GAR compatibility
+					imageName := NormalizeImagePath(service.ImageName)
+					if err := ValidateImageName(imageName); err != nil {
+						errors = append(errors, err)
+						continue
+					}
+					discovered.ImageName = imageName
+				}
+				if service.Tag != "" {
+					discovered.Tag = service.Tag
+				}
+			}
+			filter.Record(discovered.Key(), true, "services entry "+service.Name)
+			services = append(services, discovered)
 		}
-		services = append(services, discovered)
 	}
 
 	return services, errors
 }
 
 // discoverFromDirectories discovers services in specified directories
-func discoverFromDirectories(dirs []string, defaultTag string) ([]DiscoveredService, []error) {
+func discoverFromDirectories(dirs []string, defaultTag string, matcher *DockerfileMatcher, filter *PathFilter) ([]DiscoveredService, []error) {
 	var services []DiscoveredService
 	var errors []error
 
@@ -155,47 +174,9 @@ func discoverFromDirectories(dirs []string, defaultTag string) ([]DiscoveredServ
 			continue
 		}
 
-		err := filepath.Walk(servicesDirPath, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				errors = append(errors, err)
-				return nil
-			}
-
-			if info.IsDir() {
-				isExcluded := isExcludedDirectory(info.Name())
-				// Never exclude the root directory being walked
-				if path == servicesDirPath {
-					isExcluded = false
-				}
-				// Skip excluded directories
-				if isExcluded {
-					return filepath.SkipDir
-				}
-			}
-
-			// Find Dockerfile (must be a file)
-			if !info.IsDir() && info.Name() == "Dockerfile" {
-				servicePath := filepath.Dir(path)
-				serviceName := filepath.Base(servicePath)
-
-				imageName := NormalizeImageName(serviceName)
-				if err := ValidateImageName(imageName); err != nil {
-					errors = append(errors, fmt.Errorf("service %s: %w", servicePath, err))
-					return nil
-				}
-
-				discovered := DiscoveredService{
-					Path:      servicePath,
-					Name:      serviceName,
-					ImageName: imageName,
-					Tag:       defaultTag,
-				}
-				services = append(services, discovered)
-			}
-
-			return nil
-		})
-
+		found, walkErrors, err := walkServices(servicesDirPath, defaultTag, matcher, filter)
+		services = append(services, found...)
+		errors = append(errors, walkErrors...)
 		if err != nil {
 			errors = append(errors, fmt.Errorf("error walking services directory %s: %w", servicesDirPath, err))
 		}
@@ -205,61 +186,66 @@ func discoverFromDirectories(dirs []string, defaultTag string) ([]DiscoveredServ
 }
 
 // autoDiscoverServices performs auto-discovery in project root
-func autoDiscoverServices(defaultTag string) ([]DiscoveredService, []error) {
+func autoDiscoverServices(defaultTag string, matcher *DockerfileMatcher, filter *PathFilter) ([]DiscoveredService, []error) {
+	services, errors, err := walkServices(".", defaultTag, matcher, filter)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("error during auto-discovery in project root: %w", err))
+	}
+	return services, errors
+}
+
+// walkServices finds a service for every Dockerfile below root, skipping the paths the filter rejects
+func walkServices(root string, defaultTag string, matcher *DockerfileMatcher, filter *PathFilter) ([]DiscoveredService, []error, error) {
 	var services []DiscoveredService
 	var errors []error
 
-	projectRoot := "."
-	err := filepath.Walk(projectRoot, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			errors = append(errors, err)
 			return nil
 		}
 
 		if info.IsDir() {
-			isExcluded := isExcludedDirectory(info.Name())
 			// Never exclude the root directory being walked
-			if path == projectRoot {
-				isExcluded = false
+			if path == root {
+				return nil
 			}
 			// Skip excluded directories
-			if isExcluded {
+			if included, _ := filter.Dir(path); !included {
 				return filepath.SkipDir
 			}
+			return nil
 		}
 
-		// Find Dockerfile (must be a file)
-		if !info.IsDir() && info.Name() == "Dockerfile" {
-			servicePath := filepath.Dir(path)
-			serviceName := filepath.Base(servicePath)
-
-			imageName := NormalizeImageName(serviceName)
-			if err := ValidateImageName(imageName); err != nil {
-				errors = append(errors, fmt.Errorf("service %s: %w", servicePath, err))
-				return nil
-			}
-
-			discovered := DiscoveredService{
-				Path:      servicePath,
-				Name:      serviceName,
-				ImageName: imageName,
-				Tag:       defaultTag,
+		// Find Dockerfiles (must be files); each one is a service
+		isDockerfile, rule := matcher.Rule(info.Name())
+		if rule == "" {
+			return nil
+		}
+		if isDockerfile {
+			if included, fileRule := filter.File(path); !included {
+				isDockerfile, rule = false, fileRule
 			}
-			services = append(services, discovered)
+		}
+		filter.Record(path, isDockerfile, rule)
+		if !isDockerfile {
+			return nil
 		}
 
+		discovered, err := newService(filepath.Dir(path), info.Name(), defaultTag)
+		if err != nil {
+			errors = append(errors, err)
+			return nil
+		}
+		services = append(services, discovered)
 		return nil
 	})
 
-	if err != nil {
-		errors = append(errors, fmt.Errorf("error during auto-discovery in project root: %w", err))
-	}
-
-	return services, errors
+	return services, errors, err
 }
 
 // discoverFromInputFile discovers services listed in an input file with enhanced logging
-func discoverFromInputFile(inputFilePath string, defaultTag string) ([]DiscoveredService, []error) {
+func discoverFromInputFile(inputFilePath string, defaultTag string, matcher *DockerfileMatcher, filter *PathFilter) ([]DiscoveredService, []error) {
 	var services []DiscoveredService
 	var errors []error
 
@@ -298,28 +284,32 @@ func discoverFromInputFile(inputFilePath string, defaultTag string) ([]Discovere
 
 		log.Printf("INFO: Processing service from input file: %s", serviceName)
 
-		// Validate that the service exists and has a Dockerfile
-		if err := ValidateDockerfile(serviceName); err != nil {
+		// Validate that the service exists and has a Dockerfile; a directory builds all of its Dockerfiles.
+		// Entries may name a build target as path#target.
+		entry, target, _ := strings.Cut(serviceName, "#")
+		servicePath, dockerfiles, err := matcher.Resolve(entry)
+		if err != nil {
 			log.Printf("WARNING: Service '%s' from input file is invalid: %v", serviceName, err)
+			filter.Record(serviceName, false, err.Error())
 			errors = append(errors, fmt.Errorf("service %s from input file: %w", serviceName, err))
 			continue
 		}
-
-		// Create service entry
-		imageName := NormalizeImageName(filepath.Base(serviceName))
-		if err := ValidateImageName(imageName); err != nil {
-			log.Printf("WARNING: Service '%s' has invalid image name: %v", serviceName, err)
-			errors = append(errors, fmt.Errorf("service %s: %w", serviceName, err))
-			continue
+		if servicePath == filepath.Clean(entry) {
+			servicePath = entry
 		}
 
-		discovered := DiscoveredService{
-			Path:      serviceName,
-			Name:      filepath.Base(serviceName),
-			ImageName: imageName,
-			Tag:       defaultTag,
+		// Create service entries
+		for _, dockerfile := range dockerfiles {
+			discovered, err := newService(servicePath, dockerfile, defaultTag)
+			if err != nil {
+				log.Printf("WARNING: Service '%s' has invalid image name: %v", serviceName, err)
+				errors = append(errors, err)
+				continue
+			}
+			discovered.Target = target
+			filter.Record(discovered.Key(), true, "input file "+inputFilePath)
+			services = append(services, discovered)
 		}
-		services = append(services, discovered)
 		log.Printf("INFO: Successfully added service '%s' from input file", serviceName)
 	}
 
@@ -332,14 +322,15 @@ func discoverFromInputFile(inputFilePath string, defaultTag string) ([]Discovere
 	return services, errors
 }
 
-// deduplicateServices removes duplicate services based on their path
+// deduplicateServices removes duplicate services based on their path and Dockerfile
 func deduplicateServices(services []DiscoveredService) []DiscoveredService {
 	seen := make(map[string]bool)
 	var uniqueServices []DiscoveredService
 
 	for _, service := range services {
-		if !seen[service.Path] {
-			seen[service.Path] = true
+		key := filepath.Clean(service.Key())
+		if !seen[key] {
+			seen[key] = true
 			uniqueServices = append(uniqueServices, service)
 		}
 	}
@@ -362,6 +353,9 @@ func DiscoverServices(cfg *config.Config, defaultTag string, inputFilePath ...st
 	hasServicesDirectories := len(cfg.ServicesDir) > 0
 
 	hasInputFile := len(inputFilePath) > 0 && inputFilePath[0] != ""
+	hasComposeFiles := len(cfg.ComposeFiles) > 0
+	matcher := NewDockerfileMatcher(cfg)
+	filter := NewPathFilter(cfg)
 
 	// Count active sources (auto-discovery only when no other sources are configured)
 	numSources := 0
@@ -374,37 +368,48 @@ func DiscoverServices(cfg *config.Config, defaultTag string, inputFilePath ...st
 	if hasInputFile {
 		numSources++
 	}
+	if hasComposeFiles {
+		numSources++
+	}
 
-	log.Printf("DEBUG: Discovery sources - explicit_services: %v, services_dirs: %v (config: %v), input_file: %v, total_sources: %d",
-		hasExplicitServices, hasServicesDirectories, cfg.ServicesDir, hasInputFile, numSources)
+	log.Printf("DEBUG: Discovery sources - explicit_services: %v, services_dirs: %v (config: %v), input_file: %v, compose_files: %v, total_sources: %d",
+		hasExplicitServices, hasServicesDirectories, cfg.ServicesDir, hasInputFile, cfg.ComposeFiles, numSources)
 
 	// UNIFIED DISCOVERY: Use when multiple sources are provided
 	if numSources > 1 {
 		log.Printf("DEBUG: Using UNIFIED discovery (multiple sources)")
-		// 1. Collect explicit services from YAML (if any)
+		// 1. Import services from docker-compose files (if any); they come first so their build
+		// args and targets survive deduplication, and build.yaml entries apply on top of them
+		if hasComposeFiles {
+			services, errors := discoverFromCompose(cfg.ComposeFiles, defaultTag, filter)
+			allServices = append(allServices, services...)
+			allErrors = append(allErrors, errors...)
+		}
+
+		// 2. Collect explicit services from YAML (if any)
 		if hasExplicitServices {
-			services, errors := discoverExplicitServices(cfg, defaultTag)
+			services, errors := discoverExplicitServices(cfg, defaultTag, filter)
 			allServices = append(allServices, services...)
 			allErrors = append(allErrors, errors...)
 		}
 
-		// 2. Collect services from configured directories (if any)
+		// 3. Collect services from configured directories (if any)
 		if hasServicesDirectories {
-			services, errors := discoverFromDirectories(cfg.ServicesDir, defaultTag)
+			services, errors := discoverFromDirectories(cfg.ServicesDir, defaultTag, matcher, filter)
 			allServices = append(allServices, services...)
 			allErrors = append(allErrors, errors...)
 		}
 
-		// 3. Auto-discovery (if no explicit config provided)
-		if !hasExplicitServices && !hasServicesDirectories && !hasInputFile {
-			services, errors := autoDiscoverServices(defaultTag)
+		// 4. Auto-discovery (if no explicit config provided)
+		if !hasExplicitServices && !hasServicesDirectories && !hasInputFile && !hasComposeFiles {
+			services, errors := autoDiscoverServices(defaultTag, matcher, filter)
 			allServices = append(allServices, services...)
 			allErrors = append(allErrors, errors...)
 		}
 
-		// 4. Collect services from input file (if provided)
+		// 5. Collect services from input file (if provided)
 		if hasInputFile {
-			services, errors := discoverFromInputFile(inputFilePath[0], defaultTag)
+			services, errors := discoverFromInputFile(inputFilePath[0], defaultTag, matcher, filter)
 			allServices = append(allServices, services...)
 			allErrors = append(allErrors, errors...)
 		}
@@ -418,35 +423,61 @@ func DiscoverServices(cfg *config.Config, defaultTag string, inputFilePath ...st
 		if hasExplicitServices {
 			// Use explicit services only
 			log.Printf("DEBUG: Using explicit services from YAML")
-			services, errors := discoverExplicitServices(cfg, defaultTag)
+			services, errors := discoverExplicitServices(cfg, defaultTag, filter)
 			allServices = append(allServices, services...)
 			allErrors = append(allErrors, errors...)
 		} else if hasServicesDirectories {
 			// Use services directories only
 			log.Printf("DEBUG: Using services directories")
-			services, errors := discoverFromDirectories(cfg.ServicesDir, defaultTag)
+			services, errors := discoverFromDirectories(cfg.ServicesDir, defaultTag, matcher, filter)
+			allServices = append(allServices, services...)
+			allErrors = append(allErrors, errors...)
+		} else if hasComposeFiles {
+			// Use docker-compose files only
+			log.Printf("DEBUG: Using docker-compose files")
+			services, errors := discoverFromCompose(cfg.ComposeFiles, defaultTag, filter)
 			allServices = append(allServices, services...)
 			allErrors = append(allErrors, errors...)
 		} else if hasInputFile {
 			// Use input file only - with enhanced logging for edge cases
 			log.Printf("DEBUG: Using input file only")
-			services, errors := discoverFromInputFile(inputFilePath[0], defaultTag)
+			services, errors := discoverFromInputFile(inputFilePath[0], defaultTag, matcher, filter)
 			allServices = append(allServices, services...)
 			allErrors = append(allErrors, errors...)
 		} else {
 			// No sources configured - fall back to auto-discovery
 			log.Printf("DEBUG: No sources configured, falling back to auto-discovery")
-			services, errors := autoDiscoverServices(defaultTag)
+			services, errors := autoDiscoverServices(defaultTag, matcher, filter)
 			allServices = append(allServices, services...)
 			allErrors = append(allErrors, errors...)
 		}
 	}
 
+	// Name images with the configured strategy unless build.yaml names them
+	namingErrors, err := applyNaming(cfg, allServices)
+	allErrors = append(allErrors, namingErrors...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge dockerz.service.yaml manifests with build.yaml entries and resolve dependencies
+	settingsErrors, err := applyServiceSettings(cfg, allServices)
+	allErrors = append(allErrors, settingsErrors...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Two services must never push to the same image
+	if err := checkImageCollisions(allServices); err != nil {
+		return nil, err
+	}
+
 	log.Printf("DEBUG: Final service count: %d", len(allServices))
 
 	result := &DiscoveryResult{
-		Services: allServices,
-		Errors:   allErrors,
+		Services:  allServices,
+		Errors:    allErrors,
+		Decisions: filter.Decisions,
 	}
 
 	if len(result.Services) == 0 {
@@ -460,9 +491,39 @@ func DiscoverServices(cfg *config.Config, defaultTag string, inputFilePath ...st
 func WriteChangedServicesFile(services []DiscoveredService, outputFilePath string) error {
 	var lines []string
 	for _, service := range services {
-		lines = append(lines, service.Path)
+		lines = append(lines, service.Key())
 	}
 
 	content := strings.Join(lines, "\n")
 	return os.WriteFile(outputFilePath, []byte(content), 0644)
 }
+
+// SelectListed returns the services named in an input file, in discovery order. Entries match
+// the way they are resolved when the file is read: a service directory selects all of its
+// Dockerfiles, a Dockerfile path selects that one, and either may name a "#target".
+func SelectListed(services []DiscoveredService, inputFilePath string) ([]DiscoveredService, error) {
+	content, err := os.ReadFile(inputFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file %s: %w", inputFilePath, err)
+	}
+
+	var selected []DiscoveredService
+	for _, service := range services {
+		dockerfile := service.Dockerfile
+		if dockerfile == "" {
+			dockerfile = DefaultDockerfile
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			entry, target, _ := strings.Cut(strings.TrimSpace(line), "#")
+			if entry == "" || (target != "" && target != service.Target) {
+				continue
+			}
+			entry = filepath.Clean(entry)
+			if entry == filepath.Clean(service.Path) || entry == filepath.Join(service.Path, dockerfile) {
+				selected = append(selected, service)
+				break
+			}
+		}
+	}
+	return selected, nil
+}
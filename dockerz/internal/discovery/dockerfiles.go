@@ -0,0 +1,167 @@
+package discovery
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/config"
+)
+
+// DefaultDockerfile is the Dockerfile name whose service is named after its directory
+const DefaultDockerfile = "Dockerfile"
+
+// DockerfileMatcher decides which file names are Dockerfiles
+type DockerfileMatcher struct {
+	Patterns []string
+	Ignore   []string
+}
+
+// NewDockerfileMatcher creates a matcher from the discovery settings. Backup and editor files
+// are always ignored, whatever discovery.ignore lists.
+func NewDockerfileMatcher(cfg *config.Config) *DockerfileMatcher {
+	matcher := &DockerfileMatcher{
+		Patterns: cfg.Discovery.Dockerfiles,
+		Ignore:   append(append([]string{}, config.DefaultIgnorePatterns...), cfg.Discovery.Ignore...),
+	}
+	if len(matcher.Patterns) == 0 {
+		matcher.Patterns = config.DefaultDockerfilePatterns
+	}
+	return matcher
+}
+
+// Ignored reports whether a file name matches an ignore pattern
+func (m *DockerfileMatcher) Ignored(name string) bool {
+	return m.ignoreRule(name) != ""
+}
+
+// Match reports whether a file name is a Dockerfile
+func (m *DockerfileMatcher) Match(name string) bool {
+	matched, _ := m.Rule(name)
+	return matched
+}
+
+// Rule reports whether a file name is a Dockerfile and the pattern that decided it
+// ("" for files that match no Dockerfile pattern)
+func (m *DockerfileMatcher) Rule(name string) (bool, string) {
+	for _, pattern := range m.Patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			if rule := m.ignoreRule(name); rule != "" {
+				return false, rule
+			}
+			return true, "discovery.dockerfiles " + pattern
+		}
+	}
+	return false, ""
+}
+
+// ignoreRule returns the ignore pattern matching a file name, if any
+func (m *DockerfileMatcher) ignoreRule(name string) string {
+	for _, pattern := range m.Ignore {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return "discovery.ignore " + pattern
+		}
+	}
+	return ""
+}
+
+// Dockerfiles lists the Dockerfiles directly inside a directory, the default Dockerfile first
+func (m *DockerfileMatcher) Dockerfiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var dockerfiles []string
+	for _, entry := range entries {
+		if !entry.IsDir() && m.Match(entry.Name()) {
+			dockerfiles = append(dockerfiles, entry.Name())
+		}
+	}
+	sort.SliceStable(dockerfiles, func(a, b int) bool {
+		return dockerfiles[a] == DefaultDockerfile && dockerfiles[b] != DefaultDockerfile
+	})
+	return dockerfiles, nil
+}
+
+// ServiceKey identifies a service: its path for the default Dockerfile, otherwise the Dockerfile path
+func ServiceKey(servicePath, dockerfile string) string {
+	if dockerfile == "" || dockerfile == DefaultDockerfile {
+		return servicePath
+	}
+	return filepath.Join(servicePath, dockerfile)
+}
+
+// Key returns the identifier of the service, unique even when a directory holds several Dockerfiles.
+// Services built for a specific target append it as "#target".
+func (s DiscoveredService) Key() string {
+	if s.Target != "" {
+		return ServiceKey(s.Path, s.Dockerfile) + "#" + s.Target
+	}
+	return ServiceKey(s.Path, s.Dockerfile)
+}
+
+// VariantName returns the variant a Dockerfile name describes, e.g. "worker" for
+// Dockerfile.worker or worker.Dockerfile, and "" for a plain Dockerfile
+func VariantName(dockerfile string) string {
+	name := dockerfile
+	lower := strings.ToLower(name)
+	if index := strings.Index(lower, "dockerfile"); index >= 0 {
+		name = name[:index] + name[index+len("dockerfile"):]
+	}
+	return strings.Trim(name, "._- ")
+}
+
+// ServiceName returns the name of the service built from a Dockerfile in a directory:
+// the directory name, suffixed with the variant for Dockerfile variants (e.g. api-worker)
+func ServiceName(servicePath, dockerfile string) string {
+	name := filepath.Base(servicePath)
+	if variant := VariantName(dockerfile); variant != "" && variant != name {
+		name = name + "-" + variant
+	}
+	return name
+}
+
+// newService creates the service built from a Dockerfile in a service directory
+func newService(servicePath, dockerfile, defaultTag string) (DiscoveredService, error) {
+	name := ServiceName(servicePath, dockerfile)
+	imageName := NormalizeImageName(name)
+	if err := ValidateImageName(imageName); err != nil {
+		return DiscoveredService{}, fmt.Errorf("service %s: %w", ServiceKey(servicePath, dockerfile), err)
+	}
+
+	return DiscoveredService{
+		Path:       servicePath,
+		Dockerfile: dockerfile,
+		Name:       name,
+		ImageName:  imageName,
+		Tag:        defaultTag,
+	}, nil
+}
+
+// Resolve returns the directory and Dockerfiles of a service entry. Entries naming a directory
+// build every Dockerfile in it; entries naming a file build just that Dockerfile.
+func (m *DockerfileMatcher) Resolve(entry string) (string, []string, error) {
+	info, err := os.Stat(entry)
+	if err != nil {
+		return "", nil, fmt.Errorf("no Dockerfile found in %s", entry)
+	}
+
+	if !info.IsDir() {
+		if m.Ignored(info.Name()) {
+			return "", nil, fmt.Errorf("%s is an ignored file (see discovery.ignore)", entry)
+		}
+		return filepath.Dir(entry), []string{info.Name()}, nil
+	}
+
+	dockerfiles, err := m.Dockerfiles(entry)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(dockerfiles) == 0 {
+		return "", nil, fmt.Errorf("no Dockerfile found in %s", entry)
+	}
+	return entry, dockerfiles, nil
+}
@@ -0,0 +1,237 @@
+package discovery
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/config"
+)
+
+// PathFilter decides which directories and files discovery scans. Rules apply in order:
+// discovery.exclude, discovery.include, .gitignore (when enabled), then the built-in exclusions.
+type PathFilter struct {
+	Include   []string
+	Exclude   []string
+	Gitignore bool
+
+	// Decisions records the outcome for every path the filter was asked about
+	Decisions []PathDecision
+
+	ignoreFiles map[string][]ignorePattern
+}
+
+// ignorePattern is a single .gitignore line
+type ignorePattern struct {
+	pattern  string
+	source   string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// NewPathFilter creates a filter from the discovery settings
+func NewPathFilter(cfg *config.Config) *PathFilter {
+	return &PathFilter{
+		Include:     cfg.Discovery.Include,
+		Exclude:     cfg.Discovery.Exclude,
+		Gitignore:   cfg.Discovery.Gitignore,
+		ignoreFiles: make(map[string][]ignorePattern),
+	}
+}
+
+// Dir reports whether discovery descends into a directory, and the rule that decided it
+func (f *PathFilter) Dir(dir string) (bool, string) {
+	name := filepath.Base(dir)
+	included, rule := f.check(dir, true, func() string {
+		if strings.HasPrefix(name, ".") {
+			return "hidden directory"
+		}
+		if isExcludedDirectory(name) {
+			return "built-in exclusion " + name
+		}
+		return ""
+	})
+	f.record(dir, included, rule)
+	return included, rule
+}
+
+// File reports whether a file may be built, and the rule that decided it
+func (f *PathFilter) File(file string) (bool, string) {
+	return f.check(file, false, func() string { return "" })
+}
+
+// Record adds a decision made outside the filter, e.g. by the Dockerfile patterns
+func (f *PathFilter) Record(path string, included bool, rule string) {
+	f.record(path, included, rule)
+}
+
+// check applies the rules to a path; builtin returns the built-in exclusion rule, if any
+func (f *PathFilter) check(target string, isDir bool, builtin func() string) (bool, string) {
+	rel := relativePath(target)
+
+	if pattern, ok := matchAny(f.Exclude, rel); ok {
+		return false, "discovery.exclude " + pattern
+	}
+	if pattern, ok := matchAny(f.Include, rel); ok {
+		return true, "discovery.include " + pattern
+	}
+	if f.Gitignore {
+		if rule, ignored := f.gitignored(rel, isDir); ignored {
+			return false, rule
+		}
+	}
+	if rule := builtin(); rule != "" {
+		return false, rule
+	}
+	return true, ""
+}
+
+// record appends a decision
+func (f *PathFilter) record(target string, included bool, rule string) {
+	f.Decisions = append(f.Decisions, PathDecision{Path: target, Included: included, Rule: rule})
+}
+
+// relativePath returns a path relative to the project root with "/" separators
+func relativePath(target string) string {
+	if filepath.IsAbs(target) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, target); err == nil {
+				target = rel
+			}
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(target))
+}
+
+// matchAny returns the first pattern matching a path
+func matchAny(patterns []string, rel string) (string, bool) {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, rel) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// MatchGlob reports whether a "/"-separated path matches a glob. "**" matches any number of
+// path segments; a pattern without "/" matches the last path segment at any depth.
+func MatchGlob(pattern, target string) bool {
+	pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/")
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(target))
+		return matched
+	}
+	return matchSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(target, "/"))
+}
+
+// ValidateGlob checks that every segment of a glob is a valid pattern
+func ValidateGlob(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchSegments matches path segments against pattern segments
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// gitignored applies the .gitignore files from the project root down to the path's directory;
+// the last matching pattern wins, as in git
+func (f *PathFilter) gitignored(rel string, isDir bool) (string, bool) {
+	if rel == "." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+
+	ignored := false
+	rule := ""
+	segments := strings.Split(rel, "/")
+	for depth := 0; depth < len(segments); depth++ {
+		dir := "."
+		if depth > 0 {
+			dir = strings.Join(segments[:depth], "/")
+		}
+		relToDir := strings.Join(segments[depth:], "/")
+		for _, pattern := range f.loadIgnoreFile(dir) {
+			if pattern.dirOnly && !isDir {
+				continue
+			}
+			matched := false
+			if pattern.anchored {
+				matched = matchSegments(strings.Split(pattern.pattern, "/"), strings.Split(relToDir, "/"))
+			} else {
+				matched, _ = path.Match(pattern.pattern, path.Base(relToDir))
+			}
+			if matched {
+				ignored = !pattern.negate
+				rule = fmt.Sprintf(".gitignore %s (%s)", pattern.source, path.Join(dir, ".gitignore"))
+			}
+		}
+	}
+	return rule, ignored
+}
+
+// loadIgnoreFile parses the .gitignore in a directory, caching the result
+func (f *PathFilter) loadIgnoreFile(dir string) []ignorePattern {
+	if patterns, ok := f.ignoreFiles[dir]; ok {
+		return patterns
+	}
+
+	var patterns []ignorePattern
+	file, err := os.Open(filepath.Join(filepath.FromSlash(dir), ".gitignore"))
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimRight(scanner.Text(), " \t")
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			pattern := ignorePattern{source: line}
+			if strings.HasPrefix(line, "!") {
+				pattern.negate = true
+				line = line[1:]
+			}
+			line = strings.TrimPrefix(line, "\\")
+			if strings.HasSuffix(line, "/") {
+				pattern.dirOnly = true
+				line = strings.TrimSuffix(line, "/")
+			}
+			// A slash anywhere but the end anchors the pattern to the .gitignore directory
+			if strings.Contains(line, "/") {
+				pattern.anchored = true
+				line = strings.TrimPrefix(line, "/")
+			}
+			pattern.pattern = line
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	f.ignoreFiles[dir] = patterns
+	return patterns
+}
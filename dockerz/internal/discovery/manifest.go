@@ -0,0 +1,295 @@
+package discovery
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadManifest reads the dockerz.service.yaml in a service directory, if there is one
+func LoadManifest(servicePath string) (*ServiceManifest, string, error) {
+	manifestPath := filepath.Join(servicePath, ManifestFile)
+	data, err := os.ReadFile(manifestPath)
+	if os.IsNotExist(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, manifestPath, fmt.Errorf("failed to read %s: %w", manifestPath, err)
+	}
+
+	var manifest ServiceManifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil && err != io.EOF {
+		return nil, manifestPath, fmt.Errorf("failed to parse %s: %w", manifestPath, err)
+	}
+	return &manifest, manifestPath, nil
+}
+
+// MergeServiceSettings combines a service's manifest with its build.yaml entry. Root entries win;
+// every field both define differently is returned as a conflict.
+func MergeServiceSettings(manifest *ServiceManifest, root *config.Service) (ServiceManifest, []string) {
+	var merged ServiceManifest
+	if manifest != nil {
+		merged = *manifest
+	}
+	if root == nil {
+		return merged, nil
+	}
+
+	var conflicts []string
+	pick := func(field string, manifestValue, rootValue interface{}) bool {
+		if reflect.ValueOf(rootValue).Len() == 0 {
+			return false
+		}
+		if manifest != nil && reflect.ValueOf(manifestValue).Len() > 0 && !reflect.DeepEqual(manifestValue, rootValue) {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %v in %s, %v in build.yaml", field, manifestValue, ManifestFile, rootValue))
+		}
+		return true
+	}
+
+	if pick("image_name", merged.ImageName, root.ImageName) {
+		merged.ImageName = root.ImageName
+	}
+	if pick("tag", merged.Tag, root.Tag) {
+		merged.Tag = root.Tag
+	}
+	if pick("tags", merged.Tags, root.Tags) {
+		merged.Tags = root.Tags
+	}
+	if pick("context", merged.Context, root.Context) {
+		merged.Context = root.Context
+	}
+	if pick("depends_on", merged.DependsOn, root.DependsOn) {
+		merged.DependsOn = root.DependsOn
+	}
+	if pick("watch", merged.Watch, root.Watch) {
+		merged.Watch = root.Watch
+	}
+	if pick("platforms", merged.Platforms, root.Platforms) {
+		merged.Platforms = root.Platforms
+	}
+
+	// Build args merge per key
+	if len(root.BuildArgs) > 0 {
+		args := make(map[string]string, len(merged.BuildArgs)+len(root.BuildArgs))
+		for key, value := range merged.BuildArgs {
+			args[key] = value
+		}
+		keys := make([]string, 0, len(root.BuildArgs))
+		for key := range root.BuildArgs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if existing, exists := args[key]; exists && existing != root.BuildArgs[key] {
+				conflicts = append(conflicts, fmt.Sprintf("build_args.%s: %q in %s, %q in build.yaml", key, existing, ManifestFile, root.BuildArgs[key]))
+			}
+			args[key] = root.BuildArgs[key]
+		}
+		merged.BuildArgs = args
+	}
+
+	return merged, conflicts
+}
+
+// applyServiceSettings merges each service's manifest and build.yaml entry into the discovered
+// service, then resolves dependencies. It returns an error for dependency cycles.
+func applyServiceSettings(cfg *config.Config, services []DiscoveredService) ([]error, error) {
+	var errors []error
+
+	for i := range services {
+		service := &services[i]
+
+		// A directory's manifest describes the service built from its default Dockerfile
+		var manifest *ServiceManifest
+		if service.Key() == service.Path && service.Compose == "" {
+			var manifestPath string
+			var err error
+			manifest, manifestPath, err = LoadManifest(service.Path)
+			if err != nil {
+				errors = append(errors, err)
+			}
+			if manifest != nil {
+				service.Manifest = manifestPath
+			}
+		}
+
+		root, _ := cfg.ServiceConfig(service.Key())
+		settings, conflicts := MergeServiceSettings(manifest, root)
+		for _, conflict := range conflicts {
+			errors = append(errors, fmt.Errorf("service %s: conflicting definitions, build.yaml wins: %s", service.Key(), conflict))
+		}
+
+		// Image name and tag from build.yaml were already applied during discovery, except for
+		// services imported from compose files
+		if settings.ImageName != "" && (root == nil || root.ImageName == "" || service.Compose != "") {
+			imageName := NormalizeImagePath(settings.ImageName)
+			if err := ValidateImageName(imageName); err != nil {
+				errors = append(errors, fmt.Errorf("service %s: %w", service.Key(), err))
+			} else {
+				service.ImageName = imageName
+			}
+		}
+		if settings.Tag != "" && (root == nil || root.Tag == "" || service.Compose != "") {
+			service.Tag = settings.Tag
+		}
+
+		service.TagTemplates = settings.Tags
+		// Build args from a compose file are overridden per key
+		if len(service.BuildArgs) > 0 {
+			args := make(map[string]string, len(service.BuildArgs)+len(settings.BuildArgs))
+			for key, value := range service.BuildArgs {
+				args[key] = value
+			}
+			for key, value := range settings.BuildArgs {
+				args[key] = value
+			}
+			settings.BuildArgs = args
+		}
+		service.BuildArgs = settings.BuildArgs
+		service.DependsOn = settings.DependsOn
+		if settings.Context != "" {
+			service.Context = filepath.Clean(filepath.Join(service.Path, settings.Context))
+		}
+		if len(settings.Platforms) > 0 {
+			service.Platforms = settings.Platforms
+		}
+		if len(service.Platforms) == 0 {
+			service.Platforms = cfg.Platforms
+		}
+		service.Watch = nil
+		for _, watch := range settings.Watch {
+			service.Watch = append(service.Watch, filepath.Clean(filepath.Join(service.Path, watch)))
+		}
+	}
+
+	errors = append(errors, resolveDependencies(services)...)
+	if cycle := findCycle(services); cycle != nil {
+		return errors, fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return errors, nil
+}
+
+// resolveDependencies rewrites depends_on entries (service paths, Dockerfile paths or names)
+// to service keys, dropping entries that match no discovered service
+func resolveDependencies(services []DiscoveredService) []error {
+	var errors []error
+	byKey := make(map[string]string)
+	byName := make(map[string][]string)
+	for _, service := range services {
+		byKey[filepath.Clean(service.Key())] = service.Key()
+		byName[service.Name] = append(byName[service.Name], service.Key())
+	}
+
+	for i := range services {
+		service := &services[i]
+		var resolved []string
+		for _, dependency := range service.DependsOn {
+			path, found := byKey[filepath.Clean(dependency)]
+			if !found {
+				switch matches := byName[dependency]; len(matches) {
+				case 1:
+					path, found = matches[0], true
+				case 0:
+					// A service outside this build (e.g. not in the changed services file) is still a valid dependency
+					if ValidateDockerfile(dependency) == nil {
+						path, found = filepath.Clean(dependency), true
+						break
+					}
+					errors = append(errors, fmt.Errorf("service %s: unknown dependency '%s'", service.Key(), dependency))
+				default:
+					errors = append(errors, fmt.Errorf("service %s: dependency '%s' is ambiguous (%s); use the service path", service.Key(), dependency, strings.Join(matches, ", ")))
+				}
+			}
+			if found && path != service.Key() {
+				resolved = append(resolved, path)
+			}
+		}
+		service.DependsOn = resolved
+	}
+	return errors
+}
+
+// findCycle returns the service keys forming a dependency cycle, or nil
+func findCycle(services []DiscoveredService) []string {
+	dependencies := make(map[string][]string)
+	for _, service := range services {
+		dependencies[service.Key()] = service.DependsOn
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var stack []string
+	var visit func(path string) []string
+	visit = func(path string) []string {
+		switch state[path] {
+		case visiting:
+			for i, entry := range stack {
+				if entry == path {
+					return append(append([]string{}, stack[i:]...), path)
+				}
+			}
+		case done:
+			return nil
+		}
+		state[path] = visiting
+		stack = append(stack, path)
+		for _, dependency := range dependencies[path] {
+			if cycle := visit(dependency); cycle != nil {
+				return cycle
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[path] = done
+		return nil
+	}
+
+	for _, service := range services {
+		if cycle := visit(service.Key()); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// SortByDependencies orders services so every service comes after the services it depends on,
+// keeping the discovery order otherwise. Dependencies outside the list are ignored.
+func SortByDependencies(services []DiscoveredService) []DiscoveredService {
+	index := make(map[string]int)
+	for i, service := range services {
+		index[service.Key()] = i
+	}
+
+	sorted := make([]DiscoveredService, 0, len(services))
+	placed := make(map[string]bool)
+	var place func(i int)
+	place = func(i int) {
+		service := services[i]
+		if placed[service.Key()] {
+			return
+		}
+		placed[service.Key()] = true
+		for _, dependency := range service.DependsOn {
+			if j, ok := index[dependency]; ok {
+				place(j)
+			}
+		}
+		sorted = append(sorted, service)
+	}
+	for i := range services {
+		place(i)
+	}
+	return sorted
+}
@@ -0,0 +1,188 @@
+package discovery
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/addy-47/dockerz/internal/config"
+)
+
+// Image naming strategies
+const (
+	NamingBasename = "basename" // the service directory name: backend/api -> api
+	NamingPath     = "path"     // the full service path: backend/api -> backend-api (or backend/api with separator "/")
+	NamingTemplate = "template" // a text/template over NameData
+)
+
+// NameData is the data image name templates are executed with
+type NameData struct {
+	Path     string   // service path with "/" separators, after trim_prefix
+	Base     string   // service directory name
+	Parent   string   // parent directory name, "" for top-level services
+	Segments []string // path segments
+	Variant  string   // Dockerfile variant, "" for the default Dockerfile
+	Name     string   // service name (directory name plus variant)
+}
+
+// ImageNamer derives image names from service paths
+type ImageNamer struct {
+	naming   config.NamingConfig
+	template *template.Template
+}
+
+// NewImageNamer creates a namer for the naming settings
+func NewImageNamer(naming config.NamingConfig) (*ImageNamer, error) {
+	namer := &ImageNamer{naming: naming}
+	if namer.naming.Strategy == "" {
+		namer.naming.Strategy = NamingBasename
+	}
+	if namer.naming.Separator == "" {
+		namer.naming.Separator = "-"
+	}
+
+	switch namer.naming.Strategy {
+	case NamingBasename, NamingPath:
+	case NamingTemplate:
+		if naming.Template == "" {
+			return nil, fmt.Errorf("discovery.naming.template is required for the template strategy")
+		}
+		tmpl, err := template.New("image_name").Funcs(template.FuncMap{"join": strings.Join}).Parse(naming.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid discovery.naming.template: %w", err)
+		}
+		namer.template = tmpl
+	default:
+		return nil, fmt.Errorf("unknown naming strategy '%s' (use basename, path or template)", naming.Strategy)
+	}
+
+	if namer.naming.Separator != "-" && namer.naming.Separator != "/" {
+		return nil, fmt.Errorf("invalid discovery.naming.separator '%s' (use - or /)", naming.Separator)
+	}
+	return namer, nil
+}
+
+// Data returns the template data of a service
+func (n *ImageNamer) Data(service DiscoveredService) NameData {
+	path := filepath.ToSlash(filepath.Clean(service.Path))
+	if prefix := strings.Trim(filepath.ToSlash(n.naming.TrimPrefix), "/"); prefix != "" {
+		if path == prefix {
+			path = "."
+		} else {
+			path = strings.TrimPrefix(path, prefix+"/")
+		}
+	}
+
+	// Dots cannot start or end an image name component (.docker/y -> docker/y)
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment = strings.Trim(segment, "."); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		// A service at the project root (or at trim_prefix) is named after its directory
+		abs, _ := filepath.Abs(service.Path)
+		segments = []string{filepath.Base(abs)}
+	}
+
+	data := NameData{
+		Path:     strings.Join(segments, "/"),
+		Base:     segments[len(segments)-1],
+		Segments: segments,
+		Variant:  VariantName(service.Dockerfile),
+		Name:     service.Name,
+	}
+	if len(segments) > 1 {
+		data.Parent = segments[len(segments)-2]
+	}
+	return data
+}
+
+// ImageName returns the image name of a service under the naming strategy
+func (n *ImageNamer) ImageName(service DiscoveredService) (string, error) {
+	data := n.Data(service)
+
+	var name string
+	switch n.naming.Strategy {
+	case NamingBasename:
+		return NormalizeImageName(service.Name), nil
+	case NamingPath:
+		name = strings.Join(data.Segments, n.naming.Separator)
+		if data.Variant != "" && data.Variant != data.Base {
+			name += "-" + data.Variant
+		}
+	case NamingTemplate:
+		var buf bytes.Buffer
+		if err := n.template.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("service %s: image name template: %w", service.Key(), err)
+		}
+		name = buf.String()
+	}
+
+	imageName := NormalizeImagePath(name)
+	if err := ValidateImageName(imageName); err != nil {
+		return "", fmt.Errorf("service %s: %w", service.Key(), err)
+	}
+	return imageName, nil
+}
+
+// NormalizeImagePath normalizes every "/"-separated component of a nested image name
+func NormalizeImagePath(name string) string {
+	var components []string
+	for _, component := range strings.Split(name, "/") {
+		if strings.TrimSpace(component) != "" {
+			components = append(components, NormalizeImageName(component))
+		}
+	}
+	return strings.Join(components, "/")
+}
+
+// applyNaming names the services whose build.yaml entry does not set an image name
+func applyNaming(cfg *config.Config, services []DiscoveredService) ([]error, error) {
+	namer, err := NewImageNamer(cfg.Discovery.Naming)
+	if err != nil {
+		return nil, err
+	}
+
+	var errors []error
+	for i := range services {
+		if root, ok := cfg.ServiceConfig(services[i].Key()); ok && root.ImageName != "" {
+			continue
+		}
+		// Compose services keep the compose image name
+		if services[i].Compose != "" {
+			continue
+		}
+		imageName, err := namer.ImageName(services[i])
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		services[i].ImageName = imageName
+	}
+	return errors, nil
+}
+
+// checkImageCollisions returns an error when two services would push to the same image
+func checkImageCollisions(services []DiscoveredService) error {
+	owners := make(map[string][]string)
+	for _, service := range services {
+		owners[service.ImageName] = append(owners[service.ImageName], service.Key())
+	}
+
+	var collisions []string
+	for imageName, keys := range owners {
+		if len(keys) > 1 {
+			collisions = append(collisions, fmt.Sprintf("'%s' is used by %s", imageName, strings.Join(keys, ", ")))
+		}
+	}
+	if len(collisions) == 0 {
+		return nil
+	}
+	sort.Strings(collisions)
+	return fmt.Errorf("image name collision: %s; set image_name or choose another discovery.naming strategy", strings.Join(collisions, "; "))
+}
@@ -1,18 +1,63 @@
 package discovery
 
+// ManifestFile is the optional per-service manifest read from each service directory
+const ManifestFile = "dockerz.service.yaml"
+
 // DiscoveredService represents a service discovered during directory scanning
 type DiscoveredService struct {
 	Path         string
+	// Dockerfile is the Dockerfile name inside Path
+	Dockerfile   string
+	// Target is the multi-stage build target, if any
+	Target       string
 	Name         string
 	ImageName    string
 	Tag          string
+	Tags         []string
+	TagTemplates []string
+	Version      string
+	VersionTag   string
+	// Context and Watch are resolved relative to the project root
+	Context      string
+	BuildArgs    map[string]string
+	DependsOn    []string
+	Watch        []string
+	Platforms    []string
+	Manifest     string
+	// Compose is the docker-compose file the service was imported from
+	Compose      string
 	CurrentHash  string
+	// BaseImages maps the service's base images to their current digests
+	BaseImages   map[string]string
+	// BaseUpdates lists the base images whose digest moved since the last build
+	BaseUpdates  []string
 	ChangedFiles []string
 	NeedsBuild   bool
 }
 
+// ServiceManifest is the content of a dockerz.service.yaml file.
+// Paths are relative to the directory containing the manifest.
+type ServiceManifest struct {
+	ImageName string            `yaml:"image_name,omitempty"`
+	Tag       string            `yaml:"tag,omitempty"`
+	Tags      []string          `yaml:"tags,omitempty"`
+	Context   string            `yaml:"context,omitempty"`
+	BuildArgs map[string]string `yaml:"build_args,omitempty"`
+	DependsOn []string          `yaml:"depends_on,omitempty"`
+	Watch     []string          `yaml:"watch,omitempty"`
+	Platforms []string          `yaml:"platforms,omitempty"`
+}
+
+// PathDecision records why discovery scanned or skipped a path
+type PathDecision struct {
+	Path     string
+	Included bool
+	Rule     string
+}
+
 // DiscoveryResult contains the results of service discovery
 type DiscoveryResult struct {
-	Services []DiscoveredService
-	Errors   []error
+	Services  []DiscoveredService
+	Errors    []error
+	Decisions []PathDecision
 }
\ No newline at end of file
@@ -0,0 +1,197 @@
+package dockerfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// heredocPattern matches here-document redirections such as <<EOF, <<-EOF and <<"EOF"
+var heredocPattern = regexp.MustCompile(`<<-?(["']?)([A-Za-z_][A-Za-z0-9_]*)(["']?)`)
+
+// escapeDirective matches the parser directive choosing the line continuation character
+var escapeDirective = regexp.MustCompile("^#\\s*escape\\s*=\\s*([\\\\`])\\s*$")
+
+// ParseFile parses the Dockerfile at path
+func ParseFile(path string) (*Dockerfile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	parsed, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return parsed, nil
+}
+
+// Parse parses a Dockerfile: comments, line continuations, the escape directive and
+// here-documents are handled; instructions are not validated beyond needing a FROM.
+func Parse(r io.Reader) (*Dockerfile, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	escape := `\`
+	parsed := &Dockerfile{Args: make(map[string]string)}
+	var comments []string
+	directives := true
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, "#") {
+			// Parser directives are only recognized before any other line
+			if match := escapeDirective.FindStringSubmatch(trimmed); directives && match != nil {
+				escape = match[1]
+				continue
+			}
+			comments = append(comments, strings.TrimSpace(strings.TrimPrefix(trimmed, "#")))
+			continue
+		}
+		directives = false
+		if trimmed == "" {
+			comments = nil
+			continue
+		}
+
+		instruction := Instruction{Line: i + 1, Comments: comments}
+		comments = nil
+
+		// Join continuation lines; comment lines inside an instruction are dropped
+		text := trimmed
+		for strings.HasSuffix(text, escape) && i+1 < len(lines) {
+			text = strings.TrimSuffix(text, escape)
+			i++
+			next := strings.TrimSpace(lines[i])
+			if strings.HasPrefix(next, "#") {
+				text += escape
+				continue
+			}
+			text += " " + next
+		}
+		text = strings.TrimSuffix(text, escape)
+
+		command, rest, _ := strings.Cut(text, " ")
+		instruction.Command = strings.ToUpper(command)
+		rest = strings.TrimSpace(rest)
+		for strings.HasPrefix(rest, "--") {
+			flag, remaining, _ := strings.Cut(rest, " ")
+			instruction.Flags = append(instruction.Flags, flag)
+			rest = strings.TrimSpace(remaining)
+		}
+		instruction.Args = rest
+
+		// Here-document bodies of RUN, COPY and ADD follow the instruction, each up to its terminator
+		var heredocs [][]string
+		if instruction.Command == "RUN" || instruction.Command == "COPY" || instruction.Command == "ADD" {
+			heredocs = heredocPattern.FindAllStringSubmatch(rest, -1)
+		}
+		for _, match := range heredocs {
+			var body []string
+			for i+1 < len(lines) {
+				i++
+				if strings.TrimLeft(lines[i], "\t") == match[2] {
+					break
+				}
+				body = append(body, lines[i])
+			}
+			instruction.Heredocs = append(instruction.Heredocs, strings.Join(body, "\n"))
+		}
+		instruction.EndLine = i + 1
+
+		parsed.add(instruction)
+	}
+
+	if len(parsed.Stages) == 0 {
+		return nil, fmt.Errorf("no FROM instruction")
+	}
+	return parsed, nil
+}
+
+// add appends an instruction, starting a stage at FROM and recording global ARGs before it
+func (d *Dockerfile) add(instruction Instruction) {
+	d.Instructions = append(d.Instructions, instruction)
+
+	switch {
+	case instruction.Command == "FROM":
+		fields := strings.Fields(instruction.Args)
+		stage := Stage{Parent: -1, From: instruction}
+		if len(fields) > 0 {
+			stage.Base = d.Expand(fields[0])
+		}
+		if len(fields) >= 3 && strings.EqualFold(fields[1], "AS") {
+			stage.Name = fields[2]
+		}
+		for index, previous := range d.Stages {
+			if previous.Name != "" && strings.EqualFold(previous.Name, stage.Base) {
+				stage.Parent = index
+			}
+		}
+		d.Stages = append(d.Stages, stage)
+	case len(d.Stages) == 0:
+		if instruction.Command == "ARG" {
+			for _, field := range strings.Fields(instruction.Args) {
+				name, value, _ := strings.Cut(field, "=")
+				d.Args[name] = strings.Trim(value, `"'`)
+			}
+		}
+	default:
+		last := &d.Stages[len(d.Stages)-1]
+		last.Instructions = append(last.Instructions, instruction)
+	}
+}
+
+// Expand substitutes $VAR and ${VAR} with global ARG defaults; unknown variables are kept
+func (d *Dockerfile) Expand(value string) string {
+	return os.Expand(value, func(name string) string {
+		// ${VAR:-default} and friends fall back to their default
+		if base, fallback, found := strings.Cut(name, ":-"); found {
+			if arg, ok := d.Args[base]; ok && arg != "" {
+				return arg
+			}
+			return fallback
+		}
+		if arg, ok := d.Args[name]; ok {
+			return arg
+		}
+		return "${" + name + "}"
+	})
+}
+
+// Final returns the last stage, the one built unless a target is chosen
+func (d *Dockerfile) Final() *Stage {
+	return &d.Stages[len(d.Stages)-1]
+}
+
+// Target returns the stage built for a --target, or the final stage when target is empty
+func (d *Dockerfile) Target(target string) (*Stage, error) {
+	if target == "" {
+		return d.Final(), nil
+	}
+	for i := range d.Stages {
+		if strings.EqualFold(d.Stages[i].Name, target) {
+			return &d.Stages[i], nil
+		}
+	}
+	return nil, fmt.Errorf("target stage '%s' not found", target)
+}
+
+// Flag returns the value of an instruction flag such as --from, and whether it is set
+func (i Instruction) Flag(name string) (string, bool) {
+	for _, flag := range i.Flags {
+		key, value, _ := strings.Cut(strings.TrimPrefix(flag, "--"), "=")
+		if key == name {
+			return value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,38 @@
+package dockerfile
+
+// Instruction is one Dockerfile instruction with its continuation lines joined
+type Instruction struct {
+	// Command is the upper-cased instruction keyword, e.g. RUN
+	Command string
+	// Flags are the leading --name=value options, e.g. --from=build
+	Flags []string
+	// Args is the rest of the instruction with line continuations removed
+	Args string
+	// Heredocs holds the bodies of here-documents the instruction reads
+	Heredocs []string
+	// Line and EndLine are the 1-based lines the instruction spans
+	Line    int
+	EndLine int
+	// Comments are the comment lines directly above the instruction, without "#"
+	Comments []string
+}
+
+// Stage is a build stage, from its FROM instruction to the next
+type Stage struct {
+	// Base is the FROM image, with global ARG defaults substituted
+	Base string
+	// Name is the AS name, empty for unnamed stages
+	Name string
+	// Parent is the index of the earlier stage Base refers to, -1 for an image
+	Parent       int
+	From         Instruction
+	Instructions []Instruction
+}
+
+// Dockerfile is a parsed Dockerfile
+type Dockerfile struct {
+	// Args are the global ARG defaults declared before the first FROM
+	Args         map[string]string
+	Instructions []Instruction
+	Stages       []Stage
+}
@@ -0,0 +1,294 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/builder"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"gopkg.in/yaml.v3"
+)
+
+// invalidTargetChars matches the characters not allowed in bake target and compose service names
+var invalidTargetChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Targets builds the export targets for the services. Paths are made relative to baseDir,
+// the directory the exported file is written to. Dependencies on services outside the
+// export are left to the registry.
+func Targets(cfg *config.Config, services []discovery.DiscoveredService, baseDir string) ([]Target, error) {
+	names := make(map[string]string, len(services))
+	used := make(map[string]bool, len(services))
+	for _, service := range services {
+		name := targetName(service.Name)
+		unique := name
+		for i := 2; used[unique]; i++ {
+			unique = fmt.Sprintf("%s-%d", name, i)
+		}
+		used[unique] = true
+		names[service.Key()] = unique
+	}
+
+	byKey := make(map[string]discovery.DiscoveredService, len(services))
+	for _, service := range services {
+		byKey[service.Key()] = service
+	}
+
+	targets := make([]Target, 0, len(services))
+	for _, service := range services {
+		contextDir := service.Context
+		if contextDir == "" {
+			contextDir = service.Path
+		}
+		context, err := relativeTo(baseDir, contextDir)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", service.Key(), err)
+		}
+		dockerfileName := service.Dockerfile
+		if dockerfileName == "" {
+			dockerfileName = discovery.DefaultDockerfile
+		}
+		dockerfile, err := relativeTo(contextDir, filepath.Join(service.Path, dockerfileName))
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", service.Key(), err)
+		}
+
+		target := Target{
+			Name:        names[service.Key()],
+			Service:     service.Key(),
+			Context:     context,
+			Dockerfile:  dockerfile,
+			Tags:        imageReferences(cfg, service),
+			Args:        service.BuildArgs,
+			BuildTarget: service.Target,
+			Platforms:   service.Platforms,
+		}
+		for _, key := range service.DependsOn {
+			dependency, ok := byKey[key]
+			if !ok {
+				continue
+			}
+			images := []string{dependency.ImageName}
+			if references := imageReferences(cfg, dependency); references[0] != dependency.ImageName {
+				images = append(images, references[0])
+			}
+			target.DependsOn = append(target.DependsOn, Dependency{Target: names[key], Images: images})
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// Render writes the targets in an export format
+func Render(format string, targets []Target) ([]byte, error) {
+	switch format {
+	case FormatBakeHCL:
+		return BakeHCL(targets), nil
+	case FormatBakeJSON:
+		return BakeJSON(targets)
+	case FormatCompose:
+		return Compose(targets)
+	default:
+		return nil, fmt.Errorf("unknown export format '%s' (use %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// BakeJSON renders the targets as a docker buildx bake JSON file
+func BakeJSON(targets []Target) ([]byte, error) {
+	file := bakeFile{
+		Group:  map[string]bakeGroup{"default": {Targets: targetNames(targets)}},
+		Target: make(map[string]bakeTarget, len(targets)),
+	}
+	for _, target := range targets {
+		file.Target[target.Name] = toBakeTarget(target)
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// BakeHCL renders the targets as a docker buildx bake HCL file
+func BakeHCL(targets []Target) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by dockerz export\n\n")
+	buf.WriteString("group \"default\" {\n")
+	fmt.Fprintf(&buf, "  targets = %s\n", hclList(targetNames(targets)))
+	buf.WriteString("}\n")
+
+	for _, target := range targets {
+		bake := toBakeTarget(target)
+		fmt.Fprintf(&buf, "\n# %s\ntarget %s {\n", target.Service, hclString(target.Name))
+		fmt.Fprintf(&buf, "  context = %s\n", hclString(bake.Context))
+		if bake.Dockerfile != "" {
+			fmt.Fprintf(&buf, "  dockerfile = %s\n", hclString(bake.Dockerfile))
+		}
+		if bake.Target != "" {
+			fmt.Fprintf(&buf, "  target = %s\n", hclString(bake.Target))
+		}
+		if len(bake.Tags) > 0 {
+			fmt.Fprintf(&buf, "  tags = %s\n", hclList(bake.Tags))
+		}
+		if len(bake.Platforms) > 0 {
+			fmt.Fprintf(&buf, "  platforms = %s\n", hclList(bake.Platforms))
+		}
+		writeHCLMap(&buf, "args", bake.Args)
+		writeHCLMap(&buf, "contexts", bake.Contexts)
+		buf.WriteString("}\n")
+	}
+	return buf.Bytes()
+}
+
+// Compose renders the targets as a docker-compose file with build sections
+func Compose(targets []Target) ([]byte, error) {
+	file := composeFile{Services: make(map[string]composeService, len(targets))}
+	for _, target := range targets {
+		service := composeService{
+			Build: composeBuild{
+				Context:   target.Context,
+				Args:      target.Args,
+				Target:    target.BuildTarget,
+				Platforms: target.Platforms,
+			},
+		}
+		if target.Dockerfile != discovery.DefaultDockerfile {
+			service.Build.Dockerfile = target.Dockerfile
+		}
+		if len(target.Tags) > 0 {
+			service.Image = target.Tags[0]
+			service.Build.Tags = target.Tags[1:]
+		}
+		for _, dependency := range target.DependsOn {
+			if service.Build.AdditionalContexts == nil {
+				service.Build.AdditionalContexts = make(map[string]string)
+			}
+			for _, image := range dependency.Images {
+				service.Build.AdditionalContexts[image] = "service:" + dependency.Target
+			}
+		}
+		file.Services[target.Name] = service
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by dockerz export\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(file); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toBakeTarget maps a target onto its bake representation
+func toBakeTarget(target Target) bakeTarget {
+	bake := bakeTarget{
+		Context:   target.Context,
+		Tags:      target.Tags,
+		Args:      target.Args,
+		Target:    target.BuildTarget,
+		Platforms: target.Platforms,
+	}
+	if target.Dockerfile != discovery.DefaultDockerfile {
+		bake.Dockerfile = target.Dockerfile
+	}
+	for _, dependency := range target.DependsOn {
+		if bake.Contexts == nil {
+			bake.Contexts = make(map[string]string)
+		}
+		for _, image := range dependency.Images {
+			bake.Contexts[image] = "target:" + dependency.Target
+		}
+	}
+	return bake
+}
+
+// imageReferences returns the full image references of a service, one per tag
+func imageReferences(cfg *config.Config, service discovery.DiscoveredService) []string {
+	tags := service.Tags
+	if len(tags) == 0 {
+		tags = []string{service.Tag}
+	}
+	references := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		references = append(references, builder.ImageReference(cfg, service.ImageName, tag))
+	}
+	return references
+}
+
+// targetName turns a service name into a valid bake target name
+func targetName(name string) string {
+	name = strings.Trim(invalidTargetChars.ReplaceAllString(name, "-"), "-")
+	if name == "" {
+		return "service"
+	}
+	return name
+}
+
+// targetNames returns the names of the targets in order
+func targetNames(targets []Target) []string {
+	names := make([]string, len(targets))
+	for i, target := range targets {
+		names[i] = target.Name
+	}
+	return names
+}
+
+// relativeTo returns target relative to base with "/" separators
+func relativeTo(base, target string) (string, error) {
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absBase, absTarget)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// hclString quotes a string for HCL; "${" and "%{" are escaped so values are not interpolated
+func hclString(value string) string {
+	quoted, _ := json.Marshal(value)
+	escaped := strings.ReplaceAll(string(quoted), "${", "$${")
+	return strings.ReplaceAll(escaped, "%{", "%%{")
+}
+
+// hclList formats a list of strings for HCL
+func hclList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = hclString(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// writeHCLMap writes a map attribute with sorted keys
+func writeHCLMap(buf *bytes.Buffer, name string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(buf, "  %s = {\n", name)
+	for _, key := range keys {
+		fmt.Fprintf(buf, "    %s = %s\n", hclString(key), hclString(values[key]))
+	}
+	buf.WriteString("  }\n")
+}
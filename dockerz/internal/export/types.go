@@ -0,0 +1,76 @@
+package export
+
+// Export formats
+const (
+	FormatBakeHCL  = "bake-hcl"
+	FormatBakeJSON = "bake-json"
+	FormatCompose  = "compose"
+)
+
+// Formats lists the supported export formats
+var Formats = []string{FormatBakeHCL, FormatBakeJSON, FormatCompose}
+
+// Target is a single service in the exported build graph.
+// Context is relative to the export base directory; Dockerfile is relative to Context.
+type Target struct {
+	Name        string
+	Service     string
+	Context     string
+	Dockerfile  string
+	Tags        []string
+	Args        map[string]string
+	BuildTarget string
+	Platforms   []string
+	DependsOn   []Dependency
+}
+
+// Dependency links a target to another target in the same export. Images are the
+// references a dependent Dockerfile may use for it, e.g. "FROM base".
+type Dependency struct {
+	Target string
+	Images []string
+}
+
+// bakeFile is the JSON form of a docker buildx bake file
+type bakeFile struct {
+	Group  map[string]bakeGroup  `json:"group"`
+	Target map[string]bakeTarget `json:"target"`
+}
+
+// bakeGroup is a bake group
+type bakeGroup struct {
+	Targets []string `json:"targets"`
+}
+
+// bakeTarget is a bake target
+type bakeTarget struct {
+	Context    string            `json:"context"`
+	Dockerfile string            `json:"dockerfile,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Args       map[string]string `json:"args,omitempty"`
+	Target     string            `json:"target,omitempty"`
+	Platforms  []string          `json:"platforms,omitempty"`
+	Contexts   map[string]string `json:"contexts,omitempty"`
+}
+
+// composeFile is an exported docker-compose file
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+// composeService is an exported compose service
+type composeService struct {
+	Image string       `yaml:"image,omitempty"`
+	Build composeBuild `yaml:"build"`
+}
+
+// composeBuild is an exported compose build section
+type composeBuild struct {
+	Context            string            `yaml:"context"`
+	Dockerfile         string            `yaml:"dockerfile,omitempty"`
+	Args               map[string]string `yaml:"args,omitempty"`
+	Target             string            `yaml:"target,omitempty"`
+	Tags               []string          `yaml:"tags,omitempty"`
+	Platforms          []string          `yaml:"platforms,omitempty"`
+	AdditionalContexts map[string]string `yaml:"additional_contexts,omitempty"`
+}
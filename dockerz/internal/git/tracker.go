@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/tracing"
 )
 
 // NewTracker creates a new git tracker
@@ -25,6 +26,11 @@ func (t *Tracker) SetLogger(logger *logging.Logger) {
 	}
 }
 
+// SetTraceParent sets the span that git analysis spans are recorded under
+func (t *Tracker) SetTraceParent(span *tracing.Span) {
+	t.span = span
+}
+
 // getGitRoot finds the root directory of the git repository
 func (t *Tracker) getGitRoot() (string, error) {
 	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
@@ -37,6 +43,17 @@ func (t *Tracker) getGitRoot() (string, error) {
 
 // GetChangedFiles returns files changed in the service directory from both git status and recent commits
 func (t *Tracker) GetChangedFiles(servicePath string, depth int) ([]string, error) {
+	span := tracing.Start("git.changed_files", t.span, tracing.String("dockerz.service.path", servicePath), tracing.Int("git.depth", int64(depth)))
+	defer span.End()
+
+	files, err := t.changedFiles(servicePath, depth)
+	span.SetError(err)
+	span.SetAttributes(tracing.Int("git.changed_files", int64(len(files))))
+	return files, err
+}
+
+// changedFiles implements GetChangedFiles
+func (t *Tracker) changedFiles(servicePath string, depth int) ([]string, error) {
 	var allChangedFiles []string
 	fileSet := make(map[string]bool) // Use map to deduplicate files
 
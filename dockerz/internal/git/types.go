@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/tracing"
 )
 
 // ChangeType represents the type of change in git
@@ -27,10 +28,38 @@ type FileChange struct {
 type CommitInfo struct {
 	Hash      string
 	Message   string
+	Body      string
 	Author    string
 	Timestamp time.Time
 }
 
+// VersionBump represents how much a version must be incremented
+type VersionBump int
+
+const (
+	NoBump VersionBump = iota
+	PatchBump
+	MinorBump
+	MajorBump
+)
+
+// Version represents a semantic version
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ServiceVersion represents the computed next version of a service
+type ServiceVersion struct {
+	Previous    Version
+	PreviousTag string
+	Next        Version
+	Bump        VersionBump
+	Commits     int
+	GitTag      string
+}
+
 // DiffResult represents the result of a git diff operation
 type DiffResult struct {
 	FilesChanged []FileChange
@@ -44,4 +73,6 @@ type Tracker struct {
 	lastCommit string
 	logger     *logging.Logger
 	cache      *GitCache
+	// span is the parent of the tracker's trace spans
+	span *tracing.Span
 }
\ No newline at end of file
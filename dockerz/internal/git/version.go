@@ -0,0 +1,220 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// conventionalHeader matches "type(scope)!: subject" commit headers
+var conventionalHeader = regexp.MustCompile(`^([a-zA-Z]+)(\([^)]*\))?(!)?:\s`)
+
+// ParseVersion parses "X.Y.Z" or "vX.Y.Z" into a Version
+func ParseVersion(value string) (Version, error) {
+	parts := strings.Split(strings.TrimPrefix(value, "v"), ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version '%s': expected X.Y.Z", value)
+	}
+
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version '%s': %s is not a number", value, part)
+		}
+		numbers[i] = n
+	}
+
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// String formats the version as X.Y.Z
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Bump returns the version incremented by the given bump
+func (v Version) Bump(bump VersionBump) Version {
+	switch bump {
+	case MajorBump:
+		return Version{Major: v.Major + 1}
+	case MinorBump:
+		return Version{Major: v.Major, Minor: v.Minor + 1}
+	case PatchBump:
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	default:
+		return v
+	}
+}
+
+// GreaterThan reports whether v sorts after other
+func (v Version) GreaterThan(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch > other.Patch
+}
+
+// ClassifyCommit returns the bump a commit requires under conventional-commit rules
+func ClassifyCommit(commit CommitInfo) VersionBump {
+	if strings.Contains(commit.Body, "BREAKING CHANGE:") || strings.Contains(commit.Body, "BREAKING-CHANGE:") {
+		return MajorBump
+	}
+
+	matches := conventionalHeader.FindStringSubmatch(commit.Message)
+	if matches == nil {
+		return NoBump
+	}
+	if matches[3] == "!" {
+		return MajorBump
+	}
+
+	switch strings.ToLower(matches[1]) {
+	case "feat":
+		return MinorBump
+	case "fix", "perf":
+		return PatchBump
+	default:
+		return NoBump
+	}
+}
+
+// NextVersion computes the next version from the current one and the commits since it
+func NextVersion(current Version, commits []CommitInfo) (Version, VersionBump) {
+	bump := NoBump
+	for _, commit := range commits {
+		if b := ClassifyCommit(commit); b > bump {
+			bump = b
+		}
+	}
+	return current.Bump(bump), bump
+}
+
+// GetLastVersionTag returns the highest git tag with the given prefix (e.g. "api/v")
+func (t *Tracker) GetLastVersionTag(prefix string) (string, Version, bool, error) {
+	gitRoot, err := t.getGitRoot()
+	if err != nil {
+		return "", Version{}, false, fmt.Errorf("not a git repository: %w", err)
+	}
+
+	cmd := exec.Command("git", "tag", "--list", prefix+"*")
+	cmd.Dir = gitRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return "", Version{}, false, fmt.Errorf("failed to list git tags: %w", err)
+	}
+
+	var bestTag string
+	var best Version
+	found := false
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		tag := strings.TrimSpace(line)
+		if tag == "" {
+			continue
+		}
+		version, err := ParseVersion(strings.TrimPrefix(tag, prefix))
+		if err != nil {
+			continue // Ignore tags that share the prefix but are not X.Y.Z
+		}
+		if !found || version.GreaterThan(best) {
+			bestTag, best, found = tag, version, true
+		}
+	}
+
+	return bestTag, best, found, nil
+}
+
+// GetCommitsSince returns commits touching servicePath after ref (all history when ref is empty)
+func (t *Tracker) GetCommitsSince(ref, servicePath string) ([]CommitInfo, error) {
+	gitRoot, err := t.getGitRoot()
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %w", err)
+	}
+
+	revRange := "HEAD"
+	if ref != "" {
+		revRange = ref + "..HEAD"
+	}
+
+	// Unit and record separators keep multi-line bodies intact
+	cmd := exec.Command("git", "log", "--format=%H%x1f%an%x1f%s%x1f%b%x1e", revRange, "--", servicePath)
+	cmd.Dir = gitRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits for %s: %w", servicePath, err)
+	}
+
+	var commits []CommitInfo
+	for _, record := range strings.Split(string(output), "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		if len(fields) < 4 {
+			continue
+		}
+		commits = append(commits, CommitInfo{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Message: fields[2],
+			Body:    fields[3],
+		})
+	}
+
+	return commits, nil
+}
+
+// ComputeServiceVersion works out the next version of a service from its git tags and commits
+func (t *Tracker) ComputeServiceVersion(servicePath, tagPrefix string) (*ServiceVersion, error) {
+	lastTag, current, found, err := t.GetLastVersionTag(tagPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := t.GetCommitsSince(lastTag, servicePath)
+	if err != nil {
+		return nil, err
+	}
+
+	next, bump := NextVersion(current, commits)
+	if !found && bump == NoBump {
+		// First release of a service without releasable commits
+		next = Version{Minor: 1}
+		bump = MinorBump
+	}
+
+	return &ServiceVersion{
+		Previous:    current,
+		PreviousTag: lastTag,
+		Next:        next,
+		Bump:        bump,
+		Commits:     len(commits),
+		GitTag:      tagPrefix + next.String(),
+	}, nil
+}
+
+// CreateTag creates an annotated git tag on HEAD, optionally pushing it to the remote
+func (t *Tracker) CreateTag(tag, message string, push bool) error {
+	gitRoot, err := t.getGitRoot()
+	if err != nil {
+		return fmt.Errorf("not a git repository: %w", err)
+	}
+
+	cmd := exec.Command("git", "tag", "-a", tag, "-m", message)
+	cmd.Dir = gitRoot
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create tag %s: %s", tag, strings.TrimSpace(string(output)))
+	}
+
+	if push {
+		cmd = exec.Command("git", "push", "origin", tag)
+		cmd.Dir = gitRoot
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to push tag %s: %s", tag, strings.TrimSpace(string(output)))
+		}
+	}
+
+	return nil
+}
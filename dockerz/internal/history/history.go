@@ -0,0 +1,81 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// historyFile is the file inside the history directory
+const historyFile = "builds.json"
+
+// Load reads the build history from a directory; a missing history is empty
+func Load(dir string) (*Store, error) {
+	store := &Store{Services: make(map[string][]Run), path: filepath.Join(dir, historyFile)}
+	data, err := os.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return store, fmt.Errorf("failed to read build history: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return &Store{Services: make(map[string][]Run), path: store.path}, fmt.Errorf("failed to parse build history %s: %w", store.path, err)
+	}
+	if store.Services == nil {
+		store.Services = make(map[string][]Run)
+	}
+	return store, nil
+}
+
+// Add records a run of a service, keeping the last MaxRuns
+func (s *Store) Add(service, status string, duration time.Duration, at time.Time) {
+	runs := append(s.Services[service], Run{Status: status, DurationMs: duration.Milliseconds(), Timestamp: at})
+	if len(runs) > MaxRuns {
+		runs = runs[len(runs)-MaxRuns:]
+	}
+	s.Services[service] = runs
+}
+
+// Estimate returns the expected build duration of a service: the median of its recent
+// successful runs, or of all its runs when none succeeded
+func (s *Store) Estimate(service string) (time.Duration, bool) {
+	var durations []int64
+	for _, run := range s.Services[service] {
+		if run.Status == "success" {
+			durations = append(durations, run.DurationMs)
+		}
+	}
+	if len(durations) == 0 {
+		for _, run := range s.Services[service] {
+			durations = append(durations, run.DurationMs)
+		}
+	}
+	if len(durations) == 0 {
+		return 0, false
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return time.Duration(durations[len(durations)/2]) * time.Millisecond, true
+}
+
+// Save writes the history, replacing the file atomically
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write build history: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write build history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package history
+
+import (
+	"time"
+)
+
+// DefaultDir is where build history is kept, relative to the project root
+const DefaultDir = ".dockerz/history"
+
+// MaxRuns is the number of runs kept per service
+const MaxRuns = 20
+
+// Run is the outcome of one build of a service
+type Run struct {
+	Status     string    `json:"status"`
+	DurationMs int64     `json:"duration_ms"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Store holds the recent runs of every service, keyed by service key
+type Store struct {
+	Services map[string][]Run `json:"services"`
+
+	path string
+}
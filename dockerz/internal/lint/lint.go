@@ -0,0 +1,150 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/dockerfile"
+)
+
+// Severities lists the valid rule severities
+var Severities = []string{SeverityError, SeverityWarning, SeverityInfo, SeverityOff}
+
+// LintFile parses and lints the Dockerfile at path
+func LintFile(path string, options Options) ([]Finding, error) {
+	file, err := dockerfile.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Lint(file, path, options)
+}
+
+// Lint runs every enabled rule on a parsed Dockerfile, reporting findings as in path.
+// Findings on instructions below a matching dockerz:ignore comment are dropped.
+func Lint(file *dockerfile.Dockerfile, path string, options Options) ([]Finding, error) {
+	built, err := builtStages(file, options.Target)
+	if err != nil {
+		return nil, err
+	}
+	ignored := make(map[string]bool, len(options.Ignore))
+	for _, id := range options.Ignore {
+		ignored[id] = true
+	}
+
+	var findings []Finding
+	for _, rule := range rules {
+		severity := rule.Severity
+		if override, ok := options.Severity[rule.ID]; ok {
+			severity = override
+		}
+		if ignored[rule.ID] || severity == SeverityOff {
+			continue
+		}
+		for _, match := range rule.check(file, built) {
+			if suppressed(match.instruction, rule.ID) {
+				continue
+			}
+			findings = append(findings, Finding{
+				Rule:     rule.ID,
+				Severity: severity,
+				File:     path,
+				Line:     match.instruction.Line,
+				Message:  match.message,
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Line < findings[j].Line })
+	return findings, nil
+}
+
+// builtStages returns the indexes of the target stage and the stages it is based on, oldest first
+func builtStages(file *dockerfile.Dockerfile, target string) ([]int, error) {
+	stage, err := file.Target(target)
+	if err != nil {
+		return nil, err
+	}
+	index := len(file.Stages) - 1
+	for i := range file.Stages {
+		if &file.Stages[i] == stage {
+			index = i
+		}
+	}
+
+	var chain []int
+	for index >= 0 {
+		chain = append([]int{index}, chain...)
+		index = file.Stages[index].Parent
+	}
+	return chain, nil
+}
+
+// suppressed reports whether a dockerz:ignore comment above the instruction covers the rule
+func suppressed(instruction dockerfile.Instruction, rule string) bool {
+	for _, comment := range instruction.Comments {
+		rest, found := strings.CutPrefix(comment, IgnoreDirective)
+		if !found || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			return true
+		}
+		for _, id := range strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == ' ' }) {
+			if id == rule {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidSeverity reports whether severity is one of Severities
+func ValidSeverity(severity string) bool {
+	for _, known := range Severities {
+		if severity == known {
+			return true
+		}
+	}
+	return false
+}
+
+// rank orders severities, higher is more severe
+func rank(severity string) int {
+	switch severity {
+	case SeverityError:
+		return 3
+	case SeverityWarning:
+		return 2
+	case SeverityInfo:
+		return 1
+	}
+	return 0
+}
+
+// Fails reports whether any finding is at least as severe as threshold; off never fails
+func Fails(findings []Finding, threshold string) bool {
+	if rank(threshold) == 0 {
+		return false
+	}
+	for _, finding := range findings {
+		if rank(finding.Severity) >= rank(threshold) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of findings per severity
+func Count(findings []Finding) map[string]int {
+	counts := make(map[string]int)
+	for _, finding := range findings {
+		counts[finding.Severity]++
+	}
+	return counts
+}
+
+// String formats the finding as file:line: severity: message [rule]
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s [%s]", f.File, f.Line, f.Severity, f.Message, f.Rule)
+}
@@ -0,0 +1,208 @@
+package lint
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/dockerfile"
+)
+
+// rules are the built-in rules in report order
+var rules = []Rule{
+	{
+		ID:          "latest-tag",
+		Severity:    SeverityWarning,
+		Description: "Base image is not pinned to a tag or digest",
+		Help:        "Pin the base image to a version tag or a digest, e.g. alpine:3.20 or alpine@sha256:...",
+		check:       checkLatestTag,
+	},
+	{
+		ID:          "apt-cleanup",
+		Severity:    SeverityWarning,
+		Description: "apt-get install without removing the package lists",
+		Help:        "End the same RUN with 'rm -rf /var/lib/apt/lists/*', or use a cache mount for /var/lib/apt",
+		check:       checkAptCleanup,
+	},
+	{
+		ID:          "missing-user",
+		Severity:    SeverityWarning,
+		Description: "Image runs as root",
+		Help:        "Add a USER instruction with a non-root user to the built stage",
+		check:       checkUser,
+	},
+	{
+		ID:          "add-url",
+		Severity:    SeverityError,
+		Description: "ADD downloads a URL without a checksum",
+		Help:        "Download with curl or wget in a RUN that verifies the file, or use ADD --checksum=sha256:...",
+		check:       checkAddURL,
+	},
+	{
+		ID:          "missing-healthcheck",
+		Severity:    SeverityInfo,
+		Description: "Image has no HEALTHCHECK",
+		Help:        "Add a HEALTHCHECK instruction, or HEALTHCHECK NONE for images that are not services",
+		check:       checkHealthcheck,
+	},
+}
+
+// Rules returns the built-in rules
+func Rules() []Rule {
+	return append([]Rule(nil), rules...)
+}
+
+// Lookup returns the rule with an ID
+func Lookup(id string) (Rule, bool) {
+	for _, rule := range rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// checkLatestTag flags FROM images without a tag or digest, or tagged latest
+func checkLatestTag(file *dockerfile.Dockerfile, built []int) []violation {
+	var found []violation
+	for _, stage := range file.Stages {
+		if stage.Parent >= 0 || stage.Base == "" || strings.EqualFold(stage.Base, "scratch") || strings.Contains(stage.Base, "${") {
+			continue
+		}
+		if strings.Contains(stage.Base, "@") {
+			continue
+		}
+		tag := ""
+		if index := strings.LastIndex(stage.Base, ":"); index > strings.LastIndex(stage.Base, "/") {
+			tag = stage.Base[index+1:]
+		}
+		switch tag {
+		case "":
+			found = append(found, violation{stage.From, fmt.Sprintf("base image %s has no tag and resolves to latest", stage.Base)})
+		case "latest":
+			found = append(found, violation{stage.From, fmt.Sprintf("base image %s uses the latest tag", stage.Base)})
+		}
+	}
+	return found
+}
+
+// checkAptCleanup flags apt-get installs in built stages that leave the package lists in the layer
+func checkAptCleanup(file *dockerfile.Dockerfile, built []int) []violation {
+	var found []violation
+	for _, index := range built {
+		for _, instruction := range file.Stages[index].Instructions {
+			if instruction.Command != "RUN" {
+				continue
+			}
+			script := instruction.Args + "\n" + strings.Join(instruction.Heredocs, "\n")
+			if !aptInstalls(script) {
+				continue
+			}
+			if strings.Contains(script, "/var/lib/apt/lists") {
+				continue
+			}
+			if mount, ok := instruction.Flag("mount"); ok && strings.Contains(mount, "type=cache") && strings.Contains(mount, "/var/lib/apt") {
+				continue
+			}
+			found = append(found, violation{instruction, "apt-get install leaves /var/lib/apt/lists in the image"})
+		}
+	}
+	return found
+}
+
+// aptOptionArguments lists the apt-get options whose value is the next word
+var aptOptionArguments = map[string]bool{
+	"-o": true, "--option": true, "-c": true, "--config-file": true,
+	"-t": true, "--target-release": true, "-a": true, "--host-architecture": true,
+}
+
+// aptInstalls reports whether a shell script runs apt-get or apt with the install subcommand,
+// whatever options precede it (apt-get -y --no-install-recommends install ...)
+func aptInstalls(script string) bool {
+	// Command separators end a command even without surrounding spaces (update&&apt-get)
+	script = strings.ReplaceAll(script, "\\\n", " ")
+	script = strings.NewReplacer(";", " ; ", "&", " ; ", "|", " ; ", "(", " ; ", ")", " ; ", "\n", " ; ").Replace(script)
+	tokens := strings.Fields(script)
+	// Exec form: RUN ["apt-get", "install", "-y", "curl"]
+	for i := range tokens {
+		tokens[i] = strings.Trim(tokens[i], `[]",'`)
+	}
+
+	for i, token := range tokens {
+		if name := path.Base(token); name != "apt-get" && name != "apt" {
+			continue
+		}
+		for j := i + 1; j < len(tokens) && tokens[j] != ";"; j++ {
+			argument := tokens[j]
+			if strings.HasPrefix(argument, "-") {
+				if aptOptionArguments[argument] {
+					j++
+				}
+				continue
+			}
+			if argument == "install" {
+				return true
+			}
+			break
+		}
+	}
+	return false
+}
+
+// checkUser flags built images whose last USER is root, or that never set one
+func checkUser(file *dockerfile.Dockerfile, built []int) []violation {
+	// The built stage inherits the USER of the stages it is based on
+	for i := len(built) - 1; i >= 0; i-- {
+		stage := file.Stages[built[i]]
+		for j := len(stage.Instructions) - 1; j >= 0; j-- {
+			instruction := stage.Instructions[j]
+			if instruction.Command != "USER" {
+				continue
+			}
+			user, _, _ := strings.Cut(strings.TrimSpace(instruction.Args), ":")
+			if user == "root" || user == "0" {
+				return []violation{{instruction, "image runs as root"}}
+			}
+			return nil
+		}
+	}
+	target := file.Stages[built[len(built)-1]]
+	return []violation{{target.From, "image has no USER instruction and runs as root"}}
+}
+
+// checkAddURL flags ADD instructions downloading http(s) URLs without --checksum
+func checkAddURL(file *dockerfile.Dockerfile, built []int) []violation {
+	var found []violation
+	for _, instruction := range file.Instructions {
+		if instruction.Command != "ADD" {
+			continue
+		}
+		if _, ok := instruction.Flag("checksum"); ok {
+			continue
+		}
+		sources := strings.Fields(instruction.Args)
+		if len(sources) > 0 {
+			sources = sources[:len(sources)-1]
+		}
+		for _, source := range sources {
+			source = strings.Trim(source, `[]",`)
+			if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+				found = append(found, violation{instruction, fmt.Sprintf("ADD downloads %s without verifying it", source)})
+			}
+		}
+	}
+	return found
+}
+
+// checkHealthcheck flags built images without a HEALTHCHECK
+func checkHealthcheck(file *dockerfile.Dockerfile, built []int) []violation {
+	for _, index := range built {
+		for _, instruction := range file.Stages[index].Instructions {
+			if instruction.Command == "HEALTHCHECK" {
+				return nil
+			}
+		}
+	}
+	target := file.Stages[built[len(built)-1]]
+	return []violation{{target.From, "image has no HEALTHCHECK"}}
+}
@@ -0,0 +1,28 @@
+package lint
+
+import "testing"
+
+func TestAptInstalls(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected bool
+	}{
+		{"apt-get install -y curl", true},
+		{"apt-get update && apt-get -y install curl", true},
+		{"apt-get  -qq   install git", true},
+		{"apt --no-install-recommends install vim", true},
+		{"apt-get -o Dpkg::Options::=--force-confold install nginx", true},
+		{"apt-get update&&/usr/bin/apt-get -y install curl", true},
+		{"DEBIAN_FRONTEND=noninteractive apt-get \\\n  -y install curl", true},
+		{`["apt-get", "install", "-y", "curl"]`, true},
+		{"apt-get update", false},
+		{"apt-get -y remove install", false},
+		{"echo apt-get; install.sh", false},
+	}
+
+	for _, test := range tests {
+		if got := aptInstalls(test.script); got != test.expected {
+			t.Errorf("aptInstalls(%q) = %v, expected %v", test.script, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package lint
+
+import (
+	"encoding/json"
+	"path/filepath"
+)
+
+// SARIF encodes findings as a SARIF 2.1.0 log, e.g. for GitHub code scanning
+func SARIF(findings []Finding, version string) ([]byte, error) {
+	type message struct {
+		Text string `json:"text"`
+	}
+	type rule struct {
+		ID                   string            `json:"id"`
+		ShortDescription     message           `json:"shortDescription"`
+		Help                 message           `json:"help"`
+		DefaultConfiguration map[string]string `json:"defaultConfiguration"`
+	}
+	type location struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine int `json:"startLine"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+	}
+	type result struct {
+		RuleID    string     `json:"ruleId"`
+		Level     string     `json:"level"`
+		Message   message    `json:"message"`
+		Locations []location `json:"locations"`
+	}
+
+	driverRules := make([]rule, 0, len(rules))
+	for _, r := range rules {
+		driverRules = append(driverRules, rule{
+			ID:                   r.ID,
+			ShortDescription:     message{r.Description},
+			Help:                 message{r.Help},
+			DefaultConfiguration: map[string]string{"level": sarifLevel(r.Severity)},
+		})
+	}
+	results := make([]result, 0, len(findings))
+	for _, finding := range findings {
+		var loc location
+		loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(finding.File)
+		loc.PhysicalLocation.Region.StartLine = finding.Line
+		results = append(results, result{
+			RuleID:    finding.Rule,
+			Level:     sarifLevel(finding.Severity),
+			Message:   message{finding.Message},
+			Locations: []location{loc},
+		})
+	}
+
+	log := map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []interface{}{
+			map[string]interface{}{
+				"tool": map[string]interface{}{
+					"driver": map[string]interface{}{
+						"name":           "dockerz",
+						"version":        version,
+						"informationUri": "https://github.com/addy-47/dockerz",
+						"rules":          driverRules,
+					},
+				},
+				"results": results,
+			},
+		},
+	}
+	return json.MarshalIndent(log, "", "  ")
+}
+
+// sarifLevel maps a severity to a SARIF level
+func sarifLevel(severity string) string {
+	if severity == SeverityInfo {
+		return "note"
+	}
+	return severity
+}
@@ -0,0 +1,53 @@
+package lint
+
+import (
+	"github.com/addy-47/dockerz/internal/dockerfile"
+)
+
+// Severities, from most to least severe; off disables a rule
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+	SeverityOff     = "off"
+)
+
+// IgnoreDirective starts a comment suppressing rules for the instruction below it:
+// "# dockerz:ignore" suppresses every rule, "# dockerz:ignore latest-tag,add-url" only those
+const IgnoreDirective = "dockerz:ignore"
+
+// Rule is a Dockerfile check
+type Rule struct {
+	ID          string
+	Severity    string
+	Description string
+	// Help explains how to fix a finding
+	Help  string
+	check func(file *dockerfile.Dockerfile, built []int) []violation
+}
+
+// violation is a rule match before severities and suppressions apply
+type violation struct {
+	instruction dockerfile.Instruction
+	message     string
+}
+
+// Options adjust the rules for one Dockerfile
+type Options struct {
+	// Ignore lists rule IDs not reported
+	Ignore []string
+	// Severity overrides the severity of rules by ID; "off" disables a rule
+	Severity map[string]string
+	// Target is the stage built, the final stage when empty
+	Target string
+}
+
+// Finding is one rule violation
+type Finding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Message  string `json:"message"`
+	Service  string `json:"service,omitempty"`
+}
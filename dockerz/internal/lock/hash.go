@@ -0,0 +1,215 @@
+package lock
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ContentHash hashes what a build sends to Docker: the relative path and content of every
+// file of the build context not excluded by its .dockerignore, plus the Dockerfile. Paths in
+// skip (such as the lock file and dockerz's state directory) and .git directories are never
+// hashed, so recording the lock does not change the hash it records.
+func ContentHash(context, dockerfile string, skip []string) (string, error) {
+	patterns, err := readIgnore(context, dockerfile)
+	if err != nil {
+		return "", err
+	}
+	skipped := make(map[string]bool, len(skip))
+	for _, path := range skip {
+		if abs, err := filepath.Abs(path); err == nil {
+			skipped[abs] = true
+		}
+	}
+
+	hash := sha256.New()
+	err = filepath.Walk(context, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(context, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		abs, _ := filepath.Abs(path)
+		if skipped[abs] || (info.IsDir() && info.Name() == ".git") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ignored(patterns, rel) {
+			// Exceptions may re-include files below an ignored directory
+			if info.IsDir() && !hasExceptions(patterns) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return hashEntry(hash, rel, path, info)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash build context %s: %w", context, err)
+	}
+
+	// Docker always sends the Dockerfile, even when it is ignored or outside the context
+	info, err := os.Lstat(dockerfile)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash Dockerfile: %w", err)
+	}
+	if err := hashEntry(hash, "\x00dockerfile", dockerfile, info); err != nil {
+		return "", fmt.Errorf("failed to hash Dockerfile: %w", err)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// hashEntry adds one file to the hash: its name, then the sha256 of its content or the
+// target of a symlink. Directories only count through their files.
+func hashEntry(hash io.Writer, name, path string, info os.FileInfo) error {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s\x00link\x00%s\n", name, target)
+	case info.Mode().IsRegular():
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		content := sha256.New()
+		if _, err := io.Copy(content, file); err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s\x00file\x00%x\n", name, content.Sum(nil))
+	}
+	return nil
+}
+
+// readIgnore reads the ignore patterns of a build: <Dockerfile>.dockerignore next to the
+// Dockerfile when it exists, as BuildKit prefers it, otherwise .dockerignore in the context
+func readIgnore(context, dockerfile string) ([]ignorePattern, error) {
+	file, err := os.Open(dockerfile + ".dockerignore")
+	if os.IsNotExist(err) {
+		file, err = os.Open(filepath.Join(context, ".dockerignore"))
+	}
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .dockerignore: %w", err)
+	}
+	defer file.Close()
+
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pattern := ignorePattern{}
+		if strings.HasPrefix(line, "!") {
+			pattern.exception = true
+			line = strings.TrimSpace(line[1:])
+		}
+		line = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(line)), "/")
+		if line == "" || line == "." {
+			continue
+		}
+		expression, err := regexp.Compile(patternExpression(line))
+		if err != nil {
+			return nil, fmt.Errorf("invalid .dockerignore pattern '%s': %w", line, err)
+		}
+		pattern.expression = expression
+		patterns = append(patterns, pattern)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read .dockerignore: %w", err)
+	}
+	return patterns, nil
+}
+
+// patternExpression translates a .dockerignore pattern to a regular expression: * and ?
+// stay within one path element and ** spans any number of them
+func patternExpression(pattern string) string {
+	var expression strings.Builder
+	expression.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					expression.WriteString("(.*/)?")
+				} else {
+					expression.WriteString(".*")
+				}
+				continue
+			}
+			expression.WriteString("[^/]*")
+		case '?':
+			expression.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				expression.WriteString(`\[`)
+				continue
+			}
+			expression.WriteString(strings.Replace(pattern[i:i+end+1], "[!", "[^", 1))
+			i += end
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				expression.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expression.WriteString("$")
+	return expression.String()
+}
+
+// ignored reports whether a context path is excluded: the last pattern matching the path or
+// one of its parent directories decides
+func ignored(patterns []ignorePattern, rel string) bool {
+	excluded := false
+	for _, pattern := range patterns {
+		if pattern.exception == excluded && pattern.matches(rel) {
+			excluded = !pattern.exception
+		}
+	}
+	return excluded
+}
+
+// matches reports whether the pattern matches the path or one of its parent directories
+func (p ignorePattern) matches(rel string) bool {
+	for path := rel; ; {
+		if p.expression.MatchString(path) {
+			return true
+		}
+		index := strings.LastIndex(path, "/")
+		if index < 0 {
+			return false
+		}
+		path = path[:index]
+	}
+}
+
+func hasExceptions(patterns []ignorePattern) bool {
+	for _, pattern := range patterns {
+		if pattern.exception {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	root := t.TempDir()
+	dockerfile := filepath.Join(root, "docker", "api.Dockerfile")
+	lockFile := filepath.Join(root, DefaultPath)
+	state := filepath.Join(root, ".dockerz")
+	writeFile(t, dockerfile, "FROM alpine\n")
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(root, ".github", "workflows", "ci.yml"), "on: push\n")
+	writeFile(t, filepath.Join(root, ".dockerignore"), "# local files\n*.log\nbuild/**\n!build/keep.txt\n")
+	writeFile(t, filepath.Join(root, "build", "keep.txt"), "kept\n")
+	writeFile(t, filepath.Join(root, "build", "out", "bin"), "binary\n")
+
+	hash := func() string {
+		t.Helper()
+		sum, err := ContentHash(root, dockerfile, []string{lockFile, state})
+		if err != nil {
+			t.Fatalf("ContentHash failed: %v", err)
+		}
+		return sum
+	}
+	base := hash()
+
+	unchanged := []struct {
+		name string
+		path string
+	}{
+		{"lock file", lockFile},
+		{"dockerz state", filepath.Join(state, "history", "builds.json")},
+		{"git directory", filepath.Join(root, ".git", "HEAD")},
+		{"ignored file", filepath.Join(root, "debug.log")},
+		{"ignored directory", filepath.Join(root, "build", "out", "bin")},
+	}
+	for _, test := range unchanged {
+		writeFile(t, test.path, "changed\n")
+		if got := hash(); got != base {
+			t.Errorf("Changing the %s changed the hash", test.name)
+		}
+	}
+
+	changed := []struct {
+		name   string
+		change func()
+	}{
+		{"Dockerfile", func() { writeFile(t, dockerfile, "FROM alpine:3.20\n") }},
+		{".github file", func() { writeFile(t, filepath.Join(root, ".github", "workflows", "ci.yml"), "on: pull_request\n") }},
+		{"re-included file", func() { writeFile(t, filepath.Join(root, "build", "keep.txt"), "changed\n") }},
+		{"file name", func() {
+			if err := os.Rename(filepath.Join(root, "main.go"), filepath.Join(root, "app.go")); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, test := range changed {
+		test.change()
+		if got := hash(); got == base {
+			t.Errorf("Changing the %s did not change the hash", test.name)
+		} else {
+			base = got
+		}
+	}
+}
+
+func TestContentHashHashesDockerfileOutsideContext(t *testing.T) {
+	root := t.TempDir()
+	context := filepath.Join(root, "context")
+	dockerfile := filepath.Join(root, "services", "api", "Dockerfile")
+	writeFile(t, filepath.Join(context, "shared.go"), "package shared\n")
+	writeFile(t, dockerfile, "FROM alpine\n")
+
+	before, err := ContentHash(context, dockerfile, nil)
+	if err != nil {
+		t.Fatalf("ContentHash failed: %v", err)
+	}
+	writeFile(t, dockerfile, "FROM alpine\nUSER app\n")
+	after, err := ContentHash(context, dockerfile, nil)
+	if err != nil {
+		t.Fatalf("ContentHash failed: %v", err)
+	}
+	if before == after {
+		t.Error("Changing a Dockerfile outside the context did not change the hash")
+	}
+}
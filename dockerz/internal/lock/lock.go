@@ -0,0 +1,214 @@
+package lock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/registry"
+)
+
+// New returns an empty lock file
+func New() *File {
+	return &File{Version: Version, Services: make(map[string]Service)}
+}
+
+// Read reads a lock file written by Write
+func Read(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	file := New()
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file %s: %w", path, err)
+	}
+	if file.Version != Version {
+		return nil, fmt.Errorf("lock file %s has unsupported version %d", path, file.Version)
+	}
+	if file.Services == nil {
+		file.Services = make(map[string]Service)
+	}
+	return file, nil
+}
+
+// Load reads a lock file, returning an empty one when it does not exist yet
+func Load(path string) (*File, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return New(), nil
+	}
+	return Read(path)
+}
+
+// Write writes the lock file as indented JSON with sorted keys, replacing it atomically
+func Write(path string, file *File) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(file); err != nil {
+		return fmt.Errorf("failed to encode lock file: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create lock file directory: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// Update records a service build. An unpushed rebuild of unchanged inputs keeps the
+// digest pushed before.
+func (f *File) Update(key string, service Service) {
+	if previous, ok := f.Services[key]; ok && service.Digest == "" && len(Diff(previous, service)) == 0 {
+		service.Digest = previous.Digest
+	}
+	f.Services[key] = service
+}
+
+// Diff lists the inputs of current that resolve differently from the locked service
+func Diff(locked, current Service) []string {
+	var diffs []string
+	if locked.Image != current.Image {
+		diffs = append(diffs, fmt.Sprintf("image: locked %s, resolved %s", locked.Image, current.Image))
+	}
+	if locked.ContentHash != current.ContentHash {
+		diffs = append(diffs, fmt.Sprintf("content hash: locked %s, resolved %s", short(locked.ContentHash), short(current.ContentHash)))
+	}
+	if !equalStrings(locked.Tags, current.Tags) {
+		diffs = append(diffs, fmt.Sprintf("tags: locked [%s], resolved [%s]", strings.Join(locked.Tags, ", "), strings.Join(current.Tags, ", ")))
+	}
+	if !equalStrings(locked.Platforms, current.Platforms) {
+		diffs = append(diffs, fmt.Sprintf("platforms: locked [%s], resolved [%s]", strings.Join(locked.Platforms, ", "), strings.Join(current.Platforms, ", ")))
+	}
+	if !equalMaps(locked.BuildArgs, current.BuildArgs) {
+		diffs = append(diffs, "build args: "+mapDiff(locked.BuildArgs, current.BuildArgs))
+	}
+	if !equalMaps(locked.BaseImages, current.BaseImages) {
+		diffs = append(diffs, "base images: "+mapDiff(locked.BaseImages, current.BaseImages))
+	}
+	return diffs
+}
+
+// Verify checks that every tag of the locked, pushed services still points to the locked digest
+func Verify(client *registry.Client, file *File) []Check {
+	keys := make([]string, 0, len(file.Services))
+	for key := range file.Services {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var checks []Check
+	for _, key := range keys {
+		service := file.Services[key]
+		if service.Digest == "" {
+			continue
+		}
+		for _, tag := range service.Tags {
+			check := Check{Service: key, Image: service.Image + ":" + tag, Locked: service.Digest}
+			ref, err := registry.ParseReference(check.Image)
+			if err == nil {
+				var exists bool
+				check.Current, exists, err = client.HeadManifest(ref)
+				if err == nil && !exists {
+					check.Status = StatusMissing
+				}
+			}
+			switch {
+			case err != nil:
+				check.Status = StatusError
+				check.Error = err.Error()
+			case check.Status == StatusMissing:
+			case check.Current == check.Locked:
+				check.Status = StatusMatch
+			default:
+				check.Status = StatusMismatch
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// Failed reports whether any check did not match
+func Failed(checks []Check) bool {
+	for _, check := range checks {
+		if check.Status != StatusMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// mapDiff describes the keys whose values differ between two maps
+func mapDiff(locked, current map[string]string) string {
+	keys := make(map[string]bool)
+	for key := range locked {
+		keys[key] = true
+	}
+	for key := range current {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var parts []string
+	for _, key := range sorted {
+		before, wasLocked := locked[key]
+		after, isCurrent := current[key]
+		switch {
+		case !wasLocked:
+			parts = append(parts, fmt.Sprintf("%s not locked", key))
+		case !isCurrent:
+			parts = append(parts, fmt.Sprintf("%s missing", key))
+		case before != after:
+			parts = append(parts, fmt.Sprintf("%s locked %s, resolved %s", key, short(before), short(after)))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// short abbreviates digests and hashes for messages
+func short(value string) string {
+	algorithm, hex, found := strings.Cut(value, ":")
+	if !found {
+		algorithm, hex = "", value
+	}
+	if len(hex) > 12 {
+		hex = hex[:12]
+	}
+	if algorithm == "" {
+		return hex
+	}
+	return algorithm + ":" + hex
+}
+
+// equalStrings compares lists, treating nil and empty as equal
+func equalStrings(a, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// equalMaps compares maps, treating nil and empty as equal
+func equalMaps(a, b map[string]string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,58 @@
+package lock
+
+import (
+	"regexp"
+)
+
+// DefaultPath is the lock file written next to build.yaml
+const DefaultPath = "dockerz.lock"
+
+// Version is the lock file format version
+const Version = 1
+
+// File is the content of a lock file: what went into each service's image and what came out
+type File struct {
+	Version int `json:"version"`
+	// Services are keyed by service key
+	Services map[string]Service `json:"services"`
+}
+
+// Service records the resolved inputs and the pushed output of one service build
+type Service struct {
+	Name string `json:"name"`
+	// Image is the image repository, without tag
+	Image     string            `json:"image"`
+	Tags      []string          `json:"tags"`
+	BuildArgs map[string]string `json:"build_args,omitempty"`
+	Platforms []string          `json:"platforms,omitempty"`
+	// ContentHash is the sha256 of the service's build context and Dockerfile (see ContentHash)
+	ContentHash string `json:"content_hash"`
+	// BaseImages maps base image references to the digests they resolved to
+	BaseImages map[string]string `json:"base_images,omitempty"`
+	// Digest is the manifest digest pushed under every tag, empty when not pushed
+	Digest string `json:"digest,omitempty"`
+}
+
+// Check statuses of a pushed tag against the lock
+const (
+	StatusMatch    = "match"
+	StatusMismatch = "mismatch"
+	StatusMissing  = "missing"
+	StatusError    = "error"
+)
+
+// Check is the result of checking one locked tag in the registry
+type Check struct {
+	Service string `json:"service"`
+	Image   string `json:"image"`
+	Locked  string `json:"locked"`
+	Current string `json:"current,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ignorePattern is one .dockerignore line; exceptions (!pattern) re-include paths
+type ignorePattern struct {
+	expression *regexp.Regexp
+	exception  bool
+}
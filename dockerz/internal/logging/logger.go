@@ -42,6 +42,14 @@ type Logger struct {
 	mu            sync.RWMutex
 	enabled       map[Category]bool
 	minLevel      Level
+	sections      Sections
+	openSection   string
+}
+
+// Sections renders the markers that turn log sections into collapsible groups, e.g. in CI job logs
+type Sections interface {
+	Begin(title string) string
+	End(title string) string
 }
 
 // NewLogger creates a new logger with both console and file output
@@ -100,6 +108,13 @@ func (l *Logger) SetMinLevel(level Level) {
 	l.minLevel = level
 }
 
+// SetConsoleOutput redirects console logging, e.g. to stderr when stdout carries command output
+func (l *Logger) SetConsoleOutput(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.consoleLogger = log.New(w, "", 0)
+}
+
 // formatMessage formats a log message with timestamp and category
 func (l *Logger) formatMessage(level Level, category Category, message string) string {
 	timestamp := time.Now().Format("15:04:05")
@@ -183,11 +198,37 @@ func (l *Logger) PrintBanner(title string, content []string) {
 	l.Info(CATEGORY_CONFIG, strings.Repeat("=", 40))
 }
 
+// SetSections makes every section printed by PrintSection collapsible until the next one starts
+func (l *Logger) SetSections(sections Sections) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sections = sections
+}
+
 // PrintSection prints a section header
 func (l *Logger) PrintSection(title string) {
+	l.mu.Lock()
+	if l.sections != nil {
+		if l.openSection != "" {
+			l.consoleLogger.Print(l.sections.End(l.openSection))
+		}
+		l.consoleLogger.Print(l.sections.Begin(title))
+		l.openSection = title
+	}
+	l.mu.Unlock()
 	l.Info(CATEGORY_CONFIG, fmt.Sprintf("--- %s ---", title))
 }
 
+// EndSection closes the open collapsible section, if any
+func (l *Logger) EndSection() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sections != nil && l.openSection != "" {
+		l.consoleLogger.Print(l.sections.End(l.openSection))
+		l.openSection = ""
+	}
+}
+
 // PrintSummary prints a summary with key-value pairs
 func (l *Logger) PrintSummary(data map[string]interface{}) {
 	l.Info(CATEGORY_CONFIG, "SUMMARY:")
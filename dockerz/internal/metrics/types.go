@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"time"
+)
+
+// DefaultJob is the Pushgateway job name used when metrics.job is not set
+const DefaultJob = "dockerz"
+
+// Service holds the metrics of one service in a run
+type Service struct {
+	Service string
+	Image   string
+	// Status is "success", "failed" or "skipped"
+	Status      string
+	SkipReason  string
+	Build       time.Duration
+	Push        time.Duration
+	QueueWait   time.Duration
+	PushRetries int
+	CacheHits   int
+	CacheMisses int
+	ImageSize   int64
+}
+
+// Run holds the metrics of a whole build run
+type Run struct {
+	Timestamp  time.Time
+	Duration   time.Duration
+	Sequential time.Duration
+	Services   []Service
+	// Labels are added to every sample, e.g. branch or pipeline
+	Labels map[string]string
+}
@@ -0,0 +1,139 @@
+package promote
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/registry"
+)
+
+// ParseLocation parses --from/--to values. Values containing "/" or ":" name a registry
+// prefix (optionally followed by ":tag" after the last "/"); anything else is a tag.
+func ParseLocation(value string) Location {
+	if !strings.ContainsAny(value, "/:") {
+		return Location{Tag: value}
+	}
+
+	lastSlash := strings.LastIndex(value, "/")
+	if idx := strings.LastIndex(value, ":"); idx > lastSlash && lastSlash != -1 {
+		return Location{Prefix: strings.TrimSuffix(value[:idx], "/"), Tag: value[idx+1:]}
+	}
+	return Location{Prefix: strings.TrimSuffix(value, "/")}
+}
+
+// String formats the location as prefix:tag
+func (l Location) String() string {
+	switch {
+	case l.Prefix == "":
+		return l.Tag
+	case l.Tag == "":
+		return l.Prefix
+	default:
+		return l.Prefix + ":" + l.Tag
+	}
+}
+
+// Resolve fills in missing parts: the source falls back to the configured registry and tag,
+// the destination falls back to the source
+func Resolve(from, to Location, defaultPrefix, defaultTag string) (Location, Location, error) {
+	if from.Prefix == "" {
+		from.Prefix = defaultPrefix
+	}
+	if from.Tag == "" {
+		from.Tag = defaultTag
+	}
+	if from.Prefix == "" {
+		return from, to, fmt.Errorf("--from must name a registry (e.g. localhost:5000/team) when use_gar is disabled")
+	}
+
+	if to.Prefix == "" {
+		to.Prefix = from.Prefix
+	}
+	if to.Tag == "" {
+		to.Tag = from.Tag
+	}
+
+	if from == to {
+		return from, to, fmt.Errorf("source and destination are identical (%s)", from)
+	}
+	return from, to, nil
+}
+
+// Promote copies or retags every image from one location to another and records the outcome.
+// It returns an error when any image failed; the records are complete either way.
+func Promote(client *registry.Client, from, to Location, images []Image, dryRun bool) (*Promotion, error) {
+	promotion := &Promotion{From: from.String(), To: to.String(), DryRun: dryRun}
+	failures := 0
+
+	for _, image := range images {
+		record := Record{
+			Service:     image.Service,
+			Source:      fmt.Sprintf("%s/%s:%s", from.Prefix, image.ImageName, from.Tag),
+			Destination: fmt.Sprintf("%s/%s:%s", to.Prefix, image.ImageName, to.Tag),
+			Mode:        "copy",
+			Timestamp:   time.Now().UTC(),
+		}
+		if from.Prefix == to.Prefix {
+			record.Mode = "retag"
+		}
+
+		src, err := registry.ParseReference(record.Source)
+		if err == nil {
+			var dst registry.Reference
+			dst, err = registry.ParseReference(record.Destination)
+			if err == nil {
+				err = promoteImage(client, src, dst, &record, dryRun)
+			}
+		}
+		if err != nil {
+			record.Error = err.Error()
+			failures++
+		}
+
+		promotion.Records = append(promotion.Records, record)
+	}
+
+	if failures > 0 {
+		return promotion, fmt.Errorf("%d of %d images failed to promote", failures, len(images))
+	}
+	return promotion, nil
+}
+
+// promoteImage promotes one image, or only resolves the source digest on a dry run
+func promoteImage(client *registry.Client, src, dst registry.Reference, record *Record, dryRun bool) error {
+	if dryRun {
+		digest, exists, err := client.HeadManifest(src)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("source image %s not found", src)
+		}
+		record.Digest = digest
+		return nil
+	}
+
+	result, err := client.CopyImage(src, dst)
+	if err != nil {
+		return err
+	}
+
+	record.Digest = result.Digest
+	record.Verified = true
+	record.BlobsCopied = result.BlobsCopied
+	record.BlobsMounted = result.BlobsMounted
+	record.BlobsSkipped = result.BlobsSkipped
+	return nil
+}
+
+// WriteRecord writes the promotion record as indented JSON
+func WriteRecord(promotion *Promotion, path string) error {
+	data, err := json.MarshalIndent(promotion, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal promotion record: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,125 @@
+package promote
+
+import (
+	"testing"
+
+	"github.com/addy-47/dockerz/internal/registry"
+	"github.com/addy-47/dockerz/internal/registry/registrytest"
+)
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Location
+	}{
+		{"v1.2.3", Location{Tag: "v1.2.3"}},
+		{"us-docker.pkg.dev/proj/staging", Location{Prefix: "us-docker.pkg.dev/proj/staging"}},
+		{"localhost:5000/team:rc1", Location{Prefix: "localhost:5000/team", Tag: "rc1"}},
+		{"localhost:5000/team/", Location{Prefix: "localhost:5000/team"}},
+	}
+
+	for _, test := range tests {
+		if got := ParseLocation(test.input); got != test.expected {
+			t.Errorf("ParseLocation(%q) = %+v, expected %+v", test.input, got, test.expected)
+		}
+	}
+}
+
+func TestPromoteCopiesBetweenRepositories(t *testing.T) {
+	server := registrytest.NewServer()
+	defer server.Close()
+
+	digest := server.Registry.PushImage("staging/api", "abc123", []byte("layer-1"), []byte("layer-2"))
+
+	from := Location{Prefix: server.Host() + "/staging", Tag: "abc123"}
+	to := Location{Prefix: server.Host() + "/prod", Tag: "v1.0.0"}
+	promotion, err := Promote(registry.NewClient(false), from, to, []Image{{Service: "api", ImageName: "api"}}, false)
+	if err != nil {
+		t.Fatalf("Promote failed: %v", err)
+	}
+
+	record := promotion.Records[0]
+	if record.Mode != "copy" || !record.Verified {
+		t.Errorf("Expected a verified copy, got %+v", record)
+	}
+	if record.Digest != digest {
+		t.Errorf("Expected digest %s, got %s", digest, record.Digest)
+	}
+	if got := server.Registry.Digest("prod/api", "v1.0.0"); got != digest {
+		t.Errorf("Expected destination digest %s, got %s", digest, got)
+	}
+	if record.BlobsMounted != 3 {
+		t.Errorf("Expected config and layers to be mounted on the same registry, got %+v", record)
+	}
+}
+
+func TestPromoteCopiesAcrossRegistries(t *testing.T) {
+	source := registrytest.NewServer()
+	defer source.Close()
+	destination := registrytest.NewServer()
+	defer destination.Close()
+
+	digest := source.Registry.PushImage("team/web", "rc1", []byte("layer"))
+
+	from := Location{Prefix: source.Host() + "/team", Tag: "rc1"}
+	to := Location{Prefix: destination.Host() + "/team"}
+	from, to, err := Resolve(from, to, "", "")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	promotion, err := Promote(registry.NewClient(false), from, to, []Image{{Service: "web", ImageName: "web"}}, false)
+	if err != nil {
+		t.Fatalf("Promote failed: %v", err)
+	}
+	if promotion.Records[0].BlobsCopied != 2 {
+		t.Errorf("Expected config and layer to be copied, got %+v", promotion.Records[0])
+	}
+	if got := destination.Registry.Digest("team/web", "rc1"); got != digest {
+		t.Errorf("Expected destination digest %s, got %s", digest, got)
+	}
+}
+
+func TestPromoteRetagsInPlace(t *testing.T) {
+	server := registrytest.NewServer()
+	defer server.Close()
+
+	digest := server.Registry.PushImage("apps/api", "abc123", []byte("layer"))
+
+	from, to, err := Resolve(Location{Tag: "abc123"}, Location{Tag: "prod"}, server.Host()+"/apps", "")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	promotion, err := Promote(registry.NewClient(false), from, to, []Image{{Service: "api", ImageName: "api"}}, false)
+	if err != nil {
+		t.Fatalf("Promote failed: %v", err)
+	}
+	if promotion.Records[0].Mode != "retag" {
+		t.Errorf("Expected retag mode, got %s", promotion.Records[0].Mode)
+	}
+	if got := server.Registry.Digest("apps/api", "prod"); got != digest {
+		t.Errorf("Expected retagged digest %s, got %s", digest, got)
+	}
+}
+
+func TestPromoteReportsMissingImages(t *testing.T) {
+	server := registrytest.NewServer()
+	defer server.Close()
+
+	from := Location{Prefix: server.Host() + "/staging", Tag: "missing"}
+	to := Location{Prefix: server.Host() + "/prod", Tag: "missing"}
+	promotion, err := Promote(registry.NewClient(false), from, to, []Image{{Service: "api", ImageName: "api"}}, false)
+	if err == nil {
+		t.Fatal("Expected an error for a missing source image")
+	}
+	if promotion.Records[0].Error == "" || promotion.Records[0].Verified {
+		t.Errorf("Expected a failed record, got %+v", promotion.Records[0])
+	}
+}
+
+func TestResolveRejectsIdenticalLocations(t *testing.T) {
+	if _, _, err := Resolve(Location{Tag: "v1"}, Location{}, "localhost:5000/apps", ""); err == nil {
+		t.Error("Expected an error when source and destination are identical")
+	}
+}
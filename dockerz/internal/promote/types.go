@@ -0,0 +1,40 @@
+package promote
+
+import (
+	"time"
+)
+
+// Location is one side of a promotion: a registry prefix, a tag, or both
+type Location struct {
+	Prefix string
+	Tag    string
+}
+
+// Image is a service image taking part in a promotion
+type Image struct {
+	Service   string
+	ImageName string
+}
+
+// Record describes the promotion of a single image
+type Record struct {
+	Service      string    `json:"service"`
+	Source       string    `json:"source"`
+	Destination  string    `json:"destination"`
+	Digest       string    `json:"digest,omitempty"`
+	Mode         string    `json:"mode"`
+	Verified     bool      `json:"verified"`
+	BlobsCopied  int       `json:"blobs_copied"`
+	BlobsMounted int       `json:"blobs_mounted"`
+	BlobsSkipped int       `json:"blobs_skipped"`
+	Error        string    `json:"error,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// Promotion is the full record of a promote run
+type Promotion struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	DryRun  bool     `json:"dry_run,omitempty"`
+	Records []Record `json:"records"`
+}
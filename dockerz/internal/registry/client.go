@@ -0,0 +1,448 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NewClient creates a registry client; insecure forces plain HTTP for every host
+func NewClient(insecure bool) *Client {
+	return &Client{
+		httpClient:  &http.Client{Timeout: 10 * time.Minute},
+		insecure:    insecure,
+		tokens:      make(map[string]string),
+		credentials: LookupCredentials,
+	}
+}
+
+// SetCredentials overrides how login information is resolved for registry hosts
+func (c *Client) SetCredentials(lookup func(host string) (Credentials, bool)) {
+	c.credentials = lookup
+}
+
+// DigestOf returns the sha256 digest of content
+func DigestOf(content []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+}
+
+// baseURL returns the API root for a registry host; local registries are assumed to speak HTTP
+func (c *Client) baseURL(host string) string {
+	hostname := host
+	if idx := strings.LastIndex(hostname, ":"); idx != -1 {
+		hostname = hostname[:idx]
+	}
+	if c.insecure || hostname == "localhost" || hostname == "127.0.0.1" {
+		return "http://" + host
+	}
+	return "https://" + host
+}
+
+// repositoryScope returns the token scope for a repository
+func repositoryScope(repository, actions string) string {
+	return fmt.Sprintf("repository:%s:%s", repository, actions)
+}
+
+// do sends a request, answering Basic and Bearer auth challenges and retrying once
+func (c *Client) do(req *http.Request, host string, scopes ...string) (*http.Response, error) {
+	tokenKey := host + " " + strings.Join(scopes, " ")
+
+	c.mu.Lock()
+	token := c.tokens[tokenKey]
+	c.mu.Unlock()
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	authorization, err := c.authorize(host, challenge, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.tokens[tokenKey] = authorization
+	c.mu.Unlock()
+
+	// Requests with a streamed body cannot be replayed without GetBody
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, fmt.Errorf("registry %s requires authentication for %s %s", host, req.Method, req.URL.Path)
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	retry.Header.Set("Authorization", authorization)
+
+	return c.httpClient.Do(retry)
+}
+
+// authorize turns an auth challenge into an Authorization header value
+func (c *Client) authorize(host, challenge string, scopes []string) (string, error) {
+	creds, hasCreds := c.credentials(host)
+
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasCreds {
+			return "", fmt.Errorf("registry %s requires credentials", host)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(creds.Username, creds.Password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+		realm := params["realm"]
+		if realm == "" {
+			return "", fmt.Errorf("registry %s sent a bearer challenge without realm", host)
+		}
+		query := url.Values{}
+		if params["service"] != "" {
+			query.Set("service", params["service"])
+		}
+		if len(scopes) == 0 && params["scope"] != "" {
+			scopes = []string{params["scope"]}
+		}
+		for _, scope := range scopes {
+			query.Add("scope", scope)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, realm+"?"+query.Encode(), nil)
+		if err != nil {
+			return "", fmt.Errorf("invalid token realm %s: %w", realm, err)
+		}
+		if hasCreds {
+			req.SetBasicAuth(creds.Username, creds.Password)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch registry token: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("failed to fetch registry token from %s: %s", realm, resp.Status)
+		}
+
+		var tokenResponse struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+			return "", fmt.Errorf("invalid token response: %w", err)
+		}
+		token := tokenResponse.Token
+		if token == "" {
+			token = tokenResponse.AccessToken
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("registry %s sent unsupported auth challenge %q", host, challenge)
+	}
+}
+
+// parseChallenge splits `Bearer realm="...",service="..."` into scheme and parameters
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, ", "), "=")
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end == -1 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key != "" {
+			params[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+
+	return scheme, params
+}
+
+// responseError builds an error from an unexpected registry response
+func responseError(action string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		return fmt.Errorf("%s: %s", action, resp.Status)
+	}
+	return fmt.Errorf("%s: %s: %s", action, resp.Status, message)
+}
+
+// GetManifest downloads a manifest or index
+func (c *Client) GetManifest(ref Reference) (*RawManifest, error) {
+	endpoint := fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL(ref.Registry), ref.Repository, ref.Identifier())
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestAccept, ", "))
+
+	resp, err := c.do(req, ref.Registry, repositoryScope(ref.Repository, "pull"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(fmt.Sprintf("failed to get manifest %s", ref), resp)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", ref, err)
+	}
+
+	digest := DigestOf(content)
+	if ref.Digest != "" && ref.Digest != digest {
+		return nil, fmt.Errorf("manifest %s has digest %s", ref, digest)
+	}
+
+	mediaType := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	if mediaType == "" || mediaType == "application/json" {
+		var probe Manifest
+		if err := json.Unmarshal(content, &probe); err == nil && probe.MediaType != "" {
+			mediaType = probe.MediaType
+		}
+	}
+
+	return &RawManifest{MediaType: mediaType, Digest: digest, Content: content}, nil
+}
+
+// HeadManifest returns the digest a tag or digest resolves to, and false if it does not exist
+func (c *Client) HeadManifest(ref Reference) (string, bool, error) {
+	endpoint := fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL(ref.Registry), ref.Repository, ref.Identifier())
+	req, err := http.NewRequest(http.MethodHead, endpoint, nil)
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestAccept, ", "))
+
+	resp, err := c.do(req, ref.Registry, repositoryScope(ref.Repository, "pull"))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to check manifest %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		digest := resp.Header.Get("Docker-Content-Digest")
+		if digest == "" {
+			// Some registries omit the header on HEAD; fall back to downloading
+			manifest, err := c.GetManifest(ref)
+			if err != nil {
+				return "", false, err
+			}
+			digest = manifest.Digest
+		}
+		return digest, true, nil
+	case http.StatusNotFound:
+		return "", false, nil
+	default:
+		return "", false, responseError(fmt.Sprintf("failed to check manifest %s", ref), resp)
+	}
+}
+
+// PutManifest uploads a manifest under the reference's tag (or digest) and returns its digest
+func (c *Client) PutManifest(ref Reference, manifest *RawManifest) (string, error) {
+	endpoint := fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL(ref.Registry), ref.Repository, ref.Identifier())
+	req, err := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(manifest.Content))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", manifest.MediaType)
+
+	resp, err := c.do(req, ref.Registry, repositoryScope(ref.Repository, "pull,push"))
+	if err != nil {
+		return "", fmt.Errorf("failed to put manifest %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", responseError(fmt.Sprintf("failed to put manifest %s", ref), resp)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		digest = DigestOf(manifest.Content)
+	}
+	return digest, nil
+}
+
+// BlobExists checks whether a repository already has a blob
+func (c *Client) BlobExists(ref Reference, digest string) (bool, error) {
+	endpoint := fmt.Sprintf("%s/v2/%s/blobs/%s", c.baseURL(ref.Registry), ref.Repository, digest)
+	req, err := http.NewRequest(http.MethodHead, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.do(req, ref.Registry, repositoryScope(ref.Repository, "pull,push"))
+	if err != nil {
+		return false, fmt.Errorf("failed to check blob %s: %w", digest, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, responseError(fmt.Sprintf("failed to check blob %s", digest), resp)
+	}
+}
+
+// GetBlob opens a blob for reading; the caller must close it
+func (c *Client) GetBlob(ref Reference, digest string) (io.ReadCloser, error) {
+	endpoint := fmt.Sprintf("%s/v2/%s/blobs/%s", c.baseURL(ref.Registry), ref.Repository, digest)
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req, ref.Registry, repositoryScope(ref.Repository, "pull"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob %s: %w", digest, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(fmt.Sprintf("failed to get blob %s", digest), resp)
+	}
+	return resp.Body, nil
+}
+
+// startUpload opens an upload session, or mounts the blob from another repository when from is set.
+// It returns an empty location when the blob was mounted.
+func (c *Client) startUpload(ref Reference, digest, from string) (string, error) {
+	endpoint := fmt.Sprintf("%s/v2/%s/blobs/uploads/", c.baseURL(ref.Registry), ref.Repository)
+	scopes := []string{repositoryScope(ref.Repository, "pull,push")}
+	if from != "" {
+		endpoint += "?" + url.Values{"mount": {digest}, "from": {from}}.Encode()
+		scopes = append(scopes, repositoryScope(from, "pull"))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.do(req, ref.Registry, scopes...)
+	if err != nil {
+		return "", fmt.Errorf("failed to start upload to %s: %w", ref.Name(), err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return "", nil
+	case http.StatusAccepted:
+		location := resp.Header.Get("Location")
+		if location == "" {
+			return "", fmt.Errorf("registry did not return an upload location for %s", ref.Name())
+		}
+		// Locations may be relative to the registry root
+		if strings.HasPrefix(location, "/") {
+			location = c.baseURL(ref.Registry) + location
+		}
+		return location, nil
+	default:
+		return "", responseError(fmt.Sprintf("failed to start upload to %s", ref.Name()), resp)
+	}
+}
+
+// MountBlob tries to mount a blob from another repository on the same registry
+func (c *Client) MountBlob(ref Reference, digest, fromRepository string) (bool, error) {
+	location, err := c.startUpload(ref, digest, fromRepository)
+	if err != nil {
+		return false, err
+	}
+	return location == "", nil
+}
+
+// PushBlob uploads a blob in a single request
+func (c *Client) PushBlob(ref Reference, digest string, size int64, content io.Reader) error {
+	location, err := c.startUpload(ref, digest, "")
+	if err != nil {
+		return err
+	}
+
+	uploadURL, err := url.Parse(location)
+	if err != nil {
+		return fmt.Errorf("invalid upload location %s: %w", location, err)
+	}
+	query := uploadURL.Query()
+	query.Set("digest", digest)
+	uploadURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodPut, uploadURL.String(), content)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.do(req, ref.Registry, repositoryScope(ref.Repository, "pull,push"))
+	if err != nil {
+		return fmt.Errorf("failed to upload blob %s: %w", digest, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return responseError(fmt.Sprintf("failed to upload blob %s", digest), resp)
+	}
+	return nil
+}
+
+// ListTags returns the tags of a repository
+func (c *Client) ListTags(ref Reference) ([]string, error) {
+	endpoint := fmt.Sprintf("%s/v2/%s/tags/list", c.baseURL(ref.Registry), ref.Repository)
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req, ref.Registry, repositoryScope(ref.Repository, "pull"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %w", ref.Name(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(fmt.Sprintf("failed to list tags of %s", ref.Name()), resp)
+	}
+
+	var tagList struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tagList); err != nil {
+		return nil, fmt.Errorf("invalid tag list for %s: %w", ref.Name(), err)
+	}
+	return tagList.Tags, nil
+}
@@ -0,0 +1,111 @@
+package registry
+
+import (
+	"fmt"
+)
+
+// CopyImage copies an image (and every platform of an index) from src to dst without pulling it locally.
+// Within one repository this is a pure retag. The destination digest is verified against the source.
+func (c *Client) CopyImage(src, dst Reference) (*CopyResult, error) {
+	result := &CopyResult{Source: src, Destination: dst}
+
+	manifest, err := c.GetManifest(src)
+	if err != nil {
+		return nil, err
+	}
+	result.Digest = manifest.Digest
+
+	if src.Registry == dst.Registry && src.Repository == dst.Repository {
+		result.Retagged = true
+	} else if err := c.copyManifestContent(src, dst, manifest, result); err != nil {
+		return nil, err
+	}
+
+	if _, err := c.PutManifest(dst, manifest); err != nil {
+		return nil, err
+	}
+
+	// Verify the destination resolves to exactly the source digest
+	digest, exists, err := c.HeadManifest(dst)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify %s: %w", dst, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("verification failed: %s not found after copy", dst)
+	}
+	if digest != manifest.Digest {
+		return nil, fmt.Errorf("digest mismatch for %s: source %s, destination %s", dst, manifest.Digest, digest)
+	}
+
+	return result, nil
+}
+
+// copyManifestContent copies everything a manifest references: child manifests of an index,
+// or the config and layer blobs of an image
+func (c *Client) copyManifestContent(src, dst Reference, manifest *RawManifest, result *CopyResult) error {
+	parsed, err := manifest.Parse()
+	if err != nil {
+		return fmt.Errorf("invalid manifest %s: %w", src, err)
+	}
+
+	if manifest.IsIndex() {
+		for _, child := range parsed.Manifests {
+			childManifest, err := c.GetManifest(src.WithDigest(child.Digest))
+			if err != nil {
+				return err
+			}
+			if err := c.copyManifestContent(src, dst, childManifest, result); err != nil {
+				return err
+			}
+			if _, err := c.PutManifest(dst.WithDigest(child.Digest), childManifest); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var blobs []Descriptor
+	if parsed.Config != nil {
+		blobs = append(blobs, *parsed.Config)
+	}
+	blobs = append(blobs, parsed.Layers...)
+
+	for _, blob := range blobs {
+		if err := c.copyBlob(src, dst, blob, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyBlob makes a blob available in dst, preferring existing copies and cross-repository mounts
+func (c *Client) copyBlob(src, dst Reference, blob Descriptor, result *CopyResult) error {
+	exists, err := c.BlobExists(dst, blob.Digest)
+	if err != nil {
+		return err
+	}
+	if exists {
+		result.BlobsSkipped++
+		return nil
+	}
+
+	if src.Registry == dst.Registry {
+		mounted, err := c.MountBlob(dst, blob.Digest, src.Repository)
+		if err == nil && mounted {
+			result.BlobsMounted++
+			return nil
+		}
+	}
+
+	content, err := c.GetBlob(src, blob.Digest)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	if err := c.PushBlob(dst, blob.Digest, blob.Size, content); err != nil {
+		return err
+	}
+	result.BlobsCopied++
+	return nil
+}
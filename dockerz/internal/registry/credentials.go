@@ -0,0 +1,100 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// dockerConfig is the subset of ~/.docker/config.json used for registry logins
+type dockerConfig struct {
+	Auths       map[string]struct{ Auth string } `json:"auths"`
+	CredsStore  string                           `json:"credsStore"`
+	CredHelpers map[string]string                `json:"credHelpers"`
+}
+
+// loadDockerConfig reads the Docker CLI configuration, honoring DOCKER_CONFIG
+func loadDockerConfig() (*dockerConfig, bool) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, false
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return nil, false
+	}
+
+	var cfg dockerConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, false
+	}
+	return &cfg, true
+}
+
+// credentialHelper asks a docker-credential-<helper> binary for a host's login
+func credentialHelper(helper, host string) (Credentials, bool) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(host)
+	output, err := cmd.Output()
+	if err != nil {
+		return Credentials{}, false
+	}
+
+	var response struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return Credentials{}, false
+	}
+	return Credentials{Username: response.Username, Password: response.Secret}, true
+}
+
+// LookupCredentials resolves registry logins the same way the Docker CLI does,
+// falling back to a gcloud access token for Google Artifact Registry hosts
+func LookupCredentials(host string) (Credentials, bool) {
+	if cfg, ok := loadDockerConfig(); ok {
+		if helper, exists := cfg.CredHelpers[host]; exists {
+			if creds, ok := credentialHelper(helper, host); ok {
+				return creds, true
+			}
+		}
+
+		for _, key := range []string{host, "https://" + host, "http://" + host} {
+			entry, exists := cfg.Auths[key]
+			if !exists || entry.Auth == "" {
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				continue
+			}
+			if username, password, found := strings.Cut(string(decoded), ":"); found {
+				return Credentials{Username: username, Password: password}, true
+			}
+		}
+
+		if cfg.CredsStore != "" {
+			if creds, ok := credentialHelper(cfg.CredsStore, host); ok {
+				return creds, true
+			}
+		}
+	}
+
+	if strings.HasSuffix(host, "-docker.pkg.dev") || strings.HasSuffix(host, ".gcr.io") || host == "gcr.io" {
+		output, err := exec.Command("gcloud", "auth", "print-access-token").Output()
+		if err == nil {
+			return Credentials{Username: "oauth2accesstoken", Password: strings.TrimSpace(string(output))}, true
+		}
+	}
+
+	return Credentials{}, false
+}
@@ -0,0 +1,93 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultRegistry is used for references without an explicit registry host
+const DefaultRegistry = "registry-1.docker.io"
+
+// isRegistryHost reports whether the first path component names a registry host
+func isRegistryHost(component string) bool {
+	return strings.ContainsAny(component, ".:") || component == "localhost"
+}
+
+// ParseReference parses references such as "host/repo:tag", "repo@sha256:..." or "nginx"
+func ParseReference(value string) (Reference, error) {
+	if value == "" {
+		return Reference{}, fmt.Errorf("empty image reference")
+	}
+
+	var ref Reference
+	remainder := value
+
+	if idx := strings.Index(remainder, "@"); idx != -1 {
+		ref.Digest = remainder[idx+1:]
+		remainder = remainder[:idx]
+		if !strings.HasPrefix(ref.Digest, "sha256:") {
+			return Reference{}, fmt.Errorf("invalid digest in reference '%s'", value)
+		}
+	}
+
+	// A tag is a colon after the last slash (colons before it belong to a port)
+	if idx := strings.LastIndex(remainder, ":"); idx != -1 && idx > strings.LastIndex(remainder, "/") {
+		ref.Tag = remainder[idx+1:]
+		remainder = remainder[:idx]
+	}
+
+	parts := strings.SplitN(remainder, "/", 2)
+	if len(parts) == 2 && isRegistryHost(parts[0]) {
+		ref.Registry = parts[0]
+		ref.Repository = parts[1]
+	} else {
+		ref.Registry = DefaultRegistry
+		ref.Repository = remainder
+		if !strings.Contains(remainder, "/") {
+			ref.Repository = "library/" + remainder
+		}
+	}
+
+	if ref.Repository == "" {
+		return Reference{}, fmt.Errorf("missing repository in reference '%s'", value)
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	return ref, nil
+}
+
+// Identifier returns the digest when set, otherwise the tag
+func (r Reference) Identifier() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// Name returns host/repository without tag or digest
+func (r Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String formats the reference, preferring the digest form when known
+func (r Reference) String() string {
+	if r.Digest != "" {
+		return r.Name() + "@" + r.Digest
+	}
+	return r.Name() + ":" + r.Tag
+}
+
+// WithTag returns a copy of the reference pointing at a tag
+func (r Reference) WithTag(tag string) Reference {
+	r.Tag = tag
+	r.Digest = ""
+	return r
+}
+
+// WithDigest returns a copy of the reference pointing at a digest
+func (r Reference) WithDigest(digest string) Reference {
+	r.Digest = digest
+	return r
+}
@@ -0,0 +1,296 @@
+// Package registrytest provides an in-memory OCI distribution registry for tests and local dry runs.
+package registrytest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// repository holds the content of a single repository
+type repository struct {
+	manifests  map[string][]byte
+	mediaTypes map[string]string
+	tags       map[string]string
+	blobs      map[string][]byte
+}
+
+// Registry is an in-memory registry implementing the subset of the distribution API dockerz uses
+type Registry struct {
+	mu           sync.Mutex
+	repositories map[string]*repository
+	uploads      map[string]string
+	nextUpload   int
+}
+
+// Server is a Registry served over HTTP on localhost
+type Server struct {
+	*httptest.Server
+	Registry *Registry
+}
+
+// New creates an empty in-memory registry
+func New() *Registry {
+	return &Registry{
+		repositories: make(map[string]*repository),
+		uploads:      make(map[string]string),
+	}
+}
+
+// NewServer starts an in-memory registry on a random localhost port
+func NewServer() *Server {
+	registry := New()
+	return &Server{Server: httptest.NewServer(registry), Registry: registry}
+}
+
+// Host returns the host:port to use in image references
+func (s *Server) Host() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// digestOf returns the sha256 digest of content
+func digestOf(content []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+}
+
+// repo returns a repository, creating it when needed; callers hold the lock
+func (r *Registry) repo(name string) *repository {
+	repo, exists := r.repositories[name]
+	if !exists {
+		repo = &repository{
+			manifests:  make(map[string][]byte),
+			mediaTypes: make(map[string]string),
+			tags:       make(map[string]string),
+			blobs:      make(map[string][]byte),
+		}
+		r.repositories[name] = repo
+	}
+	return repo
+}
+
+// PushImage seeds a single-platform image built from the given layer contents and returns its digest
+func (r *Registry) PushImage(name, tag string, layers ...[]byte) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	repo := r.repo(name)
+
+	configBlob := []byte(fmt.Sprintf(`{"architecture":"amd64","os":"linux","rootfs":{"type":"layers","diff_ids":[]},"config":{"Labels":{"repository":%q}}}`, name))
+	repo.blobs[digestOf(configBlob)] = configBlob
+
+	type descriptor struct {
+		MediaType string `json:"mediaType"`
+		Digest    string `json:"digest"`
+		Size      int    `json:"size"`
+	}
+	manifest := struct {
+		SchemaVersion int          `json:"schemaVersion"`
+		MediaType     string       `json:"mediaType"`
+		Config        descriptor   `json:"config"`
+		Layers        []descriptor `json:"layers"`
+	}{
+		SchemaVersion: 2,
+		MediaType:     "application/vnd.oci.image.manifest.v1+json",
+		Config:        descriptor{"application/vnd.oci.image.config.v1+json", digestOf(configBlob), len(configBlob)},
+		Layers:        []descriptor{},
+	}
+	for _, layer := range layers {
+		repo.blobs[digestOf(layer)] = layer
+		manifest.Layers = append(manifest.Layers, descriptor{"application/vnd.oci.image.layer.v1.tar+gzip", digestOf(layer), len(layer)})
+	}
+
+	content, _ := json.Marshal(manifest)
+	digest := digestOf(content)
+	repo.manifests[digest] = content
+	repo.mediaTypes[digest] = manifest.MediaType
+	if tag != "" {
+		repo.tags[tag] = digest
+	}
+	return digest
+}
+
+// Digest returns the digest a tag points to, or "" when it does not exist
+func (r *Registry) Digest(name, tag string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if repo, exists := r.repositories[name]; exists {
+		return repo.tags[tag]
+	}
+	return ""
+}
+
+// HasBlob reports whether a repository holds a blob
+func (r *Registry) HasBlob(name, digest string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if repo, exists := r.repositories[name]; exists {
+		_, found := repo.blobs[digest]
+		return found
+	}
+	return false
+}
+
+// splitPath separates "/v2/<name>/<kind>/<rest>" into name, kind and rest
+func splitPath(path string) (string, string, string) {
+	path = strings.TrimPrefix(path, "/v2/")
+	for _, kind := range []string{"/manifests/", "/blobs/uploads/", "/blobs/", "/tags/list"} {
+		if idx := strings.LastIndex(path, kind); idx != -1 {
+			return path[:idx], strings.Trim(kind, "/"), path[idx+len(kind):]
+		}
+	}
+	return "", "", ""
+}
+
+// ServeHTTP implements the distribution API
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/v2/" || req.URL.Path == "/v2" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	name, kind, rest := splitPath(req.URL.Path)
+	if name == "" {
+		http.NotFound(w, req)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch kind {
+	case "manifests":
+		r.serveManifest(w, req, name, rest)
+	case "blobs":
+		r.serveBlob(w, req, name, rest)
+	case "blobs/uploads":
+		r.serveUpload(w, req, name, rest)
+	case "tags/list":
+		r.serveTags(w, name)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// serveManifest handles GET, HEAD and PUT on manifests
+func (r *Registry) serveManifest(w http.ResponseWriter, req *http.Request, name, reference string) {
+	repo := r.repo(name)
+
+	if req.Method == http.MethodPut {
+		content, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		digest := digestOf(content)
+		if strings.HasPrefix(reference, "sha256:") && reference != digest {
+			http.Error(w, "digest mismatch", http.StatusBadRequest)
+			return
+		}
+		repo.manifests[digest] = content
+		repo.mediaTypes[digest] = req.Header.Get("Content-Type")
+		if !strings.HasPrefix(reference, "sha256:") {
+			repo.tags[reference] = digest
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	digest := reference
+	if !strings.HasPrefix(reference, "sha256:") {
+		digest = repo.tags[reference]
+	}
+	content, exists := repo.manifests[digest]
+	if !exists {
+		http.Error(w, "manifest unknown", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", repo.mediaTypes[digest])
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+	if req.Method == http.MethodGet {
+		w.Write(content)
+	}
+}
+
+// serveBlob handles GET and HEAD on blobs
+func (r *Registry) serveBlob(w http.ResponseWriter, req *http.Request, name, digest string) {
+	content, exists := r.repo(name).blobs[digest]
+	if !exists {
+		http.Error(w, "blob unknown", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+	if req.Method == http.MethodGet {
+		w.Write(content)
+	}
+}
+
+// serveUpload handles mounts, upload sessions and monolithic uploads
+func (r *Registry) serveUpload(w http.ResponseWriter, req *http.Request, name, session string) {
+	repo := r.repo(name)
+
+	switch req.Method {
+	case http.MethodPost:
+		query := req.URL.Query()
+		if mount, from := query.Get("mount"), query.Get("from"); mount != "" && from != "" {
+			if source, exists := r.repositories[from]; exists {
+				if content, found := source.blobs[mount]; found {
+					repo.blobs[mount] = content
+					w.Header().Set("Docker-Content-Digest", mount)
+					w.WriteHeader(http.StatusCreated)
+					return
+				}
+			}
+		}
+		r.nextUpload++
+		id := fmt.Sprintf("upload-%d", r.nextUpload)
+		r.uploads[id] = name
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%s", name, id))
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPut:
+		if r.uploads[session] != name {
+			http.Error(w, "upload unknown", http.StatusNotFound)
+			return
+		}
+		content, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		digest := req.URL.Query().Get("digest")
+		if digestOf(content) != digest {
+			http.Error(w, "digest invalid", http.StatusBadRequest)
+			return
+		}
+		delete(r.uploads, session)
+		repo.blobs[digest] = content
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// serveTags lists the tags of a repository
+func (r *Registry) serveTags(w http.ResponseWriter, name string) {
+	repo, exists := r.repositories[name]
+	if !exists {
+		http.Error(w, "name unknown", http.StatusNotFound)
+		return
+	}
+	tags := make([]string, 0, len(repo.tags))
+	for tag := range repo.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"name": name, "tags": tags})
+}
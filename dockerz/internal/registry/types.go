@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// Manifest media types understood by the registry client
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// manifestAccept lists every manifest type we ask registries for
+var manifestAccept = []string{
+	MediaTypeOCIIndex,
+	MediaTypeDockerManifestList,
+	MediaTypeOCIManifest,
+	MediaTypeDockerManifest,
+}
+
+// Reference identifies an image in a registry (host/repository:tag or @digest)
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// Descriptor describes content stored in a registry
+type Descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	Platform     *Platform         `json:"platform,omitempty"`
+}
+
+// Platform describes the platform of an image in an index
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// Manifest is the subset of image manifests and indexes the client needs to walk
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        *Descriptor       `json:"config,omitempty"`
+	Layers        []Descriptor      `json:"layers,omitempty"`
+	Manifests     []Descriptor      `json:"manifests,omitempty"`
+	Subject       *Descriptor       `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// RawManifest is a manifest exactly as stored in the registry
+type RawManifest struct {
+	MediaType string
+	Digest    string
+	Content   []byte
+}
+
+// Parse decodes the raw manifest content
+func (m *RawManifest) Parse() (*Manifest, error) {
+	var manifest Manifest
+	if err := json.Unmarshal(m.Content, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// IsIndex reports whether the manifest is a multi-platform index
+func (m *RawManifest) IsIndex() bool {
+	return m.MediaType == MediaTypeOCIIndex || m.MediaType == MediaTypeDockerManifestList
+}
+
+// Credentials holds registry login information
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client talks to registries through the OCI distribution API
+type Client struct {
+	httpClient *http.Client
+	insecure   bool
+	mu         sync.Mutex
+	tokens     map[string]string
+	// credentials resolves login information for a registry host
+	credentials func(host string) (Credentials, bool)
+}
+
+// CopyResult describes the outcome of copying an image between repositories
+type CopyResult struct {
+	Source       Reference
+	Destination  Reference
+	Digest       string
+	BlobsCopied  int
+	BlobsMounted int
+	BlobsSkipped int
+	Retagged     bool
+}
@@ -0,0 +1,158 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Summarize counts the services of a report by outcome
+func Summarize(services []Service) Summary {
+	summary := Summary{Total: len(services)}
+	for _, service := range services {
+		switch service.Status {
+		case "success":
+			summary.Successful++
+		case "failed":
+			summary.Failed++
+		default:
+			summary.Skipped++
+		}
+		if service.PushStatus == "failed" {
+			summary.FailedPushes++
+		}
+		if service.SignStatus == "failed" {
+			summary.FailedSignatures++
+		}
+	}
+	return summary
+}
+
+// Durations returns the build duration of every service the report built successfully, keyed
+// by service key. Merged reports give every shard of the next build the same durations.
+func (r Report) Durations() map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	for _, service := range r.Services {
+		if service.Status == "success" {
+			durations[service.Service] = time.Duration(service.DurationMs) * time.Millisecond
+		}
+	}
+	return durations
+}
+
+// Encode writes a report as indented JSON
+func Encode(w io.Writer, report Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Write writes a report as indented JSON, replacing the file atomically
+func Write(path string, report Report) error {
+	var buf bytes.Buffer
+	if err := Encode(&buf, report); err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// Read reads a report written by Write
+func Read(path string) (Report, error) {
+	var report Report
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, fmt.Errorf("failed to read report: %w", err)
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	if report.Version != Version {
+		return report, fmt.Errorf("report %s has unsupported version %d", path, report.Version)
+	}
+	return report, nil
+}
+
+// Merge combines the reports of the shards of one build. Every shard must be present exactly
+// once and all must share the same plan; a service built by one shard replaces the skipped
+// entries other shards recorded for it. The merged duration is that of the slowest shard.
+func Merge(reports []Report) (Report, error) {
+	merged := Report{Version: Version}
+	if len(reports) == 0 {
+		return merged, fmt.Errorf("no reports to merge")
+	}
+
+	first := reports[0].Shard
+	seen := make(map[int]bool)
+	for _, r := range reports {
+		switch {
+		case first == nil && r.Shard == nil:
+			// Unsharded reports, e.g. of separate builds, are merged as they are
+		case first == nil || r.Shard == nil:
+			return merged, fmt.Errorf("cannot merge sharded and unsharded reports")
+		case r.Shard.Count != first.Count || r.Shard.Plan != first.Plan:
+			return merged, fmt.Errorf("shard %d/%d (plan %s) does not match shard %d/%d (plan %s); shards must build the same services with the same history",
+				r.Shard.Index, r.Shard.Count, r.Shard.Plan, first.Index, first.Count, first.Plan)
+		case seen[r.Shard.Index]:
+			return merged, fmt.Errorf("shard %d/%d appears more than once", r.Shard.Index, r.Shard.Count)
+		default:
+			seen[r.Shard.Index] = true
+			merged.Shards = append(merged.Shards, *r.Shard)
+		}
+
+		if r.Timestamp.After(merged.Timestamp) {
+			merged.Timestamp = r.Timestamp
+		}
+		if r.DurationMs > merged.DurationMs {
+			merged.DurationMs = r.DurationMs
+		}
+	}
+	if first != nil {
+		var missing []string
+		for i := 1; i <= first.Count; i++ {
+			if !seen[i] {
+				missing = append(missing, fmt.Sprintf("%d/%d", i, first.Count))
+			}
+		}
+		if len(missing) > 0 {
+			return merged, fmt.Errorf("missing reports for shards %s", strings.Join(missing, ", "))
+		}
+		sort.Slice(merged.Shards, func(i, j int) bool { return merged.Shards[i].Index < merged.Shards[j].Index })
+	}
+
+	services := make(map[string]Service)
+	for _, r := range reports {
+		for _, service := range r.Services {
+			if existing, ok := services[service.Service]; ok && service.Status == "skipped" && existing.Status != "skipped" {
+				continue
+			}
+			services[service.Service] = service
+		}
+	}
+	for _, service := range services {
+		merged.Services = append(merged.Services, service)
+	}
+	sort.Slice(merged.Services, func(i, j int) bool { return merged.Services[i].Service < merged.Services[j].Service })
+	merged.Summary = Summarize(merged.Services)
+	return merged, nil
+}
@@ -0,0 +1,57 @@
+package report
+
+import (
+	"time"
+)
+
+// Version is the format version of build reports
+const Version = 1
+
+// Report is the JSON record of a build run, or of one shard of it
+type Report struct {
+	Version    int       `json:"version"`
+	Timestamp  time.Time `json:"timestamp"`
+	DurationMs int64     `json:"duration_ms"`
+	Shard      *Shard    `json:"shard,omitempty"`
+	// Shards lists the shards a merged report was combined from
+	Shards   []Shard   `json:"shards,omitempty"`
+	Services []Service `json:"services"`
+	Summary  Summary   `json:"summary"`
+}
+
+// Shard describes the shard a report covers
+type Shard struct {
+	Index int `json:"index"`
+	Count int `json:"count"`
+	// Plan identifies the service assignment; every shard of a build must have the same one
+	Plan string `json:"plan"`
+}
+
+// Service is the outcome of one service
+type Service struct {
+	Service string   `json:"service"`
+	Name    string   `json:"name"`
+	Image   string   `json:"image"`
+	Images  []string `json:"images,omitempty"`
+	// Status is "success", "failed" or "skipped"
+	Status     string `json:"status"`
+	Reason     string `json:"reason,omitempty"`
+	Error      string `json:"error,omitempty"`
+	PushStatus string `json:"push_status,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+	// SignStatus is "signed" or "failed" for pushed images when signing is configured
+	SignStatus string `json:"sign_status,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	// Shard is the shard that built the service, 0 when the build was not sharded
+	Shard int `json:"shard,omitempty"`
+}
+
+// Summary counts services by outcome
+type Summary struct {
+	Total            int `json:"total"`
+	Successful       int `json:"successful"`
+	Failed           int `json:"failed"`
+	Skipped          int `json:"skipped"`
+	FailedPushes     int `json:"failed_pushes"`
+	FailedSignatures int `json:"failed_signatures"`
+}
@@ -0,0 +1,176 @@
+package shard
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/discovery"
+)
+
+// Parse parses an "i/N" shard flag value
+func Parse(value string) (Shard, error) {
+	indexText, countText, found := strings.Cut(value, "/")
+	index, indexErr := strconv.Atoi(strings.TrimSpace(indexText))
+	count, countErr := strconv.Atoi(strings.TrimSpace(countText))
+	if !found || indexErr != nil || countErr != nil {
+		return Shard{}, fmt.Errorf("invalid shard '%s': expected i/N, e.g. 1/3", value)
+	}
+	if count < 1 || index < 1 || index > count {
+		return Shard{}, fmt.Errorf("invalid shard '%s': index must be between 1 and %d", value, count)
+	}
+	return Shard{Index: index, Count: count}, nil
+}
+
+// String returns the shard as "i/N"
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// NewPlan splits services into count shards. Services linked by dependencies stay in one shard,
+// so no shard waits for an image built elsewhere. Groups are placed largest first on the least
+// loaded shard, weighted by the known durations of their services (services without one count
+// as the average of the known ones, or equally when none is known). Every shard computes the
+// same plan from the same services and durations.
+func NewPlan(services []discovery.DiscoveredService, count int, durations map[string]time.Duration) Plan {
+	plan := Plan{Count: count, Assignments: make(map[string]int, len(services)), Loads: make([]int64, count)}
+
+	// Union services connected by a dependency in either direction
+	parent := make(map[string]string, len(services))
+	var find func(string) string
+	find = func(key string) string {
+		if parent[key] != key {
+			parent[key] = find(parent[key])
+		}
+		return parent[key]
+	}
+	for _, service := range services {
+		parent[service.Key()] = service.Key()
+	}
+	for _, service := range services {
+		for _, dependency := range service.DependsOn {
+			if _, ok := parent[dependency]; ok {
+				a, b := find(service.Key()), find(dependency)
+				// The smaller key becomes the root so the grouping does not depend on order
+				if a < b {
+					parent[b] = a
+				} else if b < a {
+					parent[a] = b
+				}
+			}
+		}
+	}
+
+	weights, weighted := estimates(services, durations)
+	plan.Weighted = weighted
+	type group struct {
+		root   string
+		keys   []string
+		weight int64
+	}
+	groups := make(map[string]*group)
+	for _, service := range services {
+		root := find(service.Key())
+		if groups[root] == nil {
+			groups[root] = &group{root: root}
+		}
+		groups[root].keys = append(groups[root].keys, service.Key())
+		groups[root].weight += weights[service.Key()]
+	}
+
+	ordered := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		ordered = append(ordered, g)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].weight != ordered[j].weight {
+			return ordered[i].weight > ordered[j].weight
+		}
+		return ordered[i].root < ordered[j].root
+	})
+
+	for _, g := range ordered {
+		target := 0
+		for i := 1; i < count; i++ {
+			if plan.Loads[i] < plan.Loads[target] {
+				target = i
+			}
+		}
+		plan.Loads[target] += g.weight
+		for _, key := range g.keys {
+			plan.Assignments[key] = target + 1
+		}
+	}
+
+	plan.ID = planID(plan)
+	return plan
+}
+
+// estimates returns the expected duration in milliseconds of every service, and whether any
+// of them was known; without any every service weighs 1
+func estimates(services []discovery.DiscoveredService, durations map[string]time.Duration) (map[string]int64, bool) {
+	weights := make(map[string]int64, len(services))
+	var total, known int64
+	for _, service := range services {
+		if estimate, ok := durations[service.Key()]; ok {
+			weights[service.Key()] = estimate.Milliseconds()
+			total += estimate.Milliseconds()
+			known++
+		}
+	}
+
+	fallback := int64(1)
+	if known > 0 {
+		fallback = total / known
+	}
+	for _, service := range services {
+		if _, ok := weights[service.Key()]; !ok {
+			weights[service.Key()] = fallback
+		}
+		// A zero estimate would let one shard take every instant build
+		if weights[service.Key()] < 1 {
+			weights[service.Key()] = 1
+		}
+	}
+	return weights, known > 0
+}
+
+// planID hashes the assignments so shards and report merging can tell whether they agree
+func planID(plan Plan) string {
+	keys := make([]string, 0, len(plan.Assignments))
+	for key := range plan.Assignments {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d\n", plan.Count)
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s=%d\n", key, plan.Assignments[key])
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// Select returns the services of one shard, keeping their order
+func (p Plan) Select(services []discovery.DiscoveredService, shard Shard) []discovery.DiscoveredService {
+	var selected []discovery.DiscoveredService
+	for _, service := range services {
+		if p.Assignments[service.Key()] == shard.Index {
+			selected = append(selected, service)
+		}
+	}
+	return selected
+}
+
+// Check fails when the plan differs from the plan a build expects, so a shard never builds
+// a split the other shards did not compute
+func (p Plan) Check(expected string) error {
+	if expected != "" && expected != p.ID {
+		return fmt.Errorf("shard plan %s does not match the expected plan %s: the shards were given different services or durations", p.ID, expected)
+	}
+	return nil
+}
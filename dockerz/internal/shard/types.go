@@ -0,0 +1,21 @@
+package shard
+
+// Shard identifies one of Count shards of a build; Index is 1-based
+type Shard struct {
+	Index int
+	Count int
+}
+
+// Plan assigns every service to build to a shard
+type Plan struct {
+	Count int
+	// Assignments maps service keys to 1-based shard indexes
+	Assignments map[string]int
+	// Loads holds the estimated duration in milliseconds of each shard, or its number of
+	// services when no duration was known
+	Loads []int64
+	// Weighted is set when services were weighted by known durations
+	Weighted bool
+	// ID identifies the assignment; shards of one build must share it
+	ID string
+}
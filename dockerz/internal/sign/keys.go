@@ -0,0 +1,107 @@
+package sign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GenerateKeyPair creates an ECDSA P-256 key pair, the key type cosign uses, as an
+// unencrypted PKCS#8 private key and a PKIX public key in PEM
+func GenerateKeyPair() ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}), nil
+}
+
+// LoadPrivateKey reads an ECDSA private key from a PEM file (PKCS#8 or SEC 1)
+func LoadPrivateKey(path string) (*ecdsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(block.Type, "ENCRYPTED") {
+		return nil, fmt.Errorf("%s is encrypted (%s); use an unencrypted PKCS#8 key, e.g. from 'dockerz generate-key-pair'", path, block.Type)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %w", path, err)
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is not an ECDSA key", path)
+	}
+	return ecKey, nil
+}
+
+// LoadPublicKey reads an ECDSA public key from a PEM file, such as a cosign.pub
+func LoadPublicKey(path string) (*ecdsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is not an ECDSA key", path)
+	}
+	return ecKey, nil
+}
+
+// readPEM reads the first PEM block of a file
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	return block, nil
+}
+
+// signBytes signs the sha256 of data and returns the ASN.1 signature
+func signBytes(key *ecdsa.PrivateKey, data []byte) ([]byte, error) {
+	sum := sha256.Sum256(data)
+	return key.Sign(rand.Reader, sum[:], crypto.SHA256)
+}
+
+// verifyBytes checks an ASN.1 signature over the sha256 of data
+func verifyBytes(key *ecdsa.PublicKey, data, signature []byte) bool {
+	sum := sha256.Sum256(data)
+	return ecdsa.VerifyASN1(key, sum[:], signature)
+}
+
+// pae is the DSSE pre-authentication encoding of a payload
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
@@ -0,0 +1,237 @@
+package sign
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/addy-47/dockerz/internal/registry"
+)
+
+// SignatureTag returns the tag cosign stores the signatures of a digest under
+func SignatureTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1) + ".sig"
+}
+
+// AttestationTag returns the tag cosign stores the attestations of a digest under
+func AttestationTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1) + ".att"
+}
+
+// Sign signs the image digest of ref and stores the signature next to the image
+func Sign(client *registry.Client, key *ecdsa.PrivateKey, ref registry.Reference) error {
+	if ref.Digest == "" {
+		return fmt.Errorf("cannot sign %s: no digest", ref)
+	}
+	payload, err := json.Marshal(Payload{Critical: Critical{
+		Identity: Identity{DockerReference: ref.Name()},
+		Image:    Image{DockerManifestDigest: ref.Digest},
+		Type:     SignatureType,
+	}})
+	if err != nil {
+		return err
+	}
+	signature, err := signBytes(key, payload)
+	if err != nil {
+		return fmt.Errorf("failed to sign %s: %w", ref, err)
+	}
+
+	layer := registry.Descriptor{
+		MediaType:   MediaTypeSimpleSigning,
+		Annotations: map[string]string{AnnotationSignature: base64.StdEncoding.EncodeToString(signature)},
+	}
+	return appendLayer(client, ref.WithTag(SignatureTag(ref.Digest)), layer, payload)
+}
+
+// Attest signs an in-toto statement with the provenance of the image digest of ref and
+// stores it next to the image
+func Attest(client *registry.Client, key *ecdsa.PrivateKey, ref registry.Reference, provenance Provenance) error {
+	if ref.Digest == "" {
+		return fmt.Errorf("cannot attest %s: no digest", ref)
+	}
+	algorithm, hex, _ := strings.Cut(ref.Digest, ":")
+	statement, err := json.Marshal(Statement{
+		Type:          StatementType,
+		PredicateType: PredicateSLSA,
+		Subject:       []Subject{{Name: ref.Name(), Digest: map[string]string{algorithm: hex}}},
+		Predicate:     provenance,
+	})
+	if err != nil {
+		return err
+	}
+	signature, err := signBytes(key, pae(PayloadTypeInToto, statement))
+	if err != nil {
+		return fmt.Errorf("failed to sign provenance of %s: %w", ref, err)
+	}
+	envelope, err := json.Marshal(Envelope{
+		PayloadType: PayloadTypeInToto,
+		Payload:     base64.StdEncoding.EncodeToString(statement),
+		Signatures:  []EnvelopeSignature{{Sig: base64.StdEncoding.EncodeToString(signature)}},
+	})
+	if err != nil {
+		return err
+	}
+
+	layer := registry.Descriptor{
+		MediaType:   MediaTypeDSSE,
+		Annotations: map[string]string{AnnotationSignature: "", AnnotationPredicate: PredicateSLSA},
+	}
+	return appendLayer(client, ref.WithTag(AttestationTag(ref.Digest)), layer, envelope)
+}
+
+// appendLayer uploads content as a layer of the artifact manifest at ref, keeping the
+// layers already there like cosign does
+func appendLayer(client *registry.Client, ref registry.Reference, layer registry.Descriptor, content []byte) error {
+	layer.Digest = registry.DigestOf(content)
+	layer.Size = int64(len(content))
+
+	manifest := registry.Manifest{SchemaVersion: 2, MediaType: registry.MediaTypeOCIManifest}
+	if _, exists, err := client.HeadManifest(ref); err != nil {
+		return err
+	} else if exists {
+		raw, err := client.GetManifest(ref)
+		if err != nil {
+			return err
+		}
+		existing, err := raw.Parse()
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", ref, err)
+		}
+		manifest.Layers = existing.Layers
+	}
+	for _, existing := range manifest.Layers {
+		if existing.Digest == layer.Digest && existing.Annotations[AnnotationSignature] == layer.Annotations[AnnotationSignature] {
+			return nil
+		}
+	}
+	manifest.Layers = append(manifest.Layers, layer)
+
+	// The config lists the layers like an image config, which is what cosign writes
+	diffIDs := make([]string, len(manifest.Layers))
+	for i, existing := range manifest.Layers {
+		diffIDs[i] = existing.Digest
+	}
+	config, err := json.Marshal(map[string]interface{}{
+		"architecture": "",
+		"os":           "",
+		"config":       map[string]interface{}{},
+		"rootfs":       map[string]interface{}{"type": "layers", "diff_ids": diffIDs},
+	})
+	if err != nil {
+		return err
+	}
+	manifest.Config = &registry.Descriptor{MediaType: MediaTypeOCIConfig, Digest: registry.DigestOf(config), Size: int64(len(config))}
+
+	for _, blob := range [][]byte{content, config} {
+		if err := pushBlob(client, ref, blob); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	_, err = client.PutManifest(ref, &registry.RawManifest{MediaType: registry.MediaTypeOCIManifest, Content: data})
+	return err
+}
+
+// pushBlob uploads a blob unless the repository already has it
+func pushBlob(client *registry.Client, ref registry.Reference, content []byte) error {
+	digest := registry.DigestOf(content)
+	exists, err := client.BlobExists(ref, digest)
+	if err != nil || exists {
+		return err
+	}
+	return client.PushBlob(ref, digest, int64(len(content)), bytes.NewReader(content))
+}
+
+// Verify checks the signatures and provenance attestations of the image digest of ref
+// made with the public key. The provenance returned is the last valid attestation.
+func Verify(client *registry.Client, key *ecdsa.PublicKey, ref registry.Reference) (Verification, error) {
+	verification := Verification{Image: ref.Name(), Digest: ref.Digest}
+
+	err := eachLayer(client, ref.WithTag(SignatureTag(ref.Digest)), MediaTypeSimpleSigning, func(layer registry.Descriptor, content []byte) {
+		signature, err := base64.StdEncoding.DecodeString(layer.Annotations[AnnotationSignature])
+		if err != nil || !verifyBytes(key, content, signature) {
+			return
+		}
+		var payload Payload
+		if json.Unmarshal(content, &payload) == nil && payload.Critical.Image.DockerManifestDigest == ref.Digest {
+			verification.Signatures++
+		}
+	})
+	if err != nil {
+		return verification, err
+	}
+
+	algorithm, hex, _ := strings.Cut(ref.Digest, ":")
+	err = eachLayer(client, ref.WithTag(AttestationTag(ref.Digest)), MediaTypeDSSE, func(layer registry.Descriptor, content []byte) {
+		var envelope Envelope
+		if json.Unmarshal(content, &envelope) != nil || envelope.PayloadType != PayloadTypeInToto {
+			return
+		}
+		statement, err := base64.StdEncoding.DecodeString(envelope.Payload)
+		if err != nil {
+			return
+		}
+		signed := false
+		for _, signature := range envelope.Signatures {
+			sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+			if err == nil && verifyBytes(key, pae(envelope.PayloadType, statement), sig) {
+				signed = true
+			}
+		}
+		var parsed Statement
+		if !signed || json.Unmarshal(statement, &parsed) != nil || parsed.PredicateType != PredicateSLSA {
+			return
+		}
+		for _, subject := range parsed.Subject {
+			if subject.Digest[algorithm] == hex {
+				verification.Attestations++
+				provenance := parsed.Predicate
+				verification.Provenance = &provenance
+				return
+			}
+		}
+	})
+	return verification, err
+}
+
+// eachLayer calls fn with every layer of the given media type in the artifact at ref;
+// a missing artifact has no layers
+func eachLayer(client *registry.Client, ref registry.Reference, mediaType string, fn func(registry.Descriptor, []byte)) error {
+	if _, exists, err := client.HeadManifest(ref); err != nil || !exists {
+		return err
+	}
+	raw, err := client.GetManifest(ref)
+	if err != nil {
+		return err
+	}
+	manifest, err := raw.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", ref, err)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != mediaType {
+			continue
+		}
+		blob, err := client.GetBlob(ref, layer.Digest)
+		if err != nil {
+			return err
+		}
+		content, err := io.ReadAll(blob)
+		blob.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", layer.Digest, err)
+		}
+		if registry.DigestOf(content) != layer.Digest {
+			continue
+		}
+		fn(layer, content)
+	}
+	return nil
+}
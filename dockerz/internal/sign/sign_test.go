@@ -0,0 +1,114 @@
+package sign
+
+import (
+	"crypto/ecdsa"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/addy-47/dockerz/internal/registry"
+	"github.com/addy-47/dockerz/internal/registry/registrytest"
+)
+
+// keyPair generates a key pair and loads it back from PEM files
+func keyPair(t *testing.T) (*ecdsa.PrivateKey, *ecdsa.PublicKey) {
+	t.Helper()
+	private, public, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, DefaultKey), private, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, DefaultPublicKey), public, 0644); err != nil {
+		t.Fatal(err)
+	}
+	key, err := LoadPrivateKey(filepath.Join(dir, DefaultKey))
+	if err != nil {
+		t.Fatalf("LoadPrivateKey failed: %v", err)
+	}
+	pub, err := LoadPublicKey(filepath.Join(dir, DefaultPublicKey))
+	if err != nil {
+		t.Fatalf("LoadPublicKey failed: %v", err)
+	}
+	return key, pub
+}
+
+func TestSignAttestVerify(t *testing.T) {
+	server := registrytest.NewServer()
+	defer server.Close()
+
+	digest := server.Registry.PushImage("team/api", "v1", []byte("layer-1"))
+	ref, err := registry.ParseReference(server.Host() + "/team/api:v1")
+	if err != nil {
+		t.Fatalf("ParseReference failed: %v", err)
+	}
+	ref = ref.WithDigest(digest)
+
+	key, pub := keyPair(t)
+	_, otherPub := keyPair(t)
+	client := registry.NewClient(false)
+
+	if err := Sign(client, key, ref); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	provenance := Provenance{Builder: Builder{ID: "urn:dockerz:builder:test"}, BuildType: BuildType}
+	if err := Attest(client, key, ref, provenance); err != nil {
+		t.Fatalf("Attest failed: %v", err)
+	}
+	if got := server.Registry.Digest("team/api", SignatureTag(digest)); got == "" {
+		t.Errorf("Expected the signature under %s", SignatureTag(digest))
+	}
+	if got := server.Registry.Digest("team/api", AttestationTag(digest)); got == "" {
+		t.Errorf("Expected the attestation under %s", AttestationTag(digest))
+	}
+
+	verification, err := Verify(client, pub, ref)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if verification.Digest != digest || verification.Signatures != 1 || verification.Attestations != 1 {
+		t.Errorf("Expected one signature and one attestation of %s, got %+v", digest, verification)
+	}
+	if verification.Provenance == nil || verification.Provenance.Builder.ID != "urn:dockerz:builder:test" {
+		t.Errorf("Expected the signed provenance, got %+v", verification.Provenance)
+	}
+
+	// Signing again adds a signature next to the first
+	if err := Sign(client, key, ref); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if verification, err = Verify(client, pub, ref); err != nil || verification.Signatures != 2 {
+		t.Errorf("Expected two signatures, got %+v (%v)", verification, err)
+	}
+
+	// Signatures made with another key do not count
+	verification, err = Verify(client, otherPub, ref)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if verification.Signatures != 0 || verification.Attestations != 0 || verification.Provenance != nil {
+		t.Errorf("Expected no signatures made with the wrong key, got %+v", verification)
+	}
+}
+
+func TestVerifyUnsignedImage(t *testing.T) {
+	server := registrytest.NewServer()
+	defer server.Close()
+
+	digest := server.Registry.PushImage("team/web", "v1", []byte("layer-1"))
+	ref, err := registry.ParseReference(server.Host() + "/team/web:v1")
+	if err != nil {
+		t.Fatalf("ParseReference failed: %v", err)
+	}
+	_, pub := keyPair(t)
+
+	verification, err := Verify(registry.NewClient(false), pub, ref.WithDigest(digest))
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if verification.Signatures != 0 || verification.Attestations != 0 {
+		t.Errorf("Expected an unsigned image, got %+v", verification)
+	}
+}
@@ -0,0 +1,138 @@
+package sign
+
+import "time"
+
+// Default key pair file names written by 'dockerz generate-key-pair'
+const (
+	DefaultKey       = "dockerz.key"
+	DefaultPublicKey = "dockerz.pub"
+)
+
+// Media types and annotations of the cosign signature and attestation formats
+const (
+	MediaTypeSimpleSigning = "application/vnd.dev.cosign.simplesigning.v1+json"
+	MediaTypeDSSE          = "application/vnd.dsse.envelope.v1+json"
+	MediaTypeOCIConfig     = "application/vnd.oci.image.config.v1+json"
+	AnnotationSignature    = "dev.cosignproject.cosign/signature"
+	AnnotationPredicate    = "predicateType"
+	SignatureType          = "cosign container image signature"
+)
+
+// in-toto and SLSA identifiers of the provenance attestation
+const (
+	PayloadTypeInToto = "application/vnd.in-toto+json"
+	StatementType     = "https://in-toto.io/Statement/v0.1"
+	PredicateSLSA     = "https://slsa.dev/provenance/v0.2"
+	BuildType         = "https://github.com/addy-47/dockerz/build@v1"
+)
+
+// Payload is the simple signing payload cosign signs for an image digest
+type Payload struct {
+	Critical Critical          `json:"critical"`
+	Optional map[string]string `json:"optional"`
+}
+
+// Critical holds the signed claims about the image
+type Critical struct {
+	Identity Identity `json:"identity"`
+	Image    Image    `json:"image"`
+	Type     string   `json:"type"`
+}
+
+// Identity names the repository the signature was made for
+type Identity struct {
+	DockerReference string `json:"docker-reference"`
+}
+
+// Image holds the signed manifest digest
+type Image struct {
+	DockerManifestDigest string `json:"docker-manifest-digest"`
+}
+
+// Envelope is a DSSE envelope carrying a signed in-toto statement
+type Envelope struct {
+	PayloadType string              `json:"payloadType"`
+	Payload     string              `json:"payload"`
+	Signatures  []EnvelopeSignature `json:"signatures"`
+}
+
+// EnvelopeSignature is one signature of a DSSE envelope
+type EnvelopeSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// Statement is an in-toto statement about the pushed image
+type Statement struct {
+	Type          string     `json:"_type"`
+	PredicateType string     `json:"predicateType"`
+	Subject       []Subject  `json:"subject"`
+	Predicate     Provenance `json:"predicate"`
+}
+
+// Subject is an artifact the statement is about
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Provenance is a SLSA v0.2 provenance predicate
+type Provenance struct {
+	Builder    Builder    `json:"builder"`
+	BuildType  string     `json:"buildType"`
+	Invocation Invocation `json:"invocation"`
+	Metadata   Metadata   `json:"metadata"`
+	Materials  []Material `json:"materials,omitempty"`
+}
+
+// Builder identifies the machine or CI workflow that ran the build
+type Builder struct {
+	ID string `json:"id"`
+}
+
+// Invocation describes how the build was started
+type Invocation struct {
+	ConfigSource ConfigSource           `json:"configSource"`
+	Parameters   map[string]interface{} `json:"parameters,omitempty"`
+	Environment  map[string]interface{} `json:"environment,omitempty"`
+}
+
+// ConfigSource is the build configuration: the git commit and build.yaml
+type ConfigSource struct {
+	URI        string            `json:"uri,omitempty"`
+	Digest     map[string]string `json:"digest,omitempty"`
+	EntryPoint string            `json:"entryPoint,omitempty"`
+}
+
+// Metadata records when the build ran and how complete the provenance is
+type Metadata struct {
+	BuildStartedOn  *time.Time   `json:"buildStartedOn,omitempty"`
+	BuildFinishedOn *time.Time   `json:"buildFinishedOn,omitempty"`
+	Completeness    Completeness `json:"completeness"`
+	Reproducible    bool         `json:"reproducible"`
+}
+
+// Completeness states which provenance parts are complete
+type Completeness struct {
+	Parameters  bool `json:"parameters"`
+	Environment bool `json:"environment"`
+	Materials   bool `json:"materials"`
+}
+
+// Material is an input of the build: the git commit, build.yaml or a base image
+type Material struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// Verification is the result of checking the signatures and attestations of an image
+type Verification struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+	// Signatures counts the signatures made with the public key
+	Signatures int `json:"signatures"`
+	// Attestations counts the provenance attestations made with the public key
+	Attestations int         `json:"attestations"`
+	Provenance   *Provenance `json:"provenance,omitempty"`
+	Error        string      `json:"error,omitempty"`
+}
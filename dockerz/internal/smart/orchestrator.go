@@ -3,6 +3,7 @@ package smart
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/addy-47/dockerz/internal/cache"
@@ -10,6 +11,7 @@ import (
 	"github.com/addy-47/dockerz/internal/discovery"
 	"github.com/addy-47/dockerz/internal/git"
 	"github.com/addy-47/dockerz/internal/logging"
+	"github.com/addy-47/dockerz/internal/tracing"
 )
 
 // Orchestrator handles smart build decisions
@@ -75,21 +77,30 @@ func (o *Orchestrator) OrchestrateBuilds(cfg *config.Config, services []discover
 	result := &OrchestrationResult{
 		ServiceStates: make([]ServiceState, 0, len(services)),
 		Decisions:     make(map[string]BuildDecision),
+		Reasons:       make(map[string]string),
 		TotalServices: len(services),
 	}
 
 	if !o.config.Enabled {
 		// If smart features are disabled, build everything
 		for _, service := range services {
-			result.Decisions[service.Name] = ForceBuild
+			result.Decisions[service.Key()] = ForceBuild
+			result.Reasons[service.Key()] = "smart mode disabled"
 			result.BuildCount++
 		}
 		return result, nil
 	}
 
-	// Analyze each service
-	for _, service := range services {
+	span := tracing.Start("smart.orchestrate", nil, tracing.Int("dockerz.services", int64(len(services))))
+	defer span.End()
+
+	// Analyze each service; git analysis is traced under the service's span
+	serviceSpans := make([]*tracing.Span, len(services))
+	for i, service := range services {
+		serviceSpans[i] = tracing.Start("smart.analyze", span, tracing.String("dockerz.service", service.Key()), tracing.String("dockerz.service.name", service.Name))
+		o.gitTracker.SetTraceParent(serviceSpans[i])
 		state, decision := o.analyzeService(service)
+		serviceSpans[i].End()
 		result.ServiceStates = append(result.ServiceStates, state)
 
 		switch decision {
@@ -99,12 +110,51 @@ func (o *Orchestrator) OrchestrateBuilds(cfg *config.Config, services []discover
 			result.BuildCount++
 		}
 
-		result.Decisions[service.Name] = decision
+		result.Decisions[service.Key()] = decision
+		result.Reasons[service.Key()] = state.Reason
 	}
 
+	o.propagateDependencies(services, result)
+
+	// Decisions are final only once dependencies are propagated
+	for i, service := range services {
+		serviceSpans[i].SetAttributes(
+			tracing.Bool("dockerz.build", result.Decisions[service.Key()] != SkipBuild),
+			tracing.String("dockerz.reason", result.Reasons[service.Key()]))
+	}
+	span.SetAttributes(tracing.Int("dockerz.services.build", int64(result.BuildCount)), tracing.Int("dockerz.services.skip", int64(result.SkipCount)))
+
 	return result, nil
 }
 
+// propagateDependencies rebuilds services whose dependencies are being rebuilt
+func (o *Orchestrator) propagateDependencies(services []discovery.DiscoveredService, result *OrchestrationResult) {
+	// Repeat until nothing changes so rebuilds flow through chains of dependencies
+	for changed := true; changed; {
+		changed = false
+		for _, service := range services {
+			if result.Decisions[service.Key()] != SkipBuild {
+				continue
+			}
+			for _, dependency := range service.DependsOn {
+				decision, inBuild := result.Decisions[dependency]
+				if !inBuild || decision == SkipBuild {
+					continue
+				}
+				if o.logger != nil {
+					o.logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("%s: CONDITIONAL_BUILD - dependency %s is rebuilt", service.Name, dependency))
+				}
+				result.Decisions[service.Key()] = ConditionalBuild
+				result.Reasons[service.Key()] = fmt.Sprintf("dependency %s is rebuilt", dependency)
+				result.SkipCount--
+				result.BuildCount++
+				changed = true
+				break
+			}
+		}
+	}
+}
+
 // analyzeService determines if a service needs to be built
 func (o *Orchestrator) analyzeService(service discovery.DiscoveredService) (ServiceState, BuildDecision) {
 	state := ServiceState{
@@ -121,6 +171,7 @@ func (o *Orchestrator) analyzeService(service discovery.DiscoveredService) (Serv
 		if o.logger != nil {
 			o.logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("%s: FORCE_BUILD - force rebuild enabled", service.Name))
 		}
+		state.Reason = "force rebuild"
 		return state, ForceBuild
 	}
 
@@ -129,6 +180,7 @@ func (o *Orchestrator) analyzeService(service discovery.DiscoveredService) (Serv
 		if o.logger != nil {
 			o.logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("%s: CONDITIONAL_BUILD - git tracking disabled", service.Name))
 		}
+		state.Reason = "git tracking disabled"
 		return state, ConditionalBuild
 	}
 
@@ -142,7 +194,17 @@ func (o *Orchestrator) analyzeService(service discovery.DiscoveredService) (Serv
 		o.logger.Debug(logging.CATEGORY_GIT, fmt.Sprintf("Checking git changes for %s (depth: %d)", service.Name, depth))
 	}
 
-	changedFiles, err := o.gitTracker.GetChangedFiles(service.Path, depth)
+	// Changes to watch paths (e.g. shared libraries) count as changes to the service
+	var changedFiles []string
+	var err error
+	for _, path := range append([]string{service.Path}, service.Watch...) {
+		var files []string
+		files, err = o.gitTracker.GetChangedFiles(path, depth)
+		if err != nil {
+			break
+		}
+		changedFiles = append(changedFiles, files...)
+	}
 	if err != nil {
 		if o.logger != nil {
 			o.logger.Warn(logging.CATEGORY_GIT, fmt.Sprintf("Failed to get git changes for %s: %v", service.Name, err))
@@ -151,6 +213,7 @@ func (o *Orchestrator) analyzeService(service discovery.DiscoveredService) (Serv
 		if o.logger != nil {
 			o.logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("%s: CONDITIONAL_BUILD - git check failed", service.Name))
 		}
+		state.Reason = "git check failed"
 		return state, ConditionalBuild
 	}
 
@@ -166,6 +229,16 @@ func (o *Orchestrator) analyzeService(service discovery.DiscoveredService) (Serv
 		if o.logger != nil {
 			o.logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("%s: CONDITIONAL_BUILD - git changes detected", service.Name))
 		}
+		state.Reason = "git changes detected"
+		return state, ConditionalBuild
+	}
+
+	// A moved base image digest (e.g. a patched base) rebuilds an unchanged service
+	if len(service.BaseUpdates) > 0 {
+		if o.logger != nil {
+			o.logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("%s: CONDITIONAL_BUILD - base image updated: %s", service.Name, strings.Join(service.BaseUpdates, ", ")))
+		}
+		state.Reason = "base image updated: " + strings.Join(service.BaseUpdates, ", ")
 		return state, ConditionalBuild
 	}
 
@@ -174,6 +247,7 @@ func (o *Orchestrator) analyzeService(service discovery.DiscoveredService) (Serv
 		o.logger.Info(logging.CATEGORY_GIT, fmt.Sprintf("Git reports no changes for %s", service.Name))
 		o.logger.Info(logging.CATEGORY_SMART, fmt.Sprintf("%s: SKIP_BUILD - no git changes", service.Name))
 	}
+	state.Reason = "no git changes"
 	return state, SkipBuild
 }
 
@@ -37,12 +37,17 @@ type ServiceState struct {
 	GARConfigured   bool
 	GARReachable    bool
 	GARImageExists  bool
+	// Reason explains the build decision, e.g. "no git changes"
+	Reason          string
 }
 
 // OrchestrationResult represents the result of smart orchestration
 type OrchestrationResult struct {
 	ServiceStates []ServiceState
+	// Decisions are keyed by service key (see discovery.ServiceKey)
 	Decisions     map[string]BuildDecision
+	// Reasons explain each decision, keyed like Decisions
+	Reasons       map[string]string
 	TotalServices int
 	SkipCount     int
 	BuildCount    int
@@ -0,0 +1,183 @@
+package tagging
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/cache"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+)
+
+// validTag matches the Docker tag grammar
+var validTag = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// semverTag matches git tags such as v1.2.3 or 1.2.3-rc.1
+var semverTag = regexp.MustCompile(`^v?(\d+\.\d+\.\d+(?:[-+][0-9A-Za-z.-]+)?)$`)
+
+// runGit runs a git command and returns its trimmed output
+func runGit(args ...string) string {
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// LoadGitInfo collects commit, branch and tag information for the current checkout
+func LoadGitInfo() GitInfo {
+	info := GitInfo{
+		SHA:      runGit("rev-parse", "HEAD"),
+		ShortSHA: runGit("rev-parse", "--short", "HEAD"),
+		Branch:   runGit("rev-parse", "--abbrev-ref", "HEAD"),
+		GitTag:   runGit("describe", "--tags", "--exact-match", "HEAD"),
+	}
+
+	// Detached HEAD (common in CI) reports "HEAD" as the branch name
+	if info.Branch == "HEAD" {
+		info.Branch = ""
+	}
+	info.IsMain = info.Branch == "main" || info.Branch == "master"
+
+	return info
+}
+
+// SanitizeTag rewrites a value into a valid Docker tag (e.g. feature/x -> feature-x)
+func SanitizeTag(value string) string {
+	reg := regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+	tag := reg.ReplaceAllString(value, "-")
+	tag = strings.TrimLeft(tag, ".-")
+	if len(tag) > 128 {
+		tag = tag[:128]
+	}
+	return tag
+}
+
+// ValidateTag checks that a tag is accepted by Docker
+func ValidateTag(tag string) error {
+	if !validTag.MatchString(tag) {
+		return fmt.Errorf("invalid tag '%s': must match [A-Za-z0-9_][A-Za-z0-9_.-]{0,127}", tag)
+	}
+	return nil
+}
+
+// Render executes tag templates and returns the resulting tags, dropping empty results
+func Render(templates []string, data TemplateData) ([]string, error) {
+	var tags []string
+	for _, text := range templates {
+		tmpl, err := template.New("tag").Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag template '%s': %w", text, err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render tag template '%s': %w", text, err)
+		}
+
+		// Templates like {{if .IsMain}}latest{{end}} legitimately render to nothing
+		tag := strings.TrimSpace(buf.String())
+		if tag == "" {
+			continue
+		}
+		if err := ValidateTag(tag); err != nil {
+			return nil, fmt.Errorf("tag template '%s': %w", text, err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// templatesFor returns the tag templates that apply to a service (per-service list wins)
+func templatesFor(cfg *config.Config, service discovery.DiscoveredService) []string {
+	if len(service.TagTemplates) > 0 {
+		return service.TagTemplates
+	}
+	return cfg.Tags
+}
+
+// usesContentHash reports whether any template references the content hash
+func usesContentHash(templates []string) bool {
+	for _, text := range templates {
+		if strings.Contains(text, ".ContentHash") {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyTags resolves tag templates for every service and fills in DiscoveredService.Tags.
+// The service's primary tag always comes first so existing tagging keeps working,
+// followed by the computed service version when versioning is enabled.
+func ApplyTags(cfg *config.Config, services []discovery.DiscoveredService, info GitInfo) error {
+	semver := ""
+	if matches := semverTag.FindStringSubmatch(info.GitTag); matches != nil {
+		// Build metadata (1.2.3+build.5) is not valid in a Docker tag
+		semver = SanitizeTag(matches[1])
+	}
+
+	for i := range services {
+		service := &services[i]
+		tags := []string{service.Tag}
+
+		// A newly released service version is published as a tag
+		if service.Version != "" {
+			tags = append(tags, service.Version)
+		}
+
+		templates := templatesFor(cfg, *service)
+		if len(templates) > 0 {
+			data := TemplateData{
+				Service:  service.Name,
+				Image:    service.ImageName,
+				Tag:      service.Tag,
+				SHA:      info.SHA,
+				ShortSHA: info.ShortSHA,
+				Branch:   SanitizeTag(info.Branch),
+				IsMain:   info.IsMain,
+				GitTag:   SanitizeTag(info.GitTag),
+				Semver:   semver,
+				Version:  service.Version,
+				Date:     time.Now().UTC().Format("20060102"),
+			}
+
+			// Hashing walks the whole service tree, so only do it when asked for
+			if usesContentHash(templates) {
+				hash, err := cache.CalculateServiceHash(service.Path)
+				if err != nil {
+					return fmt.Errorf("service %s: %w", service.Path, err)
+				}
+				data.ContentHash = hash[:12]
+			}
+
+			rendered, err := Render(templates, data)
+			if err != nil {
+				return fmt.Errorf("service %s: %w", service.Path, err)
+			}
+			tags = append(tags, rendered...)
+		}
+
+		service.Tags = dedupe(tags)
+	}
+
+	return nil
+}
+
+// dedupe removes duplicate tags while keeping their order
+func dedupe(tags []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, tag := range tags {
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		unique = append(unique, tag)
+	}
+	return unique
+}
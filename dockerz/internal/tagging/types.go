@@ -0,0 +1,26 @@
+package tagging
+
+// GitInfo holds the repository facts exposed to tag templates
+type GitInfo struct {
+	SHA      string
+	ShortSHA string
+	Branch   string
+	IsMain   bool
+	GitTag   string
+}
+
+// TemplateData is the data passed to each tag template
+type TemplateData struct {
+	Service     string
+	Image       string
+	Tag         string
+	SHA         string
+	ShortSHA    string
+	Branch      string
+	IsMain      bool
+	GitTag      string
+	Semver      string
+	Version     string
+	ContentHash string
+	Date        string
+}
@@ -0,0 +1,210 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServiceName is the service.name resource attribute of exported spans
+const ServiceName = "dockerz"
+
+// OTLP status code and span kind values
+const (
+	statusCodeError  = 2
+	spanKindInternal = 1
+)
+
+// Export sends the spans of the current run to an OTLP/HTTP endpoint and/or writes them to a file,
+// both in the OTLP JSON encoding. Spans still running are ended first. Nothing happens when no run is traced.
+func Export(endpoint string, headers map[string]string, file string) error {
+	activeMu.Lock()
+	t := active
+	activeMu.Unlock()
+	if t == nil || (endpoint == "" && file == "") {
+		return nil
+	}
+
+	body, err := json.Marshal(t.payload())
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	if file != "" {
+		if err := writeFile(file, body); err != nil {
+			return err
+		}
+	}
+	if endpoint != "" {
+		if err := post(endpoint, headers, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TracesURL returns the OTLP/HTTP traces URL for an endpoint, appending /v1/traces to a base URL
+func TracesURL(endpoint string) string {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if strings.HasSuffix(endpoint, "/v1/traces") {
+		return endpoint
+	}
+	return endpoint + "/v1/traces"
+}
+
+// post sends an OTLP JSON export request
+func post(endpoint string, headers map[string]string, body []byte) error {
+	request, err := http.NewRequest(http.MethodPost, TracesURL(endpoint), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid tracing endpoint: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("failed to export spans: %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// writeFile writes the export request atomically so viewers never read a partial file
+func writeFile(path string, body []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create trace file directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".dockerz-trace-*")
+	if err != nil {
+		return fmt.Errorf("failed to write trace file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(body, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write trace file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write trace file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write trace file: %w", err)
+	}
+	return nil
+}
+
+// OTLP JSON encoding of ExportTraceServiceRequest; IDs are hex strings and
+// 64-bit integers are decimal strings as the protobuf JSON mapping requires
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus     `json:"status,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+)
+
+// payload converts the recorded spans into an export request
+func (t *tracer) payload() otlpRequest {
+	t.mu.Lock()
+	spans := append([]*Span(nil), t.spans...)
+	t.mu.Unlock()
+
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.End()
+		span.mu.Lock()
+		otlp := otlpSpan{
+			TraceID:           hex.EncodeToString(span.traceID[:]),
+			SpanID:            hex.EncodeToString(span.spanID[:]),
+			Name:              span.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        encodeAttributes(span.attributes),
+		}
+		if span.parentID != [8]byte{} {
+			otlp.ParentSpanID = hex.EncodeToString(span.parentID[:])
+		}
+		if span.err != "" {
+			otlp.Status = &otlpStatus{Code: statusCodeError, Message: span.err}
+		}
+		span.mu.Unlock()
+		encoded = append(encoded, otlp)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttributes([]Attribute{String("service.name", ServiceName)})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: ServiceName}, Spans: encoded}},
+	}}}
+}
+
+// encodeAttributes converts attributes into OTLP AnyValue form
+func encodeAttributes(attributes []Attribute) []otlpAttribute {
+	encoded := make([]otlpAttribute, 0, len(attributes))
+	for _, attribute := range attributes {
+		var value map[string]interface{}
+		switch v := attribute.Value.(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		encoded = append(encoded, otlpAttribute{Key: attribute.Key, Value: value})
+	}
+	return encoded
+}
@@ -0,0 +1,159 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	activeMu sync.Mutex
+	active   *tracer
+)
+
+// Begin starts recording spans and returns the root span of the run. When TRACEPARENT holds a
+// valid W3C trace context the root span joins that trace as a child of the CI pipeline span.
+func Begin(name string, attributes ...Attribute) *Span {
+	t := &tracer{flags: "01"}
+	if traceID, spanID, flags, ok := ParseTraceParent(os.Getenv(EnvTraceParent)); ok {
+		t.traceID, t.remote, t.flags = traceID, spanID, flags
+	} else {
+		rand.Read(t.traceID[:])
+	}
+
+	activeMu.Lock()
+	active = t
+	activeMu.Unlock()
+
+	t.root = t.start(name, t.remote, attributes)
+	return t.root
+}
+
+// Start starts a span under parent; a nil parent attaches it to the root span of the run.
+// It returns nil when no run is being traced.
+func Start(name string, parent *Span, attributes ...Attribute) *Span {
+	t := parent.tracerOrActive()
+	if t == nil {
+		return nil
+	}
+	parentID := t.remote
+	if parent != nil {
+		parentID = parent.spanID
+	} else if t.root != nil {
+		parentID = t.root.spanID
+	}
+	return t.start(name, parentID, attributes)
+}
+
+func (t *tracer) start(name string, parentID [8]byte, attributes []Attribute) *Span {
+	span := &Span{
+		tracer:     t,
+		traceID:    t.traceID,
+		parentID:   parentID,
+		name:       name,
+		start:      time.Now(),
+		attributes: append([]Attribute(nil), attributes...),
+	}
+	rand.Read(span.spanID[:])
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return span
+}
+
+func (s *Span) tracerOrActive() *tracer {
+	if s != nil {
+		return s.tracer
+	}
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	return active
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attributes = append(s.attributes, attributes...)
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed with the error's message; a nil error is ignored
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err.Error()
+	s.mu.Unlock()
+}
+
+// End records the end time of the span; later calls are ignored
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.end.IsZero() {
+		s.end = time.Now()
+	}
+	s.mu.Unlock()
+}
+
+// TraceParent returns the W3C traceparent header value identifying the span, used to pass the
+// trace on to child processes; it is empty for a nil span
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]), s.tracer.flags)
+}
+
+// ParseTraceParent parses a W3C traceparent value ("00-<trace-id>-<parent-id>-<flags>")
+func ParseTraceParent(value string) (traceID [16]byte, spanID [8]byte, flags string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return traceID, spanID, "", false
+	}
+	// Version 00 has exactly four fields; later versions may append more
+	if parts[0] == "00" && len(parts) != 4 {
+		return traceID, spanID, "", false
+	}
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || traceID == [16]byte{} {
+		return traceID, spanID, "", false
+	}
+	if _, err := hex.Decode(spanID[:], []byte(parts[2])); err != nil || spanID == [8]byte{} {
+		return traceID, spanID, "", false
+	}
+	if _, err := hex.DecodeString(parts[3]); err != nil {
+		return traceID, spanID, "", false
+	}
+	return traceID, spanID, strings.ToLower(parts[3]), true
+}
+
+// String returns a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute
+func Int(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Float returns a floating point attribute
+func Float(key string, value float64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"sync"
+	"time"
+)
+
+// EnvTraceParent is the W3C trace context variable CI systems use to hand their pipeline trace to child processes
+const EnvTraceParent = "TRACEPARENT"
+
+// Attribute is a key/value pair attached to a span; values are strings, ints, floats or bools
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span is a timed operation of a build run. A nil *Span is valid and records nothing,
+// so callers never need to check whether tracing is enabled.
+type Span struct {
+	tracer     *tracer
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	name       string
+	start      time.Time
+	mu         sync.Mutex
+	end        time.Time
+	attributes []Attribute
+	err        string
+}
+
+// tracer collects the spans of one run until they are exported
+type tracer struct {
+	mu      sync.Mutex
+	traceID [16]byte
+	// remote is the span ID from TRACEPARENT, the parent of the root span
+	remote [8]byte
+	flags  string
+	root   *Span
+	spans  []*Span
+}
@@ -0,0 +1,79 @@
+package validate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// String formats the issue as file:line:column: severity: key: message
+func (i Issue) String() string {
+	location := i.File
+	if i.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, i.Line)
+		if i.Column > 0 {
+			location = fmt.Sprintf("%s:%d", location, i.Column)
+		}
+	}
+
+	message := i.Message
+	if i.Key != "" {
+		message = i.Key + ": " + message
+	}
+	if location == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, message)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, i.Severity, message)
+}
+
+// Errors returns the number of error issues
+func (r *Report) Errors() int {
+	return r.count(SeverityError)
+}
+
+// Warnings returns the number of warning issues
+func (r *Report) Warnings() int {
+	return r.count(SeverityWarning)
+}
+
+// HasErrors reports whether the configuration is invalid
+func (r *Report) HasErrors() bool {
+	return r.Errors() > 0
+}
+
+// Print writes every issue followed by a summary line
+func (r *Report) Print(w io.Writer) {
+	for _, issue := range r.Issues {
+		fmt.Fprintln(w, issue)
+	}
+	if len(r.Issues) == 0 {
+		fmt.Fprintln(w, "✓ Configuration is valid")
+		return
+	}
+	fmt.Fprintf(w, "%d error(s), %d warning(s)\n", r.Errors(), r.Warnings())
+}
+
+// count returns the number of issues with a severity
+func (r *Report) count(severity string) int {
+	count := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// sort orders issues by file and position
+func (r *Report) sort() {
+	sort.SliceStable(r.Issues, func(a, b int) bool {
+		x, y := r.Issues[a], r.Issues[b]
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		if x.Line != y.Line {
+			return x.Line < y.Line
+		}
+		return x.Column < y.Column
+	})
+}
@@ -0,0 +1,247 @@
+package validate
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/config"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// descriptions documents config keys in the JSON Schema; list items use the list key (services.name)
+var descriptions = map[string]string{
+	"include":                      "Config files merged underneath this one, relative to this file",
+	"profiles":                     "Named overlays selected with --profile or DOCKERZ_PROFILE",
+	"services_dir":                 "Directories to scan for services (list or comma-separated string)",
+	"compose_files":                "docker-compose files whose build sections are imported as services",
+	"discovery":                    "Service discovery: scanned paths, Dockerfile patterns and image naming",
+	"discovery.dockerfiles":        "Dockerfile name patterns; each match is a separate image (default: Dockerfile)",
+	"discovery.ignore":             "File name patterns never built, in addition to *.bak, *.backup, *.orig, *.old, *.swp and *~",
+	"discovery.include":            "Path globs scanned even when built-in rules or .gitignore skip them, e.g. .docker",
+	"discovery.exclude":            "Path globs never scanned, e.g. tests/** or **/fixtures",
+	"discovery.gitignore":          "Skip paths ignored by .gitignore files",
+	"discovery.naming":             "How image names are derived from service paths",
+	"discovery.naming.strategy":    "basename (backend/api -> api), path (backend/api -> backend-api) or template",
+	"discovery.naming.separator":   "Separator between path segments for the path strategy: - or / (nested repositories)",
+	"discovery.naming.template":    "Go template over .Path, .Base, .Parent, .Segments, .Variant and .Name, e.g. \"{{.Parent}}-{{.Base}}\"",
+	"discovery.naming.trim_prefix": "Leading path removed before naming, e.g. services",
+	"project":                      "GCP project ID for Google Artifact Registry",
+	"gar":                          "Google Artifact Registry repository name",
+	"region":                       "GCP region of the Artifact Registry repository",
+	"global_tag":                   "Tag applied to every image (defaults to the git commit ID)",
+	"platforms":                    "Target platforms passed to docker build --platform, e.g. linux/amd64",
+	"tags":                         "Additional tag templates, e.g. \"{{.Branch}}-{{.ShortSHA}}\"",
+	"versioning":                   "Per-service semantic versioning from conventional commits",
+	"versioning.enabled":           "Compute per-service versions from conventional commits",
+	"versioning.tag_prefix":        "Git tag prefix; {service} is replaced with the service name",
+	"versioning.create_git_tags":   "Create the version git tag after a successful push",
+	"versioning.push_git_tags":     "Push created version tags to origin",
+	"hooks":                        "Lifecycle hooks run around every service build",
+	"max_processes":                "Maximum parallel builds (default 4, or the cgroup CPU quota rounded up if lower)",
+	"enable_resource_monitoring":   "Adapt build parallelism to CPU, memory and Docker data-root disk usage",
+	"max_cpu_threshold":            "CPU usage percentage above which build parallelism is halved",
+	"max_memory_threshold":         "Memory usage percentage above which build parallelism is halved",
+	"max_disk_threshold":           "Usage percentage of the Docker data-root filesystem above which build parallelism is halved",
+	"use_gar":                      "Name images for Google Artifact Registry (requires project, gar and region)",
+	"push_to_gar":                  "Push images to Google Artifact Registry after building (requires use_gar)",
+	"services":                     "Explicit service definitions (leave empty for auto-discovery)",
+	"services.name":                "Path to the service directory or Dockerfile, relative to the project root",
+	"services.image_name":          "Custom image name (defaults to the directory name)",
+	"services.tag":                 "Service-specific tag (overrides global_tag)",
+	"services.tags":                "Service-specific tag templates (replaces the global tags list)",
+	"services.hooks":               "Service-specific lifecycle hooks, run after the global hooks",
+	"services.context":             "Build context, relative to the service directory (default: the service directory)",
+	"services.build_args":          "Docker build arguments passed with --build-arg",
+	"services.depends_on":          "Services (paths or names) built before this one; their rebuilds trigger this one",
+	"services.platforms":           "Service-specific target platforms (replaces the global platforms list)",
+	"services.lint_ignore":         "Dockerfile lint rules not reported for this service",
+	"services.watch":               "Extra paths, relative to the service directory, whose changes trigger a rebuild",
+	"smart":                        "Enable smart build orchestration",
+	"git_track":                    "Enable git change detection",
+	"git_track_depth":              "Number of commits to check for changes (0 = full history)",
+	"cache":                        "Enable multi-level build caching",
+	"force":                        "Force rebuild of all services",
+	"input_changed_services":       "Input .txt file listing changed services",
+	"output_changed_services":      "Output .txt file for detected changed services",
+	"enable_buildkit":              "Build with BuildKit",
+	"metrics":                      "Build metrics export for charting CI build performance",
+	"metrics.textfile":             "Write metrics in OpenMetrics format to this file (.prom, for the node_exporter textfile collector)",
+	"metrics.pushgateway":          "Push metrics to this Pushgateway-compatible URL",
+	"metrics.job":                  "Pushgateway job name (default dockerz)",
+	"metrics.labels":               "Labels added to every metric, e.g. branch or pipeline",
+	"tracing":                      "OpenTelemetry tracing of config loading, discovery, git analysis, builds and pushes",
+	"tracing.endpoint":             "OTLP/HTTP collector URL; spans are sent to <endpoint>/v1/traces",
+	"tracing.file":                 "Write spans as OTLP JSON to this file for offline viewing",
+	"tracing.headers":              "HTTP headers sent with every export, e.g. authentication",
+	"ci":                           "CI system integration: step outputs, summaries, annotations and collapsible log sections",
+	"ci.provider":                  "auto (detect GitHub Actions or GitLab CI), github, gitlab or none",
+	"ci.dotenv":                    "GitLab dotenv report file (default dockerz.env)",
+	"lint":                         "Dockerfile linting with 'dockerz lint' and before builds",
+	"lint.gate":                    "Lowest severity that stops 'dockerz build' before building: error, warning, info or off (default)",
+	"lint.ignore":                  "Lint rules not reported for any service",
+	"lint.severity":                "Rule severity overrides, e.g. missing-healthcheck: off",
+	"base_images":                  "Base image digest tracking: a moved base image digest rebuilds the service",
+	"base_images.track":            "Resolve base image digests through the registry API on every build",
+	"base_images.ignore":           "Base image patterns never tracked, e.g. gcr.io/my-project/*",
+	"base_images.insecure":         "Resolve base images over plain HTTP (localhost registries always use it)",
+	"lock":                         "Reproducibility lock file recording each service's inputs and pushed digest",
+	"lock.enabled":                 "Update the lock file after every successful build (--lock)",
+	"lock.file":                    "Lock file path (default dockerz.lock)",
+	"signing":                      "Cosign-compatible signing of pushed image digests with a local key",
+	"signing.key":                  "PEM private key (ECDSA P-256) pushed digests are signed with; empty disables signing",
+	"signing.provenance":           "Attach a signed in-toto SLSA provenance attestation to every pushed image",
+	"signing.builder_id":           "Builder identity recorded in provenance (default urn:dockerz:builder:<hostname>)",
+	"signing.insecure":             "Push signatures and attestations over plain HTTP (localhost registries always use it)",
+}
+
+// enums lists the allowed values of string keys
+var enums = map[string][]interface{}{
+	"discovery.naming.strategy":  {"basename", "path", "template", nil},
+	"discovery.naming.separator": {"-", "/", nil},
+	"lint.gate":                  {"error", "warning", "info", "off", nil},
+}
+
+// hookDescriptions documents the fields shared by every hook list
+var hookDescriptions = map[string]string{
+	"name":    "Hook name used in logs",
+	"command": "Shell command, run from the service directory",
+	"timeout": "Maximum run time, e.g. 30s or 2m (default 5m)",
+	"policy":  "fail (a failing hook fails the build) or ignore (log and continue)",
+}
+
+// yamlFields returns the struct fields of t keyed by their YAML name
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// Schema returns the JSON Schema describing build.yaml
+func Schema() map[string]interface{} {
+	root := objectSchema(reflect.TypeOf(config.Config{}), "")
+	properties := root["properties"].(map[string]interface{})
+
+	profile := objectSchema(reflect.TypeOf(config.Config{}), "")
+	properties["include"] = describe(map[string]interface{}{
+		"type":  []string{"string", "array", "null"},
+		"items": map[string]interface{}{"type": "string"},
+	}, "include")
+	properties["profiles"] = describe(map[string]interface{}{
+		"type":                 []string{"object", "null"},
+		"additionalProperties": profile,
+	}, "profiles")
+
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "Dockerz build.yaml"
+	root["$defs"] = map[string]interface{}{"hook": hookSchema()}
+	return root
+}
+
+// SchemaJSON returns the JSON Schema as indented JSON
+func SchemaJSON() ([]byte, error) {
+	data, err := json.MarshalIndent(Schema(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// objectSchema builds the schema of a config struct
+func objectSchema(t reflect.Type, path string) map[string]interface{} {
+	properties := make(map[string]interface{})
+	fields := yamlFields(t)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		key := name
+		if path != "" {
+			key = path + "." + name
+		}
+		properties[name] = describe(typeSchema(fields[name].Type, key), key)
+	}
+
+	return map[string]interface{}{
+		"type":                 []string{"object", "null"},
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// typeSchema builds the schema of a single config value
+func typeSchema(t reflect.Type, path string) map[string]interface{} {
+	if t == durationType {
+		return map[string]interface{}{
+			"type":    []string{"string", "integer", "null"},
+			"pattern": `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`,
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": []string{"boolean", "null"}}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": []string{"integer", "null"}}
+	case reflect.Float64:
+		return map[string]interface{}{"type": []string{"number", "null"}}
+	case reflect.String:
+		schema := map[string]interface{}{"type": []string{"string", "null"}}
+		if strings.HasSuffix(path, ".policy") {
+			schema["enum"] = []interface{}{"fail", "ignore", nil}
+		}
+		if enum, ok := enums[path]; ok {
+			schema["enum"] = enum
+		}
+		return schema
+	case reflect.Slice:
+		types := []string{"array", "null"}
+		if path == "services_dir" {
+			types = []string{"string", "array", "null"}
+		}
+		return map[string]interface{}{"type": types, "items": typeSchema(t.Elem(), path)}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 []string{"object", "null"},
+			"additionalProperties": map[string]interface{}{"type": []string{"string", "number", "boolean"}},
+		}
+	case reflect.Struct:
+		if t == reflect.TypeOf(config.Hook{}) {
+			return map[string]interface{}{"$ref": "#/$defs/hook"}
+		}
+		return objectSchema(t, path)
+	}
+	return map[string]interface{}{}
+}
+
+// hookSchema builds the schema of a hook, which is shared by every event and service
+func hookSchema() map[string]interface{} {
+	schema := objectSchema(reflect.TypeOf(config.Hook{}), "hook")
+	schema["type"] = "object"
+	schema["required"] = []string{"command"}
+	for name, property := range schema["properties"].(map[string]interface{}) {
+		property.(map[string]interface{})["description"] = hookDescriptions[name]
+	}
+	return schema
+}
+
+// describe attaches the description of a key, if there is one
+func describe(schema map[string]interface{}, key string) map[string]interface{} {
+	if description, ok := descriptions[key]; ok {
+		schema["description"] = description
+	}
+	return schema
+}
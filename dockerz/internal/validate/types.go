@@ -0,0 +1,29 @@
+package validate
+
+// Issue severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Position is a location in a config file
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// Issue is a single validation finding
+type Issue struct {
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Report collects the issues found in a configuration
+type Report struct {
+	Issues []Issue `json:"issues"`
+}
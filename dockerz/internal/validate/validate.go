@@ -0,0 +1,661 @@
+package validate
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/addy-47/dockerz/internal/ci"
+	"github.com/addy-47/dockerz/internal/config"
+	"github.com/addy-47/dockerz/internal/discovery"
+	"github.com/addy-47/dockerz/internal/lint"
+	"gopkg.in/yaml.v3"
+)
+
+// regionPattern matches GCP region names such as us-central1
+var regionPattern = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+$`)
+
+// metricLabelPattern matches Prometheus label names
+var metricLabelPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// yamlLinePattern extracts the line number from yaml.v3 syntax errors
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// validator accumulates issues and remembers where each config key was defined
+type validator struct {
+	report    *Report
+	positions map[string]Position
+	profile   string
+	profiles  []profileNode
+	seen      map[string]bool
+}
+
+// profileNode is a profile definition found while walking a config file
+type profileNode struct {
+	file string
+	name string
+	node *yaml.Node
+}
+
+// Validate checks a config file, its includes and the selected profile: unknown keys and type errors
+// with file:line:column, then the resolved configuration (service paths, image names, GAR settings).
+// When cfg is nil the configuration is read from configPath; callers that apply CLI overrides pass
+// their final configuration instead.
+func Validate(configPath, profile string, cfg *config.Config) *Report {
+	if profile == "" {
+		profile = os.Getenv(config.ProfileEnv)
+	}
+	v := &validator{
+		report:    &Report{},
+		positions: make(map[string]Position),
+		profile:   profile,
+		seen:      make(map[string]bool),
+	}
+
+	v.checkFile(configPath)
+
+	// Profiles override everything in the files, so their positions are recorded last
+	selected := false
+	for _, p := range v.profiles {
+		record := p.name == profile
+		selected = selected || record
+		v.walk(p.file, p.node, reflect.TypeOf(config.Config{}), "", "profiles."+p.name+".", record)
+	}
+	if profile != "" && !selected {
+		v.add(SeverityError, Position{File: configPath}, "profiles", fmt.Sprintf("profile '%s' not found", profile))
+	}
+
+	if cfg == nil {
+		if v.report.HasErrors() {
+			v.report.sort()
+			return v.report
+		}
+		loaded, err := config.ReadConfig(configPath, profile)
+		if err != nil {
+			v.add(SeverityError, Position{File: configPath}, "", err.Error())
+			return v.report
+		}
+		cfg = loaded
+	}
+
+	v.checkConfig(cfg)
+	v.report.sort()
+	return v.report
+}
+
+// checkFile validates the structure of one config file and the files it includes
+func (v *validator) checkFile(path string) {
+	absPath, err := filepath.Abs(path)
+	if err == nil {
+		if v.seen[absPath] {
+			v.add(SeverityError, Position{File: path}, "include", "include cycle detected")
+			return
+		}
+		v.seen[absPath] = true
+		defer delete(v.seen, absPath)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		v.add(SeverityError, Position{File: path}, "", fmt.Sprintf("failed to read config file: %v", err))
+		return
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		pos := Position{File: path}
+		if match := yamlLinePattern.FindStringSubmatch(err.Error()); match != nil {
+			pos.Line, _ = strconv.Atoi(match[1])
+		}
+		v.add(SeverityError, pos, "", strings.TrimPrefix(err.Error(), "yaml: "))
+		return
+	}
+	if len(doc.Content) == 0 {
+		return
+	}
+
+	root := resolve(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		if root.Tag != "!!null" {
+			v.add(SeverityError, v.at(path, root), "", "config file must be a mapping of keys to values")
+		}
+		return
+	}
+
+	// Included files are overridden by the including file, so they are walked first
+	var body []*yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], resolve(root.Content[i+1])
+		switch key.Value {
+		case "include":
+			v.checkIncludes(path, value)
+		case "profiles":
+			v.collectProfiles(path, value)
+		default:
+			body = append(body, key, root.Content[i+1])
+		}
+	}
+
+	v.walk(path, &yaml.Node{Kind: yaml.MappingNode, Content: body}, reflect.TypeOf(config.Config{}), "", "", true)
+}
+
+// checkIncludes validates the include list and walks every included file
+func (v *validator) checkIncludes(path string, node *yaml.Node) {
+	var includes []*yaml.Node
+	switch {
+	case node.Kind == yaml.ScalarNode && node.Tag == "!!str":
+		includes = []*yaml.Node{node}
+	case node.Kind == yaml.SequenceNode:
+		includes = node.Content
+	case node.Tag == "!!null":
+		return
+	default:
+		v.add(SeverityError, v.at(path, node), "include", "expected a file path or list of file paths")
+		return
+	}
+
+	for _, include := range includes {
+		include = resolve(include)
+		if include.Kind != yaml.ScalarNode || include.Tag != "!!str" {
+			v.add(SeverityError, v.at(path, include), "include", "expected a file path")
+			continue
+		}
+		includePath := config.Interpolate(include.Value)
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(path), includePath)
+		}
+		if _, err := os.Stat(includePath); err != nil {
+			v.add(SeverityError, v.at(path, include), "include", fmt.Sprintf("included file %s does not exist", includePath))
+			continue
+		}
+		v.checkFile(includePath)
+	}
+}
+
+// collectProfiles remembers profile definitions so they can be walked after the base config
+func (v *validator) collectProfiles(path string, node *yaml.Node) {
+	if node.Tag == "!!null" {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		v.add(SeverityError, v.at(path, node), "profiles", "expected a mapping of profile names to settings")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, value := node.Content[i].Value, resolve(node.Content[i+1])
+		if value.Tag == "!!null" {
+			continue
+		}
+		if value.Kind != yaml.MappingNode {
+			v.add(SeverityError, v.at(path, value), "profiles."+name, "a profile must be a mapping of config keys")
+			continue
+		}
+		v.profiles = append(v.profiles, profileNode{file: path, name: name, node: value})
+	}
+}
+
+// walk checks a YAML node against the config type it decodes into. key is the config key used
+// to look up positions later; prefix is prepended in messages (e.g. "profiles.prod.").
+// Positions are only recorded when record is set.
+func (v *validator) walk(file string, node *yaml.Node, t reflect.Type, key, prefix string, record bool) {
+	node = resolve(node)
+	if record && key != "" {
+		v.positions[key] = v.at(file, node)
+	}
+	if node.Tag == "!!null" {
+		return
+	}
+	label := prefix + key
+
+	switch {
+	case t == durationType:
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!str" && !interpolated(node.Value) {
+			if _, err := time.ParseDuration(node.Value); err != nil {
+				v.add(SeverityError, v.at(file, node), label, fmt.Sprintf("invalid duration '%s' (use e.g. 30s, 2m or 1h)", node.Value))
+			}
+			return
+		}
+		v.expectScalar(file, node, label, "a duration", "!!str", "!!int")
+
+	case t.Kind() == reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			v.typeError(file, node, label, "a mapping")
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			if keyNode.Value == "<<" {
+				continue
+			}
+			childKey := keyNode.Value
+			if key != "" {
+				childKey = key + "." + keyNode.Value
+			}
+			field, ok := fields[keyNode.Value]
+			if !ok {
+				message := "unknown key"
+				if suggestion := closest(keyNode.Value, fields); suggestion != "" {
+					message = fmt.Sprintf("unknown key (did you mean '%s'?)", suggestion)
+				}
+				v.add(SeverityError, v.at(file, keyNode), prefix+childKey, message)
+				continue
+			}
+			v.walk(file, node.Content[i+1], field.Type, childKey, prefix, record)
+			if record {
+				// Point at the key rather than the value for whole-field issues
+				v.positions[childKey] = v.at(file, keyNode)
+			}
+		}
+
+	case t.Kind() == reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			// services_dir also accepts a comma-separated string
+			if key == "services_dir" && node.Kind == yaml.ScalarNode {
+				return
+			}
+			v.typeError(file, node, label, "a list")
+			return
+		}
+		for i, item := range node.Content {
+			v.walk(file, item, t.Elem(), fmt.Sprintf("%s[%d]", key, i), prefix, record)
+		}
+
+	case t.Kind() == reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.typeError(file, node, label, "a mapping")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if value := resolve(node.Content[i+1]); value.Kind != yaml.ScalarNode {
+				v.typeError(file, value, label+"."+node.Content[i].Value, "a string")
+			}
+		}
+
+	case t.Kind() == reflect.Bool:
+		v.expectScalar(file, node, label, "a boolean (true or false)", "!!bool")
+	case t.Kind() == reflect.Int || t.Kind() == reflect.Int64:
+		v.expectScalar(file, node, label, "an integer", "!!int")
+	case t.Kind() == reflect.Float64:
+		v.expectScalar(file, node, label, "a number", "!!int", "!!float")
+	case t.Kind() == reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			v.typeError(file, node, label, "a string")
+		}
+	}
+}
+
+// expectScalar reports a type error unless the node is a scalar with one of the given tags.
+// Strings containing ${VAR} are accepted because their type is only known after interpolation.
+func (v *validator) expectScalar(file string, node *yaml.Node, label, expected string, tags ...string) {
+	if node.Kind == yaml.ScalarNode {
+		if node.Tag == "!!str" && interpolated(node.Value) {
+			return
+		}
+		for _, tag := range tags {
+			if node.Tag == tag {
+				return
+			}
+		}
+	}
+	v.typeError(file, node, label, expected)
+}
+
+// typeError reports a value of the wrong type
+func (v *validator) typeError(file string, node *yaml.Node, label, expected string) {
+	got := map[yaml.Kind]string{yaml.MappingNode: "a mapping", yaml.SequenceNode: "a list"}[node.Kind]
+	if got == "" {
+		got = fmt.Sprintf("'%s'", node.Value)
+	}
+	v.add(SeverityError, v.at(file, node), label, fmt.Sprintf("expected %s, got %s", expected, got))
+}
+
+// checkConfig validates the resolved configuration
+func (v *validator) checkConfig(cfg *config.Config) {
+	for i, dir := range cfg.ServicesDir {
+		if dir == "" {
+			continue
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			v.addKey(SeverityError, fmt.Sprintf("services_dir[%d]", i), fmt.Sprintf("services directory '%s' does not exist", dir))
+		}
+	}
+
+	for key, patterns := range map[string][]string{"discovery.dockerfiles": cfg.Discovery.Dockerfiles, "discovery.ignore": cfg.Discovery.Ignore} {
+		for i, pattern := range patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				v.addKey(SeverityError, fmt.Sprintf("%s[%d]", key, i), fmt.Sprintf("invalid pattern '%s': %v", pattern, err))
+			}
+		}
+	}
+
+	for key, patterns := range map[string][]string{"discovery.include": cfg.Discovery.Include, "discovery.exclude": cfg.Discovery.Exclude} {
+		for i, pattern := range patterns {
+			if err := discovery.ValidateGlob(pattern); err != nil {
+				v.addKey(SeverityError, fmt.Sprintf("%s[%d]", key, i), err.Error())
+			}
+		}
+	}
+
+	for i, file := range cfg.ComposeFiles {
+		key := fmt.Sprintf("compose_files[%d]", i)
+		if _, err := os.Stat(file); err != nil {
+			v.addKey(SeverityError, key, fmt.Sprintf("compose file '%s' does not exist", file))
+			continue
+		}
+		_, errs := discovery.LoadComposeFile(file, "latest")
+		for _, err := range errs {
+			v.addKey(SeverityError, key, err.Error())
+		}
+	}
+
+	namer, err := discovery.NewImageNamer(cfg.Discovery.Naming)
+	if err != nil {
+		v.addKey(SeverityError, "discovery.naming", err.Error())
+	}
+
+	matcher := discovery.NewDockerfileMatcher(cfg)
+	imageOwners := make(map[string]int)
+	paths := make(map[string]int)
+	for i, service := range cfg.Services {
+		key := fmt.Sprintf("services[%d]", i)
+		if service.Name == "" {
+			v.addKey(SeverityError, key, "service name (path) is required")
+			continue
+		}
+
+		// A service name is a directory or, for Dockerfile variants, a Dockerfile path
+		isDockerfile := false
+		if info, err := os.Stat(service.Name); err != nil {
+			v.addKey(SeverityError, key+".name", fmt.Sprintf("service path '%s' does not exist", service.Name))
+		} else if _, _, err := matcher.Resolve(service.Name); err != nil {
+			v.addKey(SeverityError, key+".name", err.Error())
+		} else {
+			isDockerfile = !info.IsDir()
+		}
+
+		if previous, exists := paths[service.Name]; exists {
+			v.addKey(SeverityWarning, key+".name", fmt.Sprintf("service '%s' is already defined at services[%d]", service.Name, previous))
+		} else {
+			paths[service.Name] = i
+		}
+
+		imageName := service.ImageName
+		if imageName != "" && !discovery.ImageNamePattern.MatchString(imageName) {
+			v.addKey(SeverityError, key+".image_name", fmt.Sprintf("invalid image name '%s': use lowercase letters, digits and single '.', '_' or '-' separators", imageName))
+		}
+		if imageName == "" {
+			named := discovery.DiscoveredService{Path: service.Name, Dockerfile: discovery.DefaultDockerfile}
+			if isDockerfile {
+				named.Path, named.Dockerfile = filepath.Dir(service.Name), filepath.Base(service.Name)
+			}
+			named.Name = discovery.ServiceName(named.Path, named.Dockerfile)
+			imageName = discovery.NormalizeImageName(named.Name)
+			if namer != nil {
+				if derived, err := namer.ImageName(named); err == nil {
+					imageName = derived
+				}
+			}
+		}
+		imageName = discovery.NormalizeImagePath(imageName)
+		if previous, exists := imageOwners[imageName]; exists {
+			field := key + ".name"
+			if service.ImageName != "" {
+				field = key + ".image_name"
+			}
+			v.addKey(SeverityError, field, fmt.Sprintf("duplicate image name '%s' (also used by %s)", imageName, cfg.Services[previous].Name))
+		} else {
+			imageOwners[imageName] = i
+		}
+
+		// Per-directory manifests must parse, and build.yaml silently overriding them is worth a warning
+		var manifest *discovery.ServiceManifest
+		var manifestPath string
+		var err error
+		if !isDockerfile {
+			manifest, manifestPath, err = discovery.LoadManifest(service.Name)
+		}
+		if err != nil {
+			v.add(SeverityError, Position{File: manifestPath}, "", err.Error())
+		} else if manifest != nil {
+			_, conflicts := discovery.MergeServiceSettings(manifest, &cfg.Services[i])
+			for _, conflict := range conflicts {
+				v.addKey(SeverityWarning, key, fmt.Sprintf("overrides %s: %s", manifestPath, conflict))
+			}
+		}
+
+		v.checkTemplates(key+".tags", service.Tags)
+		v.checkHooks(key+".hooks", service.Hooks)
+		v.checkLintRules(key+".lint_ignore", service.LintIgnore)
+	}
+
+	v.checkTemplates("tags", cfg.Tags)
+	v.checkHooks("hooks", cfg.Hooks)
+
+	// Google Artifact Registry settings
+	if cfg.PushToGAR && !cfg.UseGAR {
+		v.addKey(SeverityError, "push_to_gar", "push_to_gar requires use_gar: true")
+	}
+	if cfg.UseGAR {
+		for _, field := range []struct{ key, value string }{{"project", cfg.Project}, {"gar", cfg.GAR}, {"region", cfg.Region}} {
+			if field.value == "" {
+				v.addKey(SeverityError, "use_gar", fmt.Sprintf("use_gar requires %s to be set", field.key))
+			}
+		}
+		if cfg.Region != "" && !regionPattern.MatchString(cfg.Region) {
+			v.addKey(SeverityWarning, "region", fmt.Sprintf("'%s' does not look like a GCP region (e.g. us-central1)", cfg.Region))
+		}
+	}
+	if cfg.Versioning.CreateGitTags && !(cfg.UseGAR && cfg.PushToGAR) {
+		v.addKey(SeverityWarning, "versioning.create_git_tags", "version tags are only created after a push; enable use_gar and push_to_gar")
+	}
+	if cfg.Versioning.PushGitTags && !cfg.Versioning.CreateGitTags {
+		v.addKey(SeverityWarning, "versioning.push_git_tags", "push_git_tags has no effect without create_git_tags")
+	}
+
+	// Numeric limits
+	if cfg.MaxProcesses < 0 {
+		v.addKey(SeverityError, "max_processes", "max_processes must not be negative")
+	}
+	if cfg.GitTrackDepth < 0 {
+		v.addKey(SeverityError, "git_track_depth", "git_track_depth must not be negative")
+	}
+	for _, threshold := range []struct {
+		key   string
+		value float64
+	}{{"max_cpu_threshold", cfg.MaxCPUThreshold}, {"max_memory_threshold", cfg.MaxMemoryThreshold}, {"max_disk_threshold", cfg.MaxDiskThreshold}} {
+		if threshold.value < 0 || threshold.value > 100 {
+			v.addKey(SeverityError, threshold.key, fmt.Sprintf("%s must be a percentage between 0 and 100", threshold.key))
+		}
+	}
+
+	// Changed services files
+	if err := config.ValidateTxtFile(cfg.InputChangedServices); err != nil {
+		v.addKey(SeverityError, "input_changed_services", err.Error())
+	} else if cfg.InputChangedServices != "" {
+		if _, err := os.Stat(cfg.InputChangedServices); err != nil {
+			v.addKey(SeverityWarning, "input_changed_services", fmt.Sprintf("input file '%s' does not exist", cfg.InputChangedServices))
+		}
+	}
+	if err := config.ValidateTxtFile(cfg.OutputChangedServices); err != nil {
+		v.addKey(SeverityError, "output_changed_services", err.Error())
+	}
+
+	// Metrics export
+	if cfg.Metrics.Textfile != "" && !strings.HasSuffix(cfg.Metrics.Textfile, ".prom") {
+		v.addKey(SeverityWarning, "metrics.textfile", "the node_exporter textfile collector only reads files ending in .prom")
+	}
+	if cfg.Metrics.Pushgateway != "" {
+		if gateway, err := url.Parse(cfg.Metrics.Pushgateway); err != nil || (gateway.Scheme != "http" && gateway.Scheme != "https") || gateway.Host == "" {
+			v.addKey(SeverityError, "metrics.pushgateway", fmt.Sprintf("'%s' is not an http(s) URL", cfg.Metrics.Pushgateway))
+		}
+	}
+	for name := range cfg.Metrics.Labels {
+		if !metricLabelPattern.MatchString(name) || strings.HasPrefix(name, "__") {
+			v.addKey(SeverityError, "metrics.labels."+name, fmt.Sprintf("'%s' is not a valid Prometheus label name", name))
+		}
+	}
+
+	// Trace export
+	if cfg.Tracing.Endpoint != "" {
+		if endpoint, err := url.Parse(cfg.Tracing.Endpoint); err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			v.addKey(SeverityError, "tracing.endpoint", fmt.Sprintf("'%s' is not an http(s) URL", cfg.Tracing.Endpoint))
+		}
+	}
+
+	// CI integration
+	if _, err := ci.Resolve(cfg.CI.Provider); err != nil {
+		v.addKey(SeverityError, "ci.provider", err.Error())
+	}
+
+	// Dockerfile linting
+	if cfg.Lint.Gate != "" && !lint.ValidSeverity(cfg.Lint.Gate) {
+		v.addKey(SeverityError, "lint.gate", fmt.Sprintf("invalid gate '%s': must be one of %s", cfg.Lint.Gate, strings.Join(lint.Severities, ", ")))
+	}
+	v.checkLintRules("lint.ignore", cfg.Lint.Ignore)
+	for id, severity := range cfg.Lint.Severity {
+		if _, ok := lint.Lookup(id); !ok {
+			v.addKey(SeverityWarning, "lint.severity."+id, fmt.Sprintf("unknown lint rule '%s'", id))
+		}
+		if !lint.ValidSeverity(severity) {
+			v.addKey(SeverityError, "lint.severity."+id, fmt.Sprintf("invalid severity '%s': must be one of %s", severity, strings.Join(lint.Severities, ", ")))
+		}
+	}
+
+	// Image signing
+	if cfg.Signing.Key != "" {
+		if _, err := os.Stat(cfg.Signing.Key); err != nil {
+			v.addKey(SeverityWarning, "signing.key", fmt.Sprintf("signing key '%s' does not exist", cfg.Signing.Key))
+		}
+	} else if cfg.Signing.Provenance {
+		v.addKey(SeverityWarning, "signing.provenance", "provenance is only attached when signing.key is set")
+	}
+
+	// Base image tracking
+	for i, pattern := range cfg.BaseImages.Ignore {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			v.addKey(SeverityError, fmt.Sprintf("base_images.ignore[%d]", i), fmt.Sprintf("invalid pattern '%s': %v", pattern, err))
+		}
+	}
+}
+
+// checkLintRules reports unknown rule IDs in a list of lint rules
+func (v *validator) checkLintRules(key string, ids []string) {
+	for i, id := range ids {
+		if _, ok := lint.Lookup(id); !ok {
+			v.addKey(SeverityWarning, fmt.Sprintf("%s[%d]", key, i), fmt.Sprintf("unknown lint rule '%s'", id))
+		}
+	}
+}
+
+// checkTemplates reports tag templates that do not parse
+func (v *validator) checkTemplates(key string, templates []string) {
+	for i, text := range templates {
+		if _, err := template.New("tag").Parse(text); err != nil {
+			v.addKey(SeverityError, fmt.Sprintf("%s[%d]", key, i), fmt.Sprintf("invalid tag template: %v", err))
+		}
+	}
+}
+
+// checkHooks reports hooks without a command or with an unknown policy
+func (v *validator) checkHooks(key string, hooks config.HooksConfig) {
+	events := []struct {
+		name  string
+		hooks []config.Hook
+	}{{"pre_build", hooks.PreBuild}, {"post_build", hooks.PostBuild}, {"post_push", hooks.PostPush}, {"on_failure", hooks.OnFailure}}
+
+	for _, event := range events {
+		for i, hook := range event.hooks {
+			hookKey := fmt.Sprintf("%s.%s[%d]", key, event.name, i)
+			if strings.TrimSpace(hook.Command) == "" {
+				v.addKey(SeverityError, hookKey, "hook command is required")
+			}
+			if hook.Policy != "" && hook.Policy != "fail" && hook.Policy != "ignore" {
+				v.addKey(SeverityError, hookKey+".policy", fmt.Sprintf("policy must be 'fail' or 'ignore', got '%s'", hook.Policy))
+			}
+		}
+	}
+}
+
+// add records an issue at a position
+func (v *validator) add(severity string, pos Position, key, message string) {
+	v.report.Issues = append(v.report.Issues, Issue{
+		Severity: severity,
+		File:     pos.File,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Key:      key,
+		Message:  message,
+	})
+}
+
+// addKey records an issue at the position a config key was defined, or its closest defined parent
+func (v *validator) addKey(severity, key, message string) {
+	pos, ok := v.positions[key]
+	for lookup := key; !ok && lookup != ""; {
+		if idx := strings.LastIndexAny(lookup, ".["); idx > 0 {
+			lookup = lookup[:idx]
+		} else {
+			lookup = ""
+		}
+		pos, ok = v.positions[lookup]
+	}
+	v.add(severity, pos, key, message)
+}
+
+// at returns the position of a node in a file
+func (v *validator) at(file string, node *yaml.Node) Position {
+	return Position{File: file, Line: node.Line, Column: node.Column}
+}
+
+// resolve follows YAML aliases to the node they refer to
+func resolve(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// interpolated reports whether a value contains ${VAR} references
+func interpolated(value string) bool {
+	return strings.Contains(value, "${")
+}
+
+// closest suggests the known key nearest to an unknown one, if it is close enough to be a typo
+func closest(name string, fields map[string]reflect.StructField) string {
+	best, bestDistance := "", 3
+	for candidate := range fields {
+		if distance := levenshtein(name, candidate); distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}